@@ -10,6 +10,7 @@ import (
 	"os"
 
 	"strconv"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/ghodss/yaml"
@@ -22,6 +23,19 @@ var (
 		"env": func(name string) string {
 			return strings.TrimSpace(os.Getenv(name))
 		},
+		"now": func() string {
+			return time.Now().UTC().Format(time.RFC3339)
+		},
+		"ago": func(duration string) (string, error) {
+			since, err := time.ParseDuration(duration)
+			if err != nil {
+				return "", err
+			}
+			return time.Now().UTC().Add(-since).Format(time.RFC3339), nil
+		},
+		"daysAgo": func(days int) string {
+			return time.Now().UTC().AddDate(0, 0, -days).Format(time.RFC3339)
+		},
 	}
 )
 
@@ -30,6 +44,12 @@ type datasetList struct {
 	Mongo     []mgoDataset
 	JSONFiles []jsonDataset
 	JSONDirs  []jsonDirDataset
+	SQL       []sqlDataset
+	HTTP      []httpDataset
+	Ingest    []ingestDataset
+	Stdin     []stdinDataset
+	Join      []joinDataset
+	Generate  []generateDataset
 }
 
 type datasetConfig struct {
@@ -43,21 +63,32 @@ type lConfig struct {
 	Datasets []datasetConfig `toml:"datasets" json:"datasets"`
 }
 
-// loadYAMLConfig returns a datasetList which is generated from the provided yaml config
-// string returning appropriate config structures.
-func loadYAMLConfig(ctx context.Context, configData string) (datasetList, error) {
+// parseYAMLConfig returns the lConfig of the provided yaml config string, after
+// executing it as a template.
+func parseYAMLConfig(configData string) (lConfig, error) {
 	var formatted bytes.Buffer
 	tml, err := template.New("geckodataset-yaml-config").Funcs(tmplFuncs).Parse(configData)
 	if err != nil {
-		return datasetList{}, err
+		return lConfig{}, err
 	}
 
 	if err := tml.Execute(&formatted, nil); err != nil {
-		return datasetList{}, err
+		return lConfig{}, err
 	}
 
 	var con lConfig
 	if err := yaml.Unmarshal(formatted.Bytes(), &con); err != nil {
+		return lConfig{}, err
+	}
+
+	return con, nil
+}
+
+// loadYAMLConfig returns a datasetList which is generated from the provided yaml config
+// string returning appropriate config structures.
+func loadYAMLConfig(ctx context.Context, configData string) (datasetList, error) {
+	con, err := parseYAMLConfig(configData)
+	if err != nil {
 		return datasetList{}, err
 	}
 
@@ -111,6 +142,78 @@ func loadYAMLConfig(ctx context.Context, configData string) (datasetList, error)
 			}
 
 			dl.JSONFiles = append(dl.JSONFiles, jsonconf)
+		case "sql":
+			var sqlconf sqlDataset
+			if err := yaml.Unmarshal(encoded, &sqlconf); err != nil {
+				return datasetList{}, err
+			}
+
+			sqlconf.DatasetConfig = dataset.DatasetConfig
+			if err := sqlconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.SQL = append(dl.SQL, sqlconf)
+		case "http":
+			var httpconf httpDataset
+			if err := yaml.Unmarshal(encoded, &httpconf); err != nil {
+				return datasetList{}, err
+			}
+
+			httpconf.DatasetConfig = dataset.DatasetConfig
+			if err := httpconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.HTTP = append(dl.HTTP, httpconf)
+		case "http-ingest":
+			var ingestconf ingestDataset
+			if err := yaml.Unmarshal(encoded, &ingestconf); err != nil {
+				return datasetList{}, err
+			}
+
+			ingestconf.DatasetConfig = dataset.DatasetConfig
+			if err := ingestconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.Ingest = append(dl.Ingest, ingestconf)
+		case "stdin":
+			var stdinconf stdinDataset
+			if err := yaml.Unmarshal(encoded, &stdinconf); err != nil {
+				return datasetList{}, err
+			}
+
+			stdinconf.DatasetConfig = dataset.DatasetConfig
+			if err := stdinconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.Stdin = append(dl.Stdin, stdinconf)
+		case "join":
+			var joinconf joinDataset
+			if err := yaml.Unmarshal(encoded, &joinconf); err != nil {
+				return datasetList{}, err
+			}
+
+			joinconf.DatasetConfig = dataset.DatasetConfig
+			if err := joinconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.Join = append(dl.Join, joinconf)
+		case "generate":
+			var generateconf generateDataset
+			if err := yaml.Unmarshal(encoded, &generateconf); err != nil {
+				return datasetList{}, err
+			}
+
+			generateconf.DatasetConfig = dataset.DatasetConfig
+			if err := generateconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.Generate = append(dl.Generate, generateconf)
 		}
 	}
 
@@ -131,21 +234,32 @@ func pushYAMLDatasets(ctx context.Context, configFile string) error {
 	return runDatasetConfig(ctx, config)
 }
 
-// loadTOMLConfig returns a datasetList which is generated from the provided toml config
-// string returning appropriate config structures.
-func loadTOMLConfig(ctx context.Context, configData string) (datasetList, error) {
+// parseTOMLConfig returns the lConfig of the provided toml config string, after
+// executing it as a template.
+func parseTOMLConfig(configData string) (lConfig, error) {
 	var formatted bytes.Buffer
 	tml, err := template.New("geckodataset-toml-config").Funcs(tmplFuncs).Parse(configData)
 	if err != nil {
-		return datasetList{}, err
+		return lConfig{}, err
 	}
 
 	if err := tml.Execute(&formatted, nil); err != nil {
-		return datasetList{}, err
+		return lConfig{}, err
 	}
 
 	var config lConfig
 	if _, err := toml.Decode(formatted.String(), &config); err != nil {
+		return lConfig{}, err
+	}
+
+	return config, nil
+}
+
+// loadTOMLConfig returns a datasetList which is generated from the provided toml config
+// string returning appropriate config structures.
+func loadTOMLConfig(ctx context.Context, configData string) (datasetList, error) {
+	config, err := parseTOMLConfig(configData)
+	if err != nil {
 		return datasetList{}, err
 	}
 
@@ -200,6 +314,78 @@ func loadTOMLConfig(ctx context.Context, configData string) (datasetList, error)
 			}
 
 			dl.JSONFiles = append(dl.JSONFiles, jsonconf)
+		case "sql":
+			var sqlconf sqlDataset
+			if _, err := toml.Decode(encoded.String(), &sqlconf); err != nil {
+				return datasetList{}, err
+			}
+
+			sqlconf.DatasetConfig = dataset.DatasetConfig
+			if err := sqlconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.SQL = append(dl.SQL, sqlconf)
+		case "http":
+			var httpconf httpDataset
+			if _, err := toml.Decode(encoded.String(), &httpconf); err != nil {
+				return datasetList{}, err
+			}
+
+			httpconf.DatasetConfig = dataset.DatasetConfig
+			if err := httpconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.HTTP = append(dl.HTTP, httpconf)
+		case "http-ingest":
+			var ingestconf ingestDataset
+			if _, err := toml.Decode(encoded.String(), &ingestconf); err != nil {
+				return datasetList{}, err
+			}
+
+			ingestconf.DatasetConfig = dataset.DatasetConfig
+			if err := ingestconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.Ingest = append(dl.Ingest, ingestconf)
+		case "stdin":
+			var stdinconf stdinDataset
+			if _, err := toml.Decode(encoded.String(), &stdinconf); err != nil {
+				return datasetList{}, err
+			}
+
+			stdinconf.DatasetConfig = dataset.DatasetConfig
+			if err := stdinconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.Stdin = append(dl.Stdin, stdinconf)
+		case "join":
+			var joinconf joinDataset
+			if _, err := toml.Decode(encoded.String(), &joinconf); err != nil {
+				return datasetList{}, err
+			}
+
+			joinconf.DatasetConfig = dataset.DatasetConfig
+			if err := joinconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.Join = append(dl.Join, joinconf)
+		case "generate":
+			var generateconf generateDataset
+			if _, err := toml.Decode(encoded.String(), &generateconf); err != nil {
+				return datasetList{}, err
+			}
+
+			generateconf.DatasetConfig = dataset.DatasetConfig
+			if err := generateconf.Validate(); err != nil {
+				return datasetList{}, err
+			}
+
+			dl.Generate = append(dl.Generate, generateconf)
 		}
 	}
 
@@ -239,5 +425,41 @@ func runDatasetConfig(ctx context.Context, config datasetList) error {
 		}
 	}
 
+	for _, conf := range config.SQL {
+		if err := runSQLDataset(ctx, conf.DatasetConfig, conf, config.Config); err != nil {
+			return err
+		}
+	}
+
+	for _, conf := range config.HTTP {
+		if err := runHTTPDataset(ctx, conf.DatasetConfig, conf, config.Config); err != nil {
+			return err
+		}
+	}
+
+	for _, conf := range config.Ingest {
+		if err := runIngestDataset(ctx, conf.DatasetConfig, conf, config.Config); err != nil {
+			return err
+		}
+	}
+
+	for _, conf := range config.Stdin {
+		if err := runStdinDataset(ctx, conf.DatasetConfig, conf, config.Config); err != nil {
+			return err
+		}
+	}
+
+	for _, conf := range config.Join {
+		if err := runJoinDataset(ctx, conf.DatasetConfig, conf, config.Config); err != nil {
+			return err
+		}
+	}
+
+	for _, conf := range config.Generate {
+		if err := runGenerateDataset(ctx, conf.DatasetConfig, conf, config.Config); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
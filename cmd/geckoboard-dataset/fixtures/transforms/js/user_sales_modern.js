@@ -0,0 +1,10 @@
+// transform processes incoming records returning a aggregrate of individual user sales,
+// using ES2015 features supported by the modern engine.
+const transform = (recordsJSON) => {
+	const records = JSON.parse(recordsJSON);
+
+	return JSON.stringify(records.map(({ name, sales = [] }) => ({
+		user: name,
+		sales: sales.reduce((total, sale) => total + sale, 0),
+	})));
+};
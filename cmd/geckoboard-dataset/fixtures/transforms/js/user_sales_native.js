@@ -0,0 +1,21 @@
+// Transform processes incoming records returning a aggregrate of individual user sales,
+// where records are received and returned as native objects.
+function Transform(records){
+	var processed = []
+	
+	for(var index in records){
+		var record = records[index]
+		
+		var totalSales = 0
+		for(var saleIndex in record.sales){
+			totalSales += record.sales[saleIndex]
+		}
+		
+		processed.push({
+			"user": record.name,
+			"sales": totalSales,
+		})
+	}
+	
+	return processed
+}
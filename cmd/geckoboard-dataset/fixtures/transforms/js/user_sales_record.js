@@ -0,0 +1,12 @@
+// Transform returns the total sales of an individual user, called for each record in record mode.
+function Transform(record){
+	var totalSales = 0
+	for(var saleIndex in record.sales){
+		totalSales += record.sales[saleIndex]
+	}
+
+	return {
+		"user": record.name,
+		"sales": totalSales,
+	}
+}
@@ -0,0 +1,18 @@
+-- Transform processes incoming records returning a aggregrate of individual user sales.
+function Transform(records)
+	local processed = {}
+
+	for _, record in ipairs(records) do
+		local totalSales = 0
+		for _, sale in ipairs(record.sales or {}) do
+			totalSales = totalSales + sale
+		end
+
+		table.insert(processed, {
+			user = record.name,
+			sales = totalSales,
+		})
+	end
+
+	return processed
+end
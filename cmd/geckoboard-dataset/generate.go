@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/influx6/geckodataset/dataset/pullers/generate"
+	"github.com/influx6/geckodataset/dataset/pushers"
+)
+
+func runGenerateDataset(ctx context.Context, set config.DatasetConfig, conf generateDataset, base config.ProcConfig) error {
+	geckoboard, err := pushers.NewGeckoboardPusher(base.APIKey, set)
+	if err != nil {
+		return err
+	}
+
+	transformer, closeProc, err := newProc(conf.driverConfig)
+	if err != nil {
+		return err
+	}
+
+	defer closeProc()
+
+	puller, err := generate.New(conf.Config)
+	if err != nil {
+		return err
+	}
+
+	var pushers dataset.DataPushers
+	pushers = append(pushers, geckoboard)
+
+	controller := dataset.Dataset{
+		Pull:    puller,
+		Pushers: pushers,
+		Proc:    transformer,
+	}
+
+	// Records are pushed as they are generated, where the generator keeps to
+	// it's rate, until the total is reached or the context is done.
+	for {
+		if err := controller.Do(ctx, base.PullBatch, base.PushBatch); err != nil {
+			if err == dataset.ErrNoMore || ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// generateDataset defines generate dataset requests for
+// synthetic records produced from a spec of fields.
+type generateDataset struct {
+	driverConfig
+	config.DatasetConfig
+	generate.Config
+}
+
+// Validate returns an error if the config is invalid.
+func (c *generateDataset) Validate() error {
+	if err := c.driverConfig.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.DatasetConfig.Validate(); err != nil {
+		return err
+	}
+
+	return c.Config.Validate()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/influx6/geckodataset/dataset/pullers/httpapi"
+	"github.com/influx6/geckodataset/dataset/pushers"
+)
+
+func runHTTPDataset(ctx context.Context, set config.DatasetConfig, conf httpDataset, base config.ProcConfig) error {
+	geckoboard, err := pushers.NewGeckoboardPusher(base.APIKey, set)
+	if err != nil {
+		return err
+	}
+
+	puller, err := httpapi.New(conf.Config)
+	if err != nil {
+		return err
+	}
+
+	transformer, closeProc, err := newProc(conf.driverConfig)
+	if err != nil {
+		return err
+	}
+
+	defer closeProc()
+
+	var pushers dataset.DataPushers
+	pushers = append(pushers, geckoboard)
+
+	controller := dataset.Dataset{
+		Pull:    puller,
+		Pushers: pushers,
+		Proc:    transformer,
+	}
+
+	for {
+		// Seek new batch for processing.
+		if err := controller.Do(ctx, base.PullBatch, base.PushBatch); err != nil {
+			if err == dataset.ErrNoMore {
+				return nil
+			}
+
+			return err
+		}
+
+		// Sleep for giving duration after last run of pull-process-push routine.
+		time.Sleep(base.RunInterval)
+	}
+}
+
+// httpDataset defines http dataset requests for
+// specific api endpoint.
+type httpDataset struct {
+	driverConfig
+	config.DatasetConfig
+	httpapi.Config
+}
+
+// Validate returns an error if the config is invalid.
+func (c *httpDataset) Validate() error {
+	if err := c.driverConfig.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.DatasetConfig.Validate(); err != nil {
+		return err
+	}
+
+	return c.Config.Validate()
+}
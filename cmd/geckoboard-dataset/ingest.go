@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/influx6/geckodataset/dataset/pullers/httpingest"
+	"github.com/influx6/geckodataset/dataset/pushers"
+)
+
+func runIngestDataset(ctx context.Context, set config.DatasetConfig, conf ingestDataset, base config.ProcConfig) error {
+	geckoboard, err := pushers.NewGeckoboardPusher(base.APIKey, set)
+	if err != nil {
+		return err
+	}
+
+	transformer, closeProc, err := newProc(conf.driverConfig)
+	if err != nil {
+		return err
+	}
+
+	defer closeProc()
+
+	puller, err := httpingest.New(conf.Config)
+	if err != nil {
+		return err
+	}
+
+	if err := puller.Start(); err != nil {
+		return err
+	}
+
+	defer puller.Close()
+
+	var pushers dataset.DataPushers
+	pushers = append(pushers, geckoboard)
+
+	controller := dataset.Dataset{
+		Pull:    puller,
+		Pushers: pushers,
+		Proc:    transformer,
+	}
+
+	return ingestRecords(ctx, controller, puller, base)
+}
+
+// ingestDrainTimeout sets the maximum time records already accepted are given to
+// be pushed once the context of an ingest is done.
+const ingestDrainTimeout = time.Minute
+
+// ingestRecords pushes records as soon as a batch is filled or flushed, until the
+// context is done. Records were acknowledged to their senders once accepted, so
+// the puller stops accepting records before the records still buffered are pushed
+// with a new context.
+func ingestRecords(ctx context.Context, controller dataset.Dataset, puller *httpingest.Ingest, base config.ProcConfig) error {
+	for {
+		err := controller.Do(ctx, base.PullBatch, base.PushBatch)
+		if err == nil {
+			continue
+		}
+
+		if err == dataset.ErrNoMore {
+			return nil
+		}
+
+		if ctx.Err() == nil {
+			return err
+		}
+
+		break
+	}
+
+	if err := puller.Close(); err != nil {
+		return err
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), ingestDrainTimeout)
+	defer cancel()
+
+	for {
+		if err := controller.Do(drainCtx, base.PullBatch, base.PushBatch); err != nil {
+			if err == dataset.ErrNoMore {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// ingestDataset defines http-ingest dataset requests for
+// records posted to a local HTTP server.
+type ingestDataset struct {
+	driverConfig
+	config.DatasetConfig
+	httpingest.Config
+}
+
+// Validate returns an error if the config is invalid.
+func (c *ingestDataset) Validate() error {
+	if err := c.driverConfig.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.DatasetConfig.Validate(); err != nil {
+		return err
+	}
+
+	// Ingesting has no end of source, where a reduced accumulator would be flushed
+	// whenever no records are queued.
+	if c.reduces() {
+		return errors.New("ingest can't be used with js.Mode reduce")
+	}
+
+	return c.Config.Validate()
+}
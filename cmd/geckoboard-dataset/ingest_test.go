@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/influx6/geckodataset/dataset/pullers/httpingest"
+)
+
+func TestIngestRecordsDrainsOnShutdown(t *testing.T) {
+	puller, err := httpingest.New(httpingest.Config{
+		Addr:          "127.0.0.1:0",
+		FlushInterval: "1m",
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created ingest")
+	}
+
+	if err := puller.Start(); err != nil {
+		tests.FailedWithError(err, "Should have successfully started ingest")
+	}
+
+	defer puller.Close()
+
+	pusher := &collectPush{}
+	controller := dataset.Dataset{
+		Pull:    puller,
+		Proc:    dataset.Procs{},
+		Pushers: dataset.DataPushers{pusher},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ingestRecords(ctx, controller, puller, config.ProcConfig{PullBatch: 100, PushBatch: 2})
+	}()
+
+	tests.Header("When records are acknowledged before shutdown")
+	{
+		res, err := http.Post("http://"+puller.Addr()+"/", "application/json", strings.NewReader(`[{"user":"alex"},{"user":"bob"},{"user":"carl"}]`))
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully posted records")
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusAccepted {
+			tests.Failed("Should have received status %d but got %d", http.StatusAccepted, res.StatusCode)
+		}
+		tests.Passed("Should have received status %d", http.StatusAccepted)
+
+		cancel()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				tests.FailedWithError(err, "Should have successfully drained ingest")
+			}
+		case <-time.After(time.Second * 10):
+			tests.Failed("Should have returned once ingest was drained")
+		}
+		tests.Passed("Should have successfully drained ingest")
+
+		if total := pusher.Total(); total != 3 {
+			tests.Failed("Should have pushed all 3 acknowledged records but got %d", total)
+		}
+		tests.Passed("Should have pushed all 3 acknowledged records")
+
+		res, err = http.Post("http://"+puller.Addr()+"/", "application/json", strings.NewReader(`[{"user":"dan"}]`))
+		if err == nil {
+			res.Body.Close()
+			tests.Failed("Should have stopped accepting records but got status %d", res.StatusCode)
+		}
+		tests.Passed("Should have stopped accepting records")
+	}
+}
+
+type collectPush struct {
+	ml      sync.Mutex
+	fails   int
+	records []map[string]interface{}
+}
+
+func (c *collectPush) Push(ctx context.Context, recs ...map[string]interface{}) error {
+	c.ml.Lock()
+	defer c.ml.Unlock()
+
+	if c.fails > 0 {
+		c.fails--
+		return errors.New("push failed")
+	}
+
+	c.records = append(c.records, recs...)
+	return nil
+}
+
+func (c *collectPush) Total() int {
+	c.ml.Lock()
+	defer c.ml.Unlock()
+
+	return len(c.records)
+}
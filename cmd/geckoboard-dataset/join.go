@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/influx6/geckodataset/dataset/pullers/httpapi"
+	"github.com/influx6/geckodataset/dataset/pullers/join"
+	"github.com/influx6/geckodataset/dataset/pullers/jsonfiles"
+	"github.com/influx6/geckodataset/dataset/pullers/mongodb"
+	"github.com/influx6/geckodataset/dataset/pullers/sqldb"
+	"github.com/influx6/geckodataset/dataset/pushers"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func runJoinDataset(ctx context.Context, set config.DatasetConfig, conf joinDataset, base config.ProcConfig) error {
+	geckoboard, err := pushers.NewGeckoboardPusher(base.APIKey, set)
+	if err != nil {
+		return err
+	}
+
+	left, closeLeft, err := conf.Left.open(set)
+	if err != nil {
+		return err
+	}
+
+	defer closeLeft()
+
+	right, closeRight, err := conf.Right.open(set)
+	if err != nil {
+		return err
+	}
+
+	defer closeRight()
+
+	puller, err := join.New(left, right, conf.Config())
+	if err != nil {
+		return err
+	}
+
+	defer puller.Close()
+
+	transformer, closeProc, err := newProc(conf.driverConfig)
+	if err != nil {
+		return err
+	}
+
+	defer closeProc()
+
+	var pushers dataset.DataPushers
+	pushers = append(pushers, geckoboard)
+
+	controller := dataset.Dataset{
+		Pull:    puller,
+		Pushers: pushers,
+		Proc:    transformer,
+	}
+
+	for {
+		// Seek new batch for processing.
+		if err := controller.Do(ctx, base.PullBatch, base.PushBatch); err != nil {
+			if err == dataset.ErrNoMore {
+				return nil
+			}
+
+			return err
+		}
+
+		// Sleep for giving duration after last run of pull-process-push routine.
+		time.Sleep(base.RunInterval)
+	}
+}
+
+// joinDataset defines join dataset requests for records of
+// two sources joined on key fields.
+type joinDataset struct {
+	driverConfig
+	config.DatasetConfig
+
+	// Type sets the join type: inner, left or full.
+	Type string `toml:"type" json:"type"`
+
+	// On sets the key fields of both sides, unless set by the keys of a side.
+	On []string `toml:"on" json:"on"`
+
+	// Left and Right set the sources of records joined, where the smaller source
+	// is indexed while the other is streamed, with Right indexed if both have more
+	// than MaxMemory records.
+	Left  joinSide `toml:"left" json:"left"`
+	Right joinSide `toml:"right" json:"right"`
+
+	// MaxMemory sets the maximum records of the indexed side held in memory before
+	// they are spilled into a file in SpillDir.
+	MaxMemory int    `toml:"max_memory" json:"max_memory"`
+	SpillDir  string `toml:"spill_dir" json:"spill_dir"`
+}
+
+// Config returns the join.Config for the dataset.
+func (c *joinDataset) Config() join.Config {
+	return join.Config{
+		Type:      c.Type,
+		LeftKeys:  c.Left.keys(c.On),
+		RightKeys: c.Right.keys(c.On),
+		MaxMemory: c.MaxMemory,
+		SpillDir:  c.SpillDir,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c *joinDataset) Validate() error {
+	if err := c.driverConfig.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.DatasetConfig.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := c.Left.decode(c.DatasetConfig); err != nil {
+		return fmt.Errorf("join.Left: %+s", err.Error())
+	}
+
+	if _, err := c.Right.decode(c.DatasetConfig); err != nil {
+		return fmt.Errorf("join.Right: %+s", err.Error())
+	}
+
+	config := c.Config()
+	return config.Validate()
+}
+
+// joinSide defines the source of records of a side of a join, which
+// uses the configuration of the giving driver.
+type joinSide struct {
+	Driver string                 `toml:"driver" json:"driver"`
+	Conf   map[string]interface{} `toml:"conf" json:"conf"`
+	Keys   []string               `toml:"keys" json:"keys"`
+}
+
+// keys returns the key fields of the side, defaulting to the provided keys.
+func (js joinSide) keys(on []string) []string {
+	if len(js.Keys) != 0 {
+		return js.Keys
+	}
+	return on
+}
+
+// decode returns the validated driver configuration of the side.
+func (js joinSide) decode(set config.DatasetConfig) (interface{}, error) {
+	encoded, err := json.Marshal(js.Conf)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(js.Driver) {
+	case "mongodb":
+		var conf mgoDataset
+		if err := json.Unmarshal(encoded, &conf); err != nil {
+			return nil, err
+		}
+
+		conf.DatasetConfig = set
+		if err := conf.Validate(); err != nil {
+			return nil, err
+		}
+
+		if conf.Mode == tailMode || conf.IncrementalField != "" || conf.Destination != "" {
+			return nil, errors.New("mongodb tail mode, incremental_field and dest are not supported within joins")
+		}
+
+		return &conf, nil
+	case "json-file":
+		var conf jsonDataset
+		if err := json.Unmarshal(encoded, &conf); err != nil {
+			return nil, err
+		}
+
+		conf.DatasetConfig = set
+		if err := conf.Validate(); err != nil {
+			return nil, err
+		}
+
+		return &conf, nil
+	case "json-dir":
+		var conf jsonDirDataset
+		if err := json.Unmarshal(encoded, &conf); err != nil {
+			return nil, err
+		}
+
+		conf.DatasetConfig = set
+		if err := conf.Validate(); err != nil {
+			return nil, err
+		}
+
+		if conf.Watch {
+			return nil, errors.New("json-dir watch is not supported within joins")
+		}
+
+		return &conf, nil
+	case "sql":
+		var conf sqlDataset
+		if err := json.Unmarshal(encoded, &conf); err != nil {
+			return nil, err
+		}
+
+		conf.DatasetConfig = set
+		if err := conf.Validate(); err != nil {
+			return nil, err
+		}
+
+		return &conf, nil
+	case "http":
+		var conf httpDataset
+		if err := json.Unmarshal(encoded, &conf); err != nil {
+			return nil, err
+		}
+
+		conf.DatasetConfig = set
+		if err := conf.Validate(); err != nil {
+			return nil, err
+		}
+
+		return &conf, nil
+	default:
+		return nil, fmt.Errorf("driver %q is not supported within joins (support: mongodb, json-file, json-dir, sql, http)", js.Driver)
+	}
+}
+
+// open returns the puller of the side, with a function which closes
+// the resources used by it.
+func (js joinSide) open(set config.DatasetConfig) (dataset.DataPull, func(), error) {
+	decoded, err := js.decode(set)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch conf := decoded.(type) {
+	case *mgoDataset:
+		session, err := mongodb.Dial(conf.DB)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		puller, err := mongodb.New(session, mongodb.Config{
+			DB:         conf.DB.DB,
+			Collection: conf.Source,
+			Query:      conf.Filter,
+			Projection: bson.M(conf.Projection),
+			Sort:       conf.Sort,
+			Limit:      conf.Limit,
+			Pipeline:   conf.Pipeline,
+		})
+		if err != nil {
+			session.Close()
+			return nil, nil, err
+		}
+
+		return puller, func() {
+			puller.Close()
+			session.Close()
+		}, nil
+	case *jsonDataset:
+		if conf.Source == jsonfiles.StdinSource {
+			reader, err := jsonfiles.OpenJSONReader(conf.Source)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return reader, func() { reader.Close() }, nil
+		}
+
+		stream, err := jsonfiles.NewJSONStream(conf.Source)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &stream, func() {}, nil
+	case *jsonDirDataset:
+		stream, err := jsonfiles.NewWithFilter(conf.SourceDir, conf.Deep, conf.Filter())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stream.OnFileError = jsonfiles.ErrorPolicy(conf.OnFileError)
+		stream.QuarantineDir = conf.QuarantineDir
+		return stream, func() {}, nil
+	case *sqlDataset:
+		puller, err := sqldb.Open(conf.Config())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return puller, func() { puller.Close() }, nil
+	case *httpDataset:
+		puller, err := httpapi.New(conf.Config)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return puller, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("driver %q is not supported within joins", js.Driver)
+	}
+}
@@ -8,18 +8,20 @@ import (
 
 	"context"
 
-	"github.com/influx6/faux/metrics"
 	"github.com/influx6/geckodataset/dataset"
 	"github.com/influx6/geckodataset/dataset/config"
-	"github.com/influx6/geckodataset/dataset/procs/binary"
-	"github.com/influx6/geckodataset/dataset/procs/jsotto"
 	"github.com/influx6/geckodataset/dataset/pullers/jsonfiles"
 	"github.com/influx6/geckodataset/dataset/pushers"
 )
 
 func runJSONDataset(ctx context.Context, set config.DatasetConfig, conf jsonDataset, base config.ProcConfig) error {
-	if conf.JS == nil && conf.Binary == nil {
-		return errors.New("JS or Binary configuration required")
+	// Standard input is streamed as it's read, instead of being loaded into memory.
+	if conf.Source == jsonfiles.StdinSource {
+		return runStdinDataset(ctx, set, stdinDataset{
+			driverConfig:  conf.driverConfig,
+			DatasetConfig: conf.DatasetConfig,
+			Source:        conf.Source,
+		}, base)
 	}
 
 	geckoboard, err := pushers.NewGeckoboardPusher(base.APIKey, set)
@@ -32,19 +34,12 @@ func runJSONDataset(ctx context.Context, set config.DatasetConfig, conf jsonData
 		return err
 	}
 
-	var transformer dataset.Proc
-	if conf.Binary != nil {
-		transformer = binary.New(*conf.Binary, metrics.New())
+	transformer, closeProc, err := newProc(conf.driverConfig)
+	if err != nil {
+		return err
 	}
 
-	if conf.JS != nil {
-		jso, err := jsotto.New(*conf.JS)
-		if err != nil {
-			return err
-		}
-
-		transformer = jso
-	}
+	defer closeProc()
 
 	var pushers dataset.DataPushers
 	pushers = append(pushers, geckoboard)
@@ -73,7 +68,7 @@ func runJSONDataset(ctx context.Context, set config.DatasetConfig, conf jsonData
 // jsonDataset defines json dataset requests for
 // specific file.
 type jsonDataset struct {
-	config.DriverConfig
+	driverConfig
 	config.DatasetConfig
 
 	Source string `toml:"source" json:"source"`
@@ -81,7 +76,7 @@ type jsonDataset struct {
 
 // Validate returns an error if the config is invalid.
 func (c *jsonDataset) Validate() error {
-	if err := c.DriverConfig.Validate(); err != nil {
+	if err := c.driverConfig.Validate(); err != nil {
 		return err
 	}
 
@@ -93,6 +88,10 @@ func (c *jsonDataset) Validate() error {
 		return errors.New("config.Source must be provided")
 	}
 
+	if c.Source == jsonfiles.StdinSource {
+		return nil
+	}
+
 	stat, err := os.Stat(c.Source)
 	if err != nil {
 		return fmt.Errorf("config.Source %+q failed to be found", c.Source)
@@ -1,52 +1,58 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"time"
 
 	"context"
 
 	"github.com/influx6/faux/metrics"
+	"github.com/influx6/faux/metrics/custom"
 	"github.com/influx6/geckodataset/dataset"
 	"github.com/influx6/geckodataset/dataset/config"
-	"github.com/influx6/geckodataset/dataset/procs/binary"
-	"github.com/influx6/geckodataset/dataset/procs/jsotto"
 	"github.com/influx6/geckodataset/dataset/pullers/jsonfiles"
 	"github.com/influx6/geckodataset/dataset/pushers"
 )
 
-func runJSONDirDataset(ctx context.Context, set config.DatasetConfig, conf jsonDirDataset, base config.ProcConfig) error {
-	if conf.JS == nil && conf.Binary == nil {
-		return errors.New("JS or Binary configuration required")
-	}
-
+func runJSONDirDataset(ctx context.Context, set config.DatasetConfig, conf jsonDirDataset, base config.ProcConfig) (err error) {
 	geckoboard, err := pushers.NewGeckoboardPusher(base.APIKey, set)
 	if err != nil {
 		return err
 	}
 
-	stream, err := jsonfiles.New(conf.SourceDir, conf.Deep)
+	if conf.Watch {
+		return watchJSONDirDataset(ctx, geckoboard, conf, base)
+	}
+
+	stream, err := jsonfiles.NewWithFilter(conf.SourceDir, conf.Deep, conf.Filter())
 	if err != nil {
 		return err
 	}
 
-	var transformer dataset.Proc
-	if conf.Binary != nil {
-		transformer = binary.New(*conf.Binary, metrics.New())
-	}
+	stream.OnFileError = jsonfiles.ErrorPolicy(conf.OnFileError)
+	stream.QuarantineDir = conf.QuarantineDir
 
-	if conf.JS != nil {
-		jso, err := jsotto.New(*conf.JS)
-		if err != nil {
-			return err
+	// Report the outcome of all files processed once we are done, where failing
+	// to write the report fails the run if nothing else did.
+	defer func() {
+		if rerr := reportJSONDir(stream.Report(), conf); rerr != nil && err == nil {
+			err = rerr
 		}
+	}()
 
-		transformer = jso
+	transformer, closeProc, err := newProc(conf.driverConfig)
+	if err != nil {
+		return err
 	}
 
+	defer closeProc()
+
 	var pushers dataset.DataPushers
-	pushers = append(pushers, geckoboard)
+	pushers = append(pushers, jsonDirPusher{geckoboard})
 
 	controller := dataset.Dataset{
 		Pull:    stream,
@@ -61,7 +67,18 @@ func runJSONDirDataset(ctx context.Context, set config.DatasetConfig, conf jsonD
 				return nil
 			}
 
-			return err
+			// Records which failed to be pushed stop processing, while records
+			// which failed to be transformed are handled by the OnFileError policy.
+			if _, ok := err.(pushError); ok {
+				stream.Abort(err)
+				return err
+			}
+
+			if err := stream.Failed(err); err != nil {
+				return err
+			}
+
+			continue
 		}
 
 		// Sleep for giving duration after last run of pull-process-push routine.
@@ -69,19 +86,139 @@ func runJSONDirDataset(ctx context.Context, set config.DatasetConfig, conf jsonD
 	}
 }
 
+// watchJSONDirDataset runs the dataset against a continuously watched directory, where
+// new json files are processed as they arrive until the context gets cancelled.
+func watchJSONDirDataset(ctx context.Context, pusher dataset.DataPush, conf jsonDirDataset, base config.ProcConfig) error {
+	stream, err := jsonfiles.Watch(jsonfiles.WatchConfig{
+		Dir:          conf.SourceDir,
+		Deep:         conf.Deep,
+		Poll:         conf.Poll,
+		ArchiveDir:   conf.ArchiveDir,
+		FailedDir:    conf.FailedDir,
+		Settle:       conf.SettleDuration,
+		PollInterval: conf.PollDuration,
+		Filter:       conf.Filter(),
+	})
+	if err != nil {
+		return err
+	}
+
+	defer stream.Close()
+
+	transformer, closeProc, err := newProc(conf.driverConfig)
+	if err != nil {
+		return err
+	}
+
+	defer closeProc()
+
+	var pushers dataset.DataPushers
+	pushers = append(pushers, jsonDirPusher{pusher})
+
+	controller := dataset.Dataset{
+		Pull:    stream,
+		Pushers: pushers,
+		Proc:    transformer,
+	}
+
+	m := metrics.New(custom.StackDisplay(os.Stderr))
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		err := controller.Do(ctx, base.PullBatch, base.PushBatch)
+
+		// The current file may still have records, so pull again immediately.
+		if err == nil {
+			continue
+		}
+
+		// Errors caused by the context being cancelled are no failures of the file.
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != dataset.ErrNoMore {
+			m.Emit(metrics.Errorf("failed to process json files: %+s", err), metrics.With("dir", conf.SourceDir))
+
+			// Files whose records failed to be pushed are processed again, while
+			// failed files are moved aside so that others can still be processed.
+			if _, ok := err.(pushError); ok {
+				stream.Release()
+			} else if err := stream.Failed(); err != nil {
+				return err
+			}
+		}
+
+		// Sleep for giving duration after last run of pull-process-push routine.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(base.RunInterval):
+		}
+	}
+}
+
+// pushError wraps the error of a failed push, telling it apart from errors of
+// records which failed to be loaded or transformed.
+type pushError struct {
+	error
+}
+
+// jsonDirPusher implements the dataset.DataPush interface, wrapping errors of
+// the pusher into pushError.
+type jsonDirPusher struct {
+	dataset.DataPush
+}
+
+// Push pushes the records into the pusher.
+func (wp jsonDirPusher) Push(ctx context.Context, recs ...map[string]interface{}) error {
+	if err := wp.DataPush.Push(ctx, recs...); err != nil {
+		return pushError{err}
+	}
+	return nil
+}
+
 // jsonDirDataset defines json dataset requests for
 // specific file.
 type jsonDirDataset struct {
-	config.DriverConfig
+	driverConfig
 	config.DatasetConfig
 
 	Deep      bool   `toml:"deep" json:"deep"`
 	SourceDir string `toml:"source_dir" json:"source_dir"`
+
+	// Include and Exclude contain glob patterns matched against file paths
+	// relative to SourceDir, with Sort setting the order of processing.
+	Include []string `toml:"include" json:"include"`
+	Exclude []string `toml:"exclude" json:"exclude"`
+	Sort    string   `toml:"sort" json:"sort"`
+
+	// Watch keeps the dataset running, processing new files as they
+	// arrive in SourceDir.
+	Watch        bool   `toml:"watch" json:"watch"`
+	Poll         bool   `toml:"poll" json:"poll"`
+	Settle       string `toml:"settle" json:"settle"`
+	PollInterval string `toml:"poll_interval" json:"poll_interval"`
+	ArchiveDir   string `toml:"archive_dir" json:"archive_dir"`
+	FailedDir    string `toml:"failed_dir" json:"failed_dir"`
+
+	SettleDuration time.Duration `toml:"-" json:"-"`
+	PollDuration   time.Duration `toml:"-" json:"-"`
+
+	// OnFileError sets the action taken when a file fails: skip, quarantine or fail.
+	OnFileError   string `toml:"on_file_error" json:"on_file_error"`
+	QuarantineDir string `toml:"quarantine_dir" json:"quarantine_dir"`
+
+	// Report sets a file path where the json report of processed files is written.
+	Report string `toml:"report" json:"report"`
 }
 
 // Validate returns an error if the config is invalid.
 func (c *jsonDirDataset) Validate() error {
-	if err := c.DriverConfig.Validate(); err != nil {
+	if err := c.driverConfig.Validate(); err != nil {
 		return err
 	}
 
@@ -102,5 +239,80 @@ func (c *jsonDirDataset) Validate() error {
 		return errors.New("config.SourceDir must not be a file")
 	}
 
+	if err := c.Filter().Validate(); err != nil {
+		return err
+	}
+
+	// Watched files which fail are moved into FailedDir and never reported.
+	if c.Watch && (c.OnFileError != "" || c.QuarantineDir != "" || c.Report != "") {
+		return errors.New("config.OnFileError, config.QuarantineDir and config.Report can't be used with config.Watch, use config.FailedDir instead")
+	}
+
+	// Watching has no end of source, where a reduced accumulator would be flushed
+	// whenever no files are ready.
+	if c.Watch && c.reduces() {
+		return errors.New("config.Watch can't be used with js.Mode reduce")
+	}
+
+	switch jsonfiles.ErrorPolicy(c.OnFileError) {
+	case "":
+		c.OnFileError = string(jsonfiles.FailOnError)
+	case jsonfiles.FailOnError, jsonfiles.SkipOnError:
+	case jsonfiles.QuarantineOnError:
+		if c.QuarantineDir == "" {
+			return errors.New("config.QuarantineDir must be provided for quarantine")
+		}
+	default:
+		return fmt.Errorf("config.OnFileError can only be either 'skip', 'quarantine' or 'fail' not %q", c.OnFileError)
+	}
+
+	if !c.Watch {
+		return nil
+	}
+
+	c.SettleDuration = jsonfiles.DefaultSettle
+	if c.Settle != "" {
+		settle, err := time.ParseDuration(c.Settle)
+		if err != nil {
+			return err
+		}
+		c.SettleDuration = settle
+	}
+
+	c.PollDuration = jsonfiles.DefaultPollInterval
+	if c.PollInterval != "" {
+		interval, err := time.ParseDuration(c.PollInterval)
+		if err != nil {
+			return err
+		}
+		c.PollDuration = interval
+	}
+
 	return nil
 }
+
+// Filter returns the jsonfiles.Filter for the include, exclude and sort values.
+func (c *jsonDirDataset) Filter() jsonfiles.Filter {
+	return jsonfiles.Filter{
+		Include: c.Include,
+		Exclude: c.Exclude,
+		Order:   jsonfiles.SortOrder(c.Sort),
+	}
+}
+
+// reportJSONDir prints the summary of processed files and writes the json
+// report to the configured report file if any.
+func reportJSONDir(report jsonfiles.Report, conf jsonDirDataset) error {
+	fmt.Fprintf(os.Stdout, "json-dir %q: %s", conf.SourceDir, report)
+
+	if conf.Report == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(conf.Report, data, 0644)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset/config"
+)
+
+func TestWatchJSONDirDatasetRetriesFailedPush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsondir")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created temporary directory")
+	}
+	tests.Passed("Should have successfully created temporary directory")
+
+	defer os.RemoveAll(dir)
+
+	sourceDir := filepath.Join(dir, "source")
+	archiveDir := filepath.Join(dir, "archive")
+	failedDir := filepath.Join(dir, "failed")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		tests.FailedWithError(err, "Should have successfully created source directory")
+	}
+	tests.Passed("Should have successfully created source directory")
+
+	data, err := ioutil.ReadFile("./fixtures/sales/user_sales.json")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully read sales fixture")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(sourceDir, "sales.json"), data, 0644); err != nil {
+		tests.FailedWithError(err, "Should have successfully written json file")
+	}
+	tests.Passed("Should have successfully written json file")
+
+	conf := jsonDirDataset{
+		driverConfig: driverConfig{
+			DriverConfig: config.DriverConfig{
+				JS: &config.JSOttoConf{
+					Main:   "./fixtures/transforms/js/user_sales_record.js",
+					Target: "Transform",
+					Mode:   config.JSRecordMode,
+				},
+			},
+		},
+		SourceDir:      sourceDir,
+		Watch:          true,
+		Poll:           true,
+		ArchiveDir:     archiveDir,
+		FailedDir:      failedDir,
+		SettleDuration: time.Millisecond * 50,
+		PollDuration:   time.Millisecond * 20,
+	}
+
+	pusher := &collectPush{fails: 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- watchJSONDirDataset(ctx, pusher, conf, config.ProcConfig{
+			PullBatch:   2,
+			PushBatch:   2,
+			RunInterval: time.Millisecond * 10,
+		})
+	}()
+
+	tests.Header("When records of a watched file fail to be pushed")
+	{
+		deadline := time.Now().Add(time.Second * 5)
+		for time.Now().Before(deadline) {
+			if _, err := os.Stat(filepath.Join(archiveDir, "sales.json")); err == nil {
+				break
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+
+		if _, err := os.Stat(filepath.Join(archiveDir, "sales.json")); err != nil {
+			tests.FailedWithError(err, "Should have archived file once it's records were pushed")
+		}
+		tests.Passed("Should have archived file once it's records were pushed")
+
+		if total := pusher.Total(); total != 5 {
+			tests.Failed("Should have pushed all 5 records of file again but got %d", total)
+		}
+		tests.Passed("Should have pushed all 5 records of file again")
+
+		if _, err := os.Stat(filepath.Join(failedDir, "sales.json")); err == nil {
+			tests.Failed("Should have not moved file into failed directory")
+		}
+		tests.Passed("Should have not moved file into failed directory")
+
+		cancel()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				tests.FailedWithError(err, "Should have stopped watching without error")
+			}
+		case <-time.After(time.Second * 5):
+			tests.Failed("Should have stopped watching once context was cancelled")
+		}
+		tests.Passed("Should have stopped watching once context was cancelled")
+	}
+}
@@ -11,16 +11,21 @@ func main() {
 	flags.Run("dataset", flags.Command{
 		Name:      "push",
 		ShortDesc: "Push data from a source to the geckoboard API",
-		Desc:      `Push takes provided configuration which it uses to retrieve, process and push new data to user's dataset on the Geckoboard API.`,
+		Desc:      `Push takes provided configuration which it uses to retrieve, process and push new data to user's dataset on the Geckoboard API. If a source is provided, then records are streamed from it as JSON arrays or newline delimited JSON, with flags overriding the dataset definition of the configuration.`,
 		Action: func(context flags.Context) error {
 			configFile, _ := context.GetString("config")
+
+			if source, _ := context.GetString("source"); source != "" {
+				return pushSourceDataset(context, configFile, sourceFlagsOf(context))
+			}
+
 			switch filepath.Ext(configFile) {
 			case ".yaml":
 				return pushYAMLDatasets(context, configFile)
 			case ".toml":
 				return pushTOMLDatasets(context, configFile)
 			default:
-				return fmt.Errorf("%+q config file extension unknown (support: .yaml, .toml)")
+				return fmt.Errorf("%+q config file extension unknown (support: .yaml, .toml)", configFile)
 			}
 		},
 		Flags: []flags.Flag{
@@ -29,6 +34,77 @@ func main() {
 				Default: "config.yaml",
 				Desc:    "configuration file for processing data into Geckoboard dataset.",
 			},
+			&flags.StringFlag{
+				Name: "source",
+				Desc: "source of records for a one-off push: stdin, - or path of a file or named pipe.",
+			},
+			&flags.StringFlag{
+				Name: "dataset",
+				Desc: "dataset to push records of source into, selecting it's definition within the configuration.",
+			},
+			&flags.StringFlag{
+				Name: "api-key",
+				Desc: "Geckoboard API key used for source pushes.",
+			},
+			&flags.StringFlag{
+				Name: "op",
+				Desc: "operation used for source pushes: push or update.",
+			},
+			&flags.StringFlag{
+				Name: "fields",
+				Desc: "comma separated fields of dataset for source pushes (e.g user:string,amount:money:USD,score:number:optional).",
+			},
+			&flags.StringFlag{
+				Name: "unique-by",
+				Desc: "comma separated unique_by fields of dataset for source pushes.",
+			},
+			&flags.StringFlag{
+				Name: "delete-by",
+				Desc: "comma separated delete_by fields of dataset for source pushes.",
+			},
+			&flags.IntFlag{
+				Name: "pull-batch",
+				Desc: "total records read from source per batch.",
+			},
+			&flags.IntFlag{
+				Name: "push-batch",
+				Desc: "total records pushed per request for source pushes.",
+			},
+			&flags.StringFlag{
+				Name: "js",
+				Desc: "javascript file used to process records of source pushes.",
+			},
+			&flags.StringFlag{
+				Name: "js-target",
+				Desc: "function within javascript file used to process records of source pushes.",
+			},
+			&flags.StringFlag{
+				Name: "bin",
+				Desc: "binary used to process records of source pushes.",
+			},
+			&flags.StringFlag{
+				Name: "bin-command",
+				Desc: "command run against binary used to process records of source pushes.",
+			},
 		},
 	})
 }
+
+// sourceFlagsOf returns the sourceFlags set within the giving context.
+func sourceFlagsOf(context flags.Context) sourceFlags {
+	var sf sourceFlags
+	sf.Source, _ = context.GetString("source")
+	sf.Dataset, _ = context.GetString("dataset")
+	sf.APIKey, _ = context.GetString("api-key")
+	sf.Op, _ = context.GetString("op")
+	sf.Fields, _ = context.GetString("fields")
+	sf.UniqueBy, _ = context.GetString("unique-by")
+	sf.DeleteBy, _ = context.GetString("delete-by")
+	sf.PullBatch, _ = context.GetInt("pull-batch")
+	sf.PushBatch, _ = context.GetInt("push-batch")
+	sf.JS, _ = context.GetString("js")
+	sf.JSTarget, _ = context.GetString("js-target")
+	sf.Bin, _ = context.GetString("bin")
+	sf.Command, _ = context.GetString("bin-command")
+	return sf
+}
@@ -3,57 +3,132 @@ package main
 import (
 	"context"
 	"errors"
-
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/influx6/faux/db/mongo"
-	"github.com/influx6/faux/metrics"
 	"github.com/influx6/geckodataset/dataset"
 	"github.com/influx6/geckodataset/dataset/config"
-	"github.com/influx6/geckodataset/dataset/procs/binary"
-	"github.com/influx6/geckodataset/dataset/procs/jsotto"
+	"github.com/influx6/geckodataset/dataset/pullers/mongodb"
 	"github.com/influx6/geckodataset/dataset/pushers"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// defaultWatermarkCollection sets the collection used for storing watermarks
+// of incremental mongodb datasets.
+const defaultWatermarkCollection = "geckodataset_watermarks"
+
+// mongodb destination modes.
+const (
+	upsertDestMode  = "upsert"
+	replaceDestMode = "replace"
+)
+
+// mongodb dataset modes.
+const (
+	queryMode = "query"
+	tailMode  = "tail"
 )
 
 func runMGODataset(ctx context.Context, set config.DatasetConfig, ds mgoDataset, conf config.ProcConfig) error {
-	if ds.JS == nil && ds.Binary == nil {
-		return errors.New("JS or Binary configuration required")
+	geckoboard, err := pushers.NewGeckoboardPusher(conf.APIKey, set)
+	if err != nil {
+		return err
 	}
 
-	geckoboard, err := pushers.NewGeckoboardPusher(conf.APIKey, set)
+	transformer, closeProc, err := newProc(ds.driverConfig)
+	if err != nil {
+		return err
+	}
+
+	defer closeProc()
+
+	session, err := mongodb.Dial(ds.DB)
 	if err != nil {
 		return err
 	}
 
-	mdb := mongo.NewMongoDB(ds.DB)
+	defer session.Close()
+
+	// Load last watermark or resume token of dataset, so we only pull
+	// documents not seen in previous runs.
+	var states *mongodb.StateStore
+	stateKey := set.Dataset + ":" + ds.Source
 
-	var transformer dataset.Proc
+	var state interface{}
+	if ds.IncrementalField != "" || ds.Mode == tailMode {
+		if ds.Mode == tailMode {
+			stateKey += ":resume_token"
+		}
 
-	if ds.Binary != nil {
-		transformer = binary.New(*ds.Binary, metrics.New())
+		states = mongodb.NewStateStore(session, ds.DB.DB, ds.WatermarkCollection)
+		defer states.Close()
+
+		state, _, err = states.Load(stateKey)
+		if err != nil {
+			return err
+		}
 	}
 
-	if ds.JS != nil {
-		jso, err := jsotto.New(*ds.JS)
+	var puller dataset.DataPull
+	var checkpoint func() (interface{}, bool)
+
+	switch ds.Mode {
+	case tailMode:
+		tail, err := mongodb.Tail(session, mongodb.TailConfig{
+			DB:          ds.DB.DB,
+			Collection:  ds.Source,
+			ResumeToken: state,
+			BatchWait:   ds.BatchWaitDuration,
+		})
 		if err != nil {
 			return err
 		}
 
-		transformer = jso
+		defer tail.Close()
+
+		puller = tail
+		checkpoint = tail.ResumeToken
+	default:
+		query, err := mongodb.New(session, mongodb.Config{
+			DB:               ds.DB.DB,
+			Collection:       ds.Source,
+			IncrementalField: ds.IncrementalField,
+			Watermark:        state,
+			Query:            ds.Filter,
+			Projection:       bson.M(ds.Projection),
+			Sort:             ds.Sort,
+			Limit:            ds.Limit,
+			Pipeline:         ds.Pipeline,
+		})
+		if err != nil {
+			return err
+		}
+
+		defer query.Close()
+
+		puller = query
+		checkpoint = query.Watermark
 	}
 
-	puller := new(mongo.MongoPull)
-	puller.Src = mdb
-	puller.Collection = ds.Source
+	// Records are upserted into the destination collection by the unique_by
+	// fields of the dataset, so re-runs do not duplicate them.
+	var dest dataset.DataPush
+	if ds.Destination != "" {
+		mgopusher, err := pushers.NewMongoPusher(session, ds.DB.DB, ds.Destination, set.UniqueBy, ds.DestMode == replaceDestMode)
+		if err != nil {
+			return err
+		}
+
+		dest = mgopusher
+	}
 
 	var pushers dataset.DataPushers
 	pushers = append(pushers, geckoboard)
 
-	if ds.Destination != "" {
-		var mgopusher mongo.MongoPush
-		mgopusher.Src = mdb
-		mgopusher.Collection = ds.Destination
-		pushers = append(pushers, mgopusher)
+	if dest != nil {
+		pushers = append(pushers, dest)
 	}
 
 	controller := dataset.Dataset{
@@ -65,13 +140,39 @@ func runMGODataset(ctx context.Context, set config.DatasetConfig, ds mgoDataset,
 	for {
 		// Seek new batch for processing.
 		if err := controller.Do(ctx, conf.PullBatch, conf.PushBatch); err != nil {
+			// Records held by the proc have being pushed once Do returns ErrNoMore.
 			if err == dataset.ErrNoMore {
+				if states != nil {
+					if value, ok := checkpoint(); ok {
+						return states.Save(stateKey, value)
+					}
+				}
+				return nil
+			}
+
+			// Tailing stops without error once the context is done.
+			if ds.Mode == tailMode && ctx.Err() != nil {
 				return nil
 			}
 
 			return err
 		}
 
+		// Persist watermark or resume token only after records have being successfully
+		// pushed, which for a reducing proc is only once it is flushed.
+		if states != nil && !ds.reduces() {
+			if value, ok := checkpoint(); ok {
+				if err := states.Save(stateKey, value); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Tailing pushes changes continuously as they are batched.
+		if ds.Mode == tailMode {
+			continue
+		}
+
 		// Sleep for giving duration after last run of pull-process-push routine.
 		time.Sleep(conf.RunInterval)
 	}
@@ -80,17 +181,56 @@ func runMGODataset(ctx context.Context, set config.DatasetConfig, ds mgoDataset,
 // mgoDataset defines json dataset requests for
 // specific file.
 type mgoDataset struct {
-	config.DriverConfig
+	driverConfig
 	config.DatasetConfig
 
 	Destination string       `toml:"dest" json:"dest"`
 	Source      string       `toml:"source" json:"source"`
 	DB          mongo.Config `toml:"db" json:"db"`
+
+	// DestMode sets how records are saved into the Destination collection, where records
+	// are always upserted by the unique_by fields of the dataset (`upsert`), and the
+	// collection is further cleared at the start of each run when set to `replace`.
+	DestMode string `toml:"dest_mode" json:"dest_mode"`
+
+	// IncrementalField sets the increasing field used to only pull documents added
+	// or updated since the last run, with the highest value seen stored in the
+	// WatermarkCollection of the database.
+	IncrementalField    string `toml:"incremental_field" json:"incremental_field"`
+	WatermarkCollection string `toml:"watermark_collection" json:"watermark_collection"`
+
+	// Query sets the mongodb extended json filter for documents pulled, with Projection,
+	// Sort and Limit further restricting what is sent to the proc.
+	Query      string                 `toml:"query" json:"query"`
+	Projection map[string]interface{} `toml:"projection" json:"projection"`
+	Sort       []string               `toml:"sort" json:"sort"`
+	Limit      int                    `toml:"limit" json:"limit"`
+
+	// Aggregate sets the stages of an aggregation pipeline whose results are pulled
+	// instead of querying the source collection. Stages may be documents or strings
+	// of mongodb extended json, where the latter keeps the order of fields.
+	Aggregate []interface{} `toml:"aggregate" json:"aggregate"`
+
+	// Mode sets whether documents are pulled by a query of the source collection (`query`),
+	// or changes to it are tailed through a change stream and pushed continuously (`tail`),
+	// with resume tokens stored in the WatermarkCollection. Changes are batched by the
+	// pull_batch count or the BatchWait duration after the first change of a batch.
+	Mode      string `toml:"mode" json:"mode"`
+	BatchWait string `toml:"batch_wait" json:"batch_wait"`
+
+	// BatchWaitDuration gets the parsed duration of the BatchWait field.
+	BatchWaitDuration time.Duration `toml:"-" json:"-"`
+
+	// Filter gets the parsed filter document of the Query field.
+	Filter bson.M `toml:"-" json:"-"`
+
+	// Pipeline gets the parsed stages of the Aggregate field.
+	Pipeline []interface{} `toml:"-" json:"-"`
 }
 
 // Validate returns an error if the config is invalid.
 func (c *mgoDataset) Validate() error {
-	if err := c.DriverConfig.Validate(); err != nil {
+	if err := c.driverConfig.Validate(); err != nil {
 		return err
 	}
 
@@ -106,5 +246,79 @@ func (c *mgoDataset) Validate() error {
 		return err
 	}
 
+	if c.WatermarkCollection == "" {
+		c.WatermarkCollection = defaultWatermarkCollection
+	}
+
+	switch strings.ToLower(c.DestMode) {
+	case "", upsertDestMode:
+		c.DestMode = upsertDestMode
+	case replaceDestMode:
+		c.DestMode = replaceDestMode
+	default:
+		return fmt.Errorf("mongo.DestMode %q is not supported, expected upsert or replace", c.DestMode)
+	}
+
+	switch strings.ToLower(c.Mode) {
+	case "", queryMode:
+		c.Mode = queryMode
+	case tailMode:
+		c.Mode = tailMode
+		if c.IncrementalField != "" || c.Query != "" || len(c.Projection) != 0 || len(c.Sort) != 0 || c.Limit != 0 || len(c.Aggregate) != 0 {
+			return errors.New("mongo.Mode tail can't be used with mongo.IncrementalField, mongo.Query, mongo.Projection, mongo.Sort, mongo.Limit or mongo.Aggregate")
+		}
+	default:
+		return fmt.Errorf("mongo.Mode %q is not supported, expected query or tail", c.Mode)
+	}
+
+	// Changes are tailed until the CLI stops, so a reduced accumulator is never
+	// flushed.
+	if c.Mode == tailMode && c.reduces() {
+		return errors.New("mongo.Mode tail can't be used with js.Mode reduce")
+	}
+
+	// Replacing clears the collection at the start of each run, which would leave it
+	// with only the records changed since the last run.
+	if c.DestMode == replaceDestMode && (c.IncrementalField != "" || c.Mode == tailMode) {
+		return errors.New("mongo.DestMode replace can't be used with mongo.IncrementalField or mongo.Mode tail")
+	}
+
+	if c.BatchWait != "" {
+		wait, err := time.ParseDuration(c.BatchWait)
+		if err != nil {
+			return err
+		}
+
+		c.BatchWaitDuration = wait
+	}
+
+	if c.IncrementalField != "" && len(c.Sort) != 0 {
+		return errors.New("mongo.Sort can't be used with mongo.IncrementalField")
+	}
+
+	if c.Limit < 0 {
+		return errors.New("mongo.Limit can't be negative")
+	}
+
+	filter, err := mongodb.ParseQuery(c.Query)
+	if err != nil {
+		return err
+	}
+
+	c.Filter = filter
+
+	if len(c.Aggregate) != 0 {
+		if c.IncrementalField != "" || c.Query != "" || len(c.Projection) != 0 || len(c.Sort) != 0 || c.Limit != 0 {
+			return errors.New("mongo.Aggregate can't be used with mongo.IncrementalField, mongo.Query, mongo.Projection, mongo.Sort or mongo.Limit")
+		}
+
+		pipeline, err := mongodb.ParsePipeline(c.Aggregate)
+		if err != nil {
+			return err
+		}
+
+		c.Pipeline = pipeline
+	}
+
 	return nil
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/influx6/faux/metrics"
+	"github.com/influx6/faux/metrics/custom"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/influx6/geckodataset/dataset/procs/binary"
+	"github.com/influx6/geckodataset/dataset/procs/enrich"
+	"github.com/influx6/geckodataset/dataset/procs/jsotto"
+	"github.com/influx6/geckodataset/dataset/procs/lua"
+	"github.com/influx6/geckodataset/dataset/procs/starlark"
+)
+
+// driverConfig embodies the configuration of the procs of a dataset, which adds the
+// lookup tables of the enrich proc to the config.DriverConfig.
+type driverConfig struct {
+	config.DriverConfig
+
+	// Enrich indicates the configuration values for the Enricher procs, which runs
+	// before the JS, Lua, Starlark or Binary procs.
+	Enrich *enrich.Config `toml:"enrich" json:"enrich"`
+}
+
+// Validate returns an error if the config is invalid.
+func (dc *driverConfig) Validate() error {
+	if err := dc.DriverConfig.Validate(); err != nil {
+		return err
+	}
+
+	var transformers int
+	for _, set := range []bool{dc.JS != nil, dc.Lua != nil, dc.Starlark != nil, dc.Binary != nil} {
+		if set {
+			transformers++
+		}
+	}
+
+	if transformers > 1 {
+		return errors.New("only one of JS, Lua, Starlark or Binary configuration can be used")
+	}
+
+	if dc.Enrich != nil {
+		if err := dc.Enrich.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reduces returns true if the proc holds records across batches, which are only
+// pushed once the source has no more records.
+func (dc driverConfig) reduces() bool {
+	return dc.JS != nil && dc.JS.Mode == config.JSReduceMode
+}
+
+// newProc returns the proc of the driver configuration, with a function which
+// releases the resources used by it. Records are enriched, if configured, before
+// being transformed by the JS, Lua, Starlark or Binary proc, where either may be
+// used alone.
+func newProc(conf driverConfig) (dataset.Proc, func(), error) {
+	if conf.JS == nil && conf.Lua == nil && conf.Starlark == nil && conf.Binary == nil && conf.Enrich == nil {
+		return nil, nil, errors.New("JS, Lua, Starlark, Binary or Enrich configuration required")
+	}
+
+	transformer, closeTransformer, err := newTransformer(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if conf.Enrich == nil {
+		return transformer, closeTransformer, nil
+	}
+
+	enricher, err := enrich.New(*conf.Enrich, metrics.New(custom.StackDisplay(os.Stderr)))
+	if err != nil {
+		closeTransformer()
+		return nil, nil, err
+	}
+
+	if transformer == nil {
+		return enricher, func() { enricher.Close() }, nil
+	}
+
+	return dataset.Procs{enricher, transformer}, func() {
+		enricher.Close()
+		closeTransformer()
+	}, nil
+}
+
+// newTransformer returns the JS, Lua, Starlark or Binary proc of the driver
+// configuration, with a function which releases the resources used by it, or a
+// nil proc if none is configured.
+func newTransformer(conf driverConfig) (dataset.Proc, func(), error) {
+	switch {
+	case conf.JS != nil:
+		jso, err := jsotto.New(*conf.JS, metrics.New(custom.StackDisplay(os.Stderr)))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return jso, func() { jso.Close() }, nil
+	case conf.Lua != nil:
+		lu, err := lua.New(*conf.Lua, metrics.New(custom.StackDisplay(os.Stderr)))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return lu, func() { lu.Close() }, nil
+	case conf.Starlark != nil:
+		sl, err := starlark.New(*conf.Starlark, metrics.New(custom.StackDisplay(os.Stderr)))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return sl, func() {}, nil
+	case conf.Binary != nil:
+		return binary.New(*conf.Binary, metrics.New()), func() {}, nil
+	}
+
+	return nil, func() {}, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/influx6/geckodataset/dataset/procs/enrich"
+)
+
+func TestNewProcWithEnrichOnly(t *testing.T) {
+	proc, closeProc, err := newProc(driverConfig{
+		Enrich: &enrich.Config{
+			Tables: []enrich.LookupConfig{
+				{
+					Source:    enrich.CSVLookup,
+					Path:      "../../dataset/procs/enrich/fixtures/products.csv",
+					Key:       "id",
+					RecordKey: "product_id",
+				},
+			},
+		},
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created enrich proc")
+	}
+	tests.Passed("Should have successfully created enrich proc")
+
+	defer closeProc()
+
+	recs, err := proc.Transform(context.Background(), map[string]interface{}{"product_id": "1"})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully enriched records")
+	}
+	tests.Passed("Should have successfully enriched records")
+
+	if len(recs) != 1 || recs[0]["product"] != "Keyboard" {
+		tests.Failed("Should have enriched record with product fields: %#v", recs)
+	}
+	tests.Passed("Should have enriched record with product fields")
+
+	if _, _, err := newProc(driverConfig{}); err == nil {
+		tests.Failed("Should have failed to create proc without configuration")
+	}
+	tests.Passed("Should have failed to create proc without configuration")
+}
+
+func TestDriverConfigWithManyTransformers(t *testing.T) {
+	conf := driverConfig{
+		DriverConfig: config.DriverConfig{
+			JS:     &config.JSOttoConf{Main: "./fixtures/transforms/js/user_sales.js", Target: "transformDocument"},
+			Binary: &config.BinaryConf{Bin: "echo"},
+		},
+	}
+
+	if err := conf.Validate(); err == nil {
+		tests.Failed("Should have failed to validate config with JS and Binary configuration")
+	}
+	tests.Passed("Should have failed to validate config with JS and Binary configuration")
+
+	conf.Binary = nil
+	if err := conf.Validate(); err != nil {
+		tests.FailedWithError(err, "Should have successfully validated config with JS configuration")
+	}
+	tests.Passed("Should have successfully validated config with JS configuration")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/influx6/geckodataset/dataset/config"
+)
+
+// sourceFlags contains the values of CLI flags defining a one-off push of records
+// read from a source, where set values override the dataset definition found
+// in the config file.
+type sourceFlags struct {
+	Source    string
+	Dataset   string
+	APIKey    string
+	Op        string
+	Fields    string
+	UniqueBy  string
+	DeleteBy  string
+	PullBatch int
+	PushBatch int
+	JS        string
+	JSTarget  string
+	Bin       string
+	Command   string
+}
+
+// pushSourceDataset pushes records streamed from the source of the flags.
+func pushSourceDataset(ctx context.Context, configFile string, flags sourceFlags) error {
+	base, ds, err := loadSourceDataset(configFile, flags)
+	if err != nil {
+		return err
+	}
+
+	return runStdinDataset(ctx, ds.DatasetConfig, ds, base)
+}
+
+// loadSourceDataset returns the config and dataset for a one-off push of records streamed
+// from the source of the flags. The dataset named by the flags, or the only dataset, of
+// the config file is used as the base definition if the config file exists.
+func loadSourceDataset(configFile string, flags sourceFlags) (config.ProcConfig, stdinDataset, error) {
+	var base lConfig
+
+	data, err := ioutil.ReadFile(configFile)
+	switch {
+	case err == nil:
+		switch filepath.Ext(configFile) {
+		case ".yaml":
+			base, err = parseYAMLConfig(string(data))
+		case ".toml":
+			base, err = parseTOMLConfig(string(data))
+		default:
+			err = fmt.Errorf("%+q config file extension unknown (support: .yaml, .toml)", configFile)
+		}
+
+		if err != nil {
+			return config.ProcConfig{}, stdinDataset{}, err
+		}
+	case !os.IsNotExist(err):
+		return config.ProcConfig{}, stdinDataset{}, err
+	}
+
+	var ds stdinDataset
+	if set, ok := findDataset(base.Datasets, flags.Dataset); ok {
+		ds.DatasetConfig = set.DatasetConfig
+
+		// Only the proc configuration is kept from the driver configuration.
+		encoded, err := json.Marshal(set.Conf)
+		if err != nil {
+			return config.ProcConfig{}, stdinDataset{}, err
+		}
+
+		if err := json.Unmarshal(encoded, &ds.driverConfig); err != nil {
+			return config.ProcConfig{}, stdinDataset{}, err
+		}
+	}
+
+	proc := base.ProcConfig
+	if err := flags.apply(&proc, &ds); err != nil {
+		return config.ProcConfig{}, stdinDataset{}, err
+	}
+
+	if err := proc.Validate(); err != nil {
+		return config.ProcConfig{}, stdinDataset{}, err
+	}
+
+	if err := ds.Validate(); err != nil {
+		return config.ProcConfig{}, stdinDataset{}, err
+	}
+
+	return proc, ds, nil
+}
+
+// findDataset returns the dataset with the giving name, or the only dataset
+// if no name is provided.
+func findDataset(datasets []datasetConfig, name string) (datasetConfig, bool) {
+	if name == "" {
+		if len(datasets) == 1 {
+			return datasets[0], true
+		}
+		return datasetConfig{}, false
+	}
+
+	for _, set := range datasets {
+		if set.Dataset == name {
+			return set, true
+		}
+	}
+
+	return datasetConfig{}, false
+}
+
+// apply overrides the config and dataset with the values set by the flags.
+func (sf sourceFlags) apply(proc *config.ProcConfig, ds *stdinDataset) error {
+	ds.Source = sf.Source
+
+	if sf.APIKey != "" {
+		proc.APIKey = sf.APIKey
+	}
+
+	if sf.PullBatch > 0 {
+		proc.PullBatch = sf.PullBatch
+	}
+
+	if sf.PushBatch > 0 {
+		proc.PushBatch = sf.PushBatch
+	}
+
+	if sf.Dataset != "" {
+		ds.Dataset = sf.Dataset
+	}
+
+	if sf.Op != "" {
+		ds.Op = sf.Op
+	}
+
+	if sf.UniqueBy != "" {
+		ds.UniqueBy = splitList(sf.UniqueBy)
+	}
+
+	if sf.DeleteBy != "" {
+		ds.DeteletBy = splitList(sf.DeleteBy)
+	}
+
+	if sf.Fields != "" {
+		fields, err := parseFields(sf.Fields)
+		if err != nil {
+			return err
+		}
+
+		ds.Fields = fields
+	}
+
+	if sf.JS != "" {
+		ds.JS = &config.JSOttoConf{Main: sf.JS, Target: sf.JSTarget}
+		ds.Lua = nil
+		ds.Starlark = nil
+		ds.Binary = nil
+	} else if sf.JSTarget != "" && ds.JS != nil {
+		ds.JS.Target = sf.JSTarget
+	}
+
+	if sf.Bin != "" {
+		ds.Binary = &config.BinaryConf{Bin: sf.Bin, Command: sf.Command}
+		ds.JS = nil
+		ds.Lua = nil
+		ds.Starlark = nil
+	}
+
+	return nil
+}
+
+// parseFields returns the dataset fields of the giving comma separated list of
+// `name:type` definitions, where money fields are followed by their currency
+// (e.g `amount:money:USD`) and optional fields end with `:optional`.
+func parseFields(list string) ([]config.FieldType, error) {
+	var fields []config.FieldType
+	for _, definition := range splitList(list) {
+		parts := strings.Split(definition, ":")
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("field %q must be defined as name:type", definition)
+		}
+
+		field := config.FieldType{Name: parts[0], Type: parts[1]}
+		for _, option := range parts[2:] {
+			if strings.ToLower(option) == "optional" {
+				field.Optional = true
+				continue
+			}
+
+			field.Currency = option
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// splitList returns the trimmed non-empty values of the comma separated list.
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
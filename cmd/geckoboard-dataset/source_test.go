@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/influx6/faux/tests"
+)
+
+func TestLoadSourceDataset(t *testing.T) {
+	tests.Header("When overriding dataset of config file")
+	{
+		base, ds, err := loadSourceDataset("./fixtures/config/sales.yaml", sourceFlags{
+			Source:    "stdin",
+			Op:        "update",
+			PushBatch: 50,
+		})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully loaded source dataset")
+		}
+		tests.Passed("Should have successfully loaded source dataset")
+
+		if ds.Source != "-" {
+			tests.Failed("Should have read records from standard input")
+		}
+		tests.Passed("Should have read records from standard input")
+
+		if ds.Dataset != "user_sales_freq" || len(ds.Fields) != 2 || len(ds.UniqueBy) != 1 {
+			tests.Failed("Should have used dataset definition of config file")
+		}
+		tests.Passed("Should have used dataset definition of config file")
+
+		if ds.JS == nil || ds.JS.Target != "transformDocument" {
+			tests.Failed("Should have used js configuration of config file")
+		}
+		tests.Passed("Should have used js configuration of config file")
+
+		if ds.Op != "update" || base.PushBatch != 50 || base.PullBatch != 100 {
+			tests.Failed("Should have overridden op and push batch only")
+		}
+		tests.Passed("Should have overridden op and push batch only")
+	}
+
+	tests.Header("When defining dataset only with flags")
+	{
+		_, ds, err := loadSourceDataset("./fixtures/config/missing.yaml", sourceFlags{
+			Source:   "-",
+			Dataset:  "daily_revenue",
+			APIKey:   "your_api_key",
+			Fields:   "day:date, revenue:money:USD, refunds:number:optional",
+			UniqueBy: "day",
+			Bin:      "echo",
+		})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully loaded source dataset")
+		}
+		tests.Passed("Should have successfully loaded source dataset")
+
+		if ds.Dataset != "daily_revenue" || ds.Binary == nil || ds.JS != nil {
+			tests.Failed("Should have defined dataset and binary from flags")
+		}
+		tests.Passed("Should have defined dataset and binary from flags")
+
+		if len(ds.Fields) != 3 || ds.Fields[1].Currency != "USD" || !ds.Fields[2].Optional {
+			tests.Failed("Should have parsed fields from flags: %#v", ds.Fields)
+		}
+		tests.Passed("Should have parsed fields from flags")
+	}
+
+	tests.Header("When flags are missing the dataset definition")
+	{
+		if _, _, err := loadSourceDataset("./fixtures/config/missing.yaml", sourceFlags{Source: "-", Bin: "echo"}); err == nil {
+			tests.Failed("Should have failed without api key and dataset")
+		}
+		tests.Passed("Should have failed without api key and dataset")
+
+		if _, _, err := loadSourceDataset("./fixtures/config/sales.yaml", sourceFlags{Source: "-", Fields: "user"}); err == nil {
+			tests.Failed("Should have failed with invalid fields")
+		}
+		tests.Passed("Should have failed with invalid fields")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/influx6/geckodataset/dataset/pullers/sqldb"
+	"github.com/influx6/geckodataset/dataset/pushers"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+func runSQLDataset(ctx context.Context, set config.DatasetConfig, conf sqlDataset, base config.ProcConfig) error {
+	geckoboard, err := pushers.NewGeckoboardPusher(base.APIKey, set)
+	if err != nil {
+		return err
+	}
+
+	puller, err := sqldb.Open(conf.Config())
+	if err != nil {
+		return err
+	}
+
+	defer puller.Close()
+
+	transformer, closeProc, err := newProc(conf.driverConfig)
+	if err != nil {
+		return err
+	}
+
+	defer closeProc()
+
+	var pushers dataset.DataPushers
+	pushers = append(pushers, geckoboard)
+
+	controller := dataset.Dataset{
+		Pull:    puller,
+		Pushers: pushers,
+		Proc:    transformer,
+	}
+
+	for {
+		// Seek new batch for processing.
+		if err := controller.Do(ctx, base.PullBatch, base.PushBatch); err != nil {
+			if err == dataset.ErrNoMore {
+				return nil
+			}
+
+			return err
+		}
+
+		// Sleep for giving duration after last run of pull-process-push routine.
+		time.Sleep(base.RunInterval)
+	}
+}
+
+// sqlDataset defines sql dataset requests for
+// specific query.
+type sqlDataset struct {
+	driverConfig
+	config.DatasetConfig
+
+	DBDriver    string                 `toml:"db_driver" json:"db_driver"`
+	DSN         string                 `toml:"dsn" json:"dsn"`
+	Query       string                 `toml:"query" json:"query"`
+	Params      map[string]interface{} `toml:"params" json:"params"`
+	Cursor      string                 `toml:"cursor" json:"cursor"`
+	CursorStart interface{}            `toml:"cursor_start" json:"cursor_start"`
+}
+
+// Config returns the sqldb.Config for the dataset.
+func (c *sqlDataset) Config() sqldb.Config {
+	return sqldb.Config{
+		Driver: c.DBDriver,
+		DSN:    c.DSN,
+		Query:  c.Query,
+		Params: c.Params,
+		Cursor: c.Cursor,
+		Start:  c.CursorStart,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c *sqlDataset) Validate() error {
+	if err := c.driverConfig.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.DatasetConfig.Validate(); err != nil {
+		return err
+	}
+
+	if c.DSN == "" {
+		return errors.New("sql.DSN is required")
+	}
+
+	return c.Config().Validate()
+}
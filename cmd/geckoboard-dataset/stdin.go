@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/influx6/geckodataset/dataset/pullers/jsonfiles"
+	"github.com/influx6/geckodataset/dataset/pushers"
+)
+
+func runStdinDataset(ctx context.Context, set config.DatasetConfig, conf stdinDataset, base config.ProcConfig) error {
+	geckoboard, err := pushers.NewGeckoboardPusher(base.APIKey, set)
+	if err != nil {
+		return err
+	}
+
+	reader, err := jsonfiles.OpenJSONReader(conf.Source)
+	if err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	transformer, closeProc, err := newProc(conf.driverConfig)
+	if err != nil {
+		return err
+	}
+
+	defer closeProc()
+
+	var pushers dataset.DataPushers
+	pushers = append(pushers, geckoboard)
+
+	controller := dataset.Dataset{
+		Pull:    reader,
+		Pushers: pushers,
+		Proc:    transformer,
+	}
+
+	// Records are pushed as they are read, until the input is exhausted.
+	for {
+		if err := controller.Do(ctx, base.PullBatch, base.PushBatch); err != nil {
+			if err == dataset.ErrNoMore {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// stdinDataset defines stdin dataset requests for records streamed
+// from standard input or a named pipe.
+type stdinDataset struct {
+	driverConfig
+	config.DatasetConfig
+
+	// Source sets the named pipe records are read from, defaults to
+	// standard input.
+	Source string `toml:"source" json:"source"`
+}
+
+// Validate returns an error if the config is invalid.
+func (c *stdinDataset) Validate() error {
+	if err := c.driverConfig.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.DatasetConfig.Validate(); err != nil {
+		return err
+	}
+
+	if c.Source == "" || c.Source == "stdin" {
+		c.Source = jsonfiles.StdinSource
+	}
+
+	if c.Source == jsonfiles.StdinSource {
+		return nil
+	}
+
+	stat, err := os.Stat(c.Source)
+	if err != nil {
+		return fmt.Errorf("config.Source %+q failed to be found", c.Source)
+	}
+
+	if stat.IsDir() {
+		return errors.New("config.Source must be a file or named pipe")
+	}
+
+	return nil
+}
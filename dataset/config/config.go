@@ -23,6 +23,18 @@ const (
 	// DefaultInterval indicates the default expected time for each
 	// requests to be processed before waiting for it's next run.
 	DefaultInterval = time.Second * 5
+
+	// DefaultReloadInterval indicates the default time between checks of the
+	// javascript files of a JSOttoConf for changes, when reloading is enabled.
+	DefaultReloadInterval = time.Second * 2
+
+	// DefaultLuaTimeout indicates the default time the target function of a
+	// LuaConf may run for a batch before it is interrupted.
+	DefaultLuaTimeout = time.Second * 30
+
+	// DefaultStarlarkMaxSteps indicates the default total steps the target
+	// function of a StarlarkConf may execute for a batch before it is cancelled.
+	DefaultStarlarkMaxSteps = 10000000
 )
 
 // DriverConfig embodies the configuration used for defining user driver processor.
@@ -30,6 +42,12 @@ type DriverConfig struct {
 	// JS indicates the configuration values for the JSOtto procs.
 	JS *JSOttoConf `toml:"js" json:"js"`
 
+	// Lua indicates the configuration values for the Lua procs.
+	Lua *LuaConf `toml:"lua" json:"lua"`
+
+	// Starlark indicates the configuration values for the Starlark procs.
+	Starlark *StarlarkConf `toml:"starlark" json:"starlark"`
+
 	// Binary indicates the configuration values to be used for the BinaryRunc procs.
 	Binary *BinaryConf `toml:"binary" json:"binary"`
 }
@@ -42,6 +60,18 @@ func (dc *DriverConfig) Validate() error {
 		}
 	}
 
+	if dc.Lua != nil {
+		if err := dc.Lua.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if dc.Starlark != nil {
+		if err := dc.Starlark.Validate(); err != nil {
+			return err
+		}
+	}
+
 	if dc.Binary != nil {
 		if err := dc.Binary.Validate(); err != nil {
 			return err
@@ -143,6 +173,32 @@ func (dc *DatasetConfig) Validate() error {
 	return nil
 }
 
+// javascript engines ...
+const (
+	// JSOttoEngine runs scripts with the otto vm, which supports ES5.
+	JSOttoEngine = "otto"
+
+	// JSModernEngine runs scripts with the goja vm, which supports ES2015+ like
+	// let, arrow functions, template strings and destructuring.
+	JSModernEngine = "modern"
+)
+
+// javascript invocation modes ...
+const (
+	// JSBatchMode calls the target function with the batch of records as a
+	// json string, expecting a json string or array of records in return.
+	JSBatchMode = "batch"
+
+	// JSRecordMode calls the target function with each record, expecting null
+	// to drop it, a record or an array of records in return.
+	JSRecordMode = "record"
+
+	// JSReduceMode calls the target function with an accumulator and each record,
+	// expecting the next accumulator in return, which is emitted once the source
+	// has no more records.
+	JSReduceMode = "reduce"
+)
+
 // JSOttoConf embodies data used to define the javascript files used for
 // providing user processing function for conversion of incoming mongo data
 // using the otto javascript vm. https://github.com/robertkrimen/otto.
@@ -150,10 +206,61 @@ type JSOttoConf struct {
 	Main      string   `toml:"main" json:"main"`
 	Target    string   `toml:"target" json:"target"`
 	Libraries []string `toml:"libraries" json:"libraries"`
+
+	// Engine sets the javascript engine running the scripts: otto or modern.
+	// Defaults to otto.
+	Engine string `toml:"engine" json:"engine"`
+
+	// ModulePaths sets the directories searched in order for modules required by
+	// scripts with ids not starting with './', '../' or '/', where relative ids
+	// are resolved from the directory of the requiring file, or of Main.
+	ModulePaths []string `toml:"module_paths" json:"module_paths"`
+
+	// PoolSize sets the total javascript vms loaded with the Libraries and Main
+	// files, which limits the batches transformed in parallel. Defaults to
+	// the number of CPUs.
+	PoolSize int `toml:"pool_size" json:"pool_size"`
+
+	// Timeout sets the maximum time the target function may run for a batch,
+	// after which it is interrupted. Runs are only interrupted when their
+	// context is done if not set.
+	Timeout string `toml:"timeout" json:"timeout"`
+
+	// Mode sets how the target function is called: batch, record or reduce.
+	// Defaults to batch.
+	Mode string `toml:"mode" json:"mode"`
+
+	// Initial sets the initial accumulator of the reduce mode, defaults to an
+	// empty object.
+	Initial interface{} `toml:"initial" json:"initial"`
+
+	// Native sets records to be passed to the target function as native javascript
+	// objects, instead of a json string in batch mode, where the function may return
+	// native objects. This avoids encoding and decoding records as json.
+	Native bool `toml:"native" json:"native"`
+
+	// Env sets the names of environment variables scripts may read with host.env.
+	Env []string `toml:"env" json:"env"`
+
+	// DeadLetter sets the file records passed to host.warn are appended to, as
+	// lines of json with the warning. Warned records are only logged if not set.
+	DeadLetter string `toml:"dead_letter" json:"dead_letter"`
+
+	// Reload enables the reloading of the Main, Libraries and required module files
+	// when they change, which are checked between batches every ReloadInterval.
+	Reload         bool   `toml:"reload" json:"reload"`
+	ReloadInterval string `toml:"reload_interval" json:"reload_interval"`
+
+	// TimeoutDuration gets the timeout value provided through the `Timeout` field.
+	TimeoutDuration time.Duration `toml:"-" json:"-"`
+
+	// ReloadDuration gets the interval value provided through the `ReloadInterval`
+	// field or is set to DefaultReloadInterval.
+	ReloadDuration time.Duration `toml:"-" json:"-"`
 }
 
 // Validate returns an error if the config is invalid.
-func (jsc JSOttoConf) Validate() error {
+func (jsc *JSOttoConf) Validate() error {
 	if jsc.Target == "" {
 		return errors.New("JSOttoConf.Target is required")
 	}
@@ -162,6 +269,59 @@ func (jsc JSOttoConf) Validate() error {
 		return errors.New("JSOttoConf.Main is required")
 	}
 
+	if jsc.PoolSize < 0 {
+		return errors.New("JSOttoConf.PoolSize can't be negative")
+	}
+
+	jsc.Engine = strings.ToLower(jsc.Engine)
+
+	switch jsc.Engine {
+	case "":
+		jsc.Engine = JSOttoEngine
+	case JSOttoEngine, JSModernEngine:
+	default:
+		return fmt.Errorf("JSOttoConf.Engine can only be either 'otto' or 'modern' not %q", jsc.Engine)
+	}
+
+	jsc.Mode = strings.ToLower(jsc.Mode)
+
+	switch jsc.Mode {
+	case "":
+		jsc.Mode = JSBatchMode
+	case JSBatchMode, JSRecordMode, JSReduceMode:
+	default:
+		return fmt.Errorf("JSOttoConf.Mode can only be either 'batch', 'record' or 'reduce' not %q", jsc.Mode)
+	}
+
+	if jsc.Timeout != "" {
+		timeout, err := time.ParseDuration(jsc.Timeout)
+		if err != nil {
+			return err
+		}
+
+		if timeout <= 0 {
+			return errors.New("JSOttoConf.Timeout must be positive")
+		}
+
+		jsc.TimeoutDuration = timeout
+	}
+
+	if jsc.Reload {
+		jsc.ReloadDuration = DefaultReloadInterval
+		if jsc.ReloadInterval != "" {
+			interval, err := time.ParseDuration(jsc.ReloadInterval)
+			if err != nil {
+				return err
+			}
+
+			if interval <= 0 {
+				return errors.New("JSOttoConf.ReloadInterval must be positive")
+			}
+
+			jsc.ReloadDuration = interval
+		}
+	}
+
 	stat, err := os.Stat(jsc.Main)
 	if err != nil {
 		return fmt.Errorf("JSOttoConf.Main must exists: %+s", err.Error())
@@ -174,6 +334,124 @@ func (jsc JSOttoConf) Validate() error {
 	return nil
 }
 
+// LuaConf embodies data used to define the lua files used for providing user
+// processing function for conversion of incoming records using the gopher-lua
+// vm. https://github.com/yuin/gopher-lua.
+type LuaConf struct {
+	Main      string   `toml:"main" json:"main"`
+	Target    string   `toml:"target" json:"target"`
+	Libraries []string `toml:"libraries" json:"libraries"`
+
+	// PoolSize sets the total lua vms loaded with the Libraries and Main files,
+	// which limits the batches transformed in parallel. Defaults to the number
+	// of CPUs.
+	PoolSize int `toml:"pool_size" json:"pool_size"`
+
+	// Timeout sets the maximum time the target function may run for a batch,
+	// after which it is interrupted. Defaults to DefaultLuaTimeout.
+	Timeout string `toml:"timeout" json:"timeout"`
+
+	// TimeoutDuration gets the timeout value provided through the `Timeout` field
+	// or is set to DefaultLuaTimeout.
+	TimeoutDuration time.Duration `toml:"-" json:"-"`
+}
+
+// Validate returns an error if the config is invalid.
+func (lc *LuaConf) Validate() error {
+	if lc.Target == "" {
+		return errors.New("LuaConf.Target is required")
+	}
+
+	if lc.Main == "" {
+		return errors.New("LuaConf.Main is required")
+	}
+
+	if lc.PoolSize < 0 {
+		return errors.New("LuaConf.PoolSize can't be negative")
+	}
+
+	lc.TimeoutDuration = DefaultLuaTimeout
+	if lc.Timeout != "" {
+		timeout, err := time.ParseDuration(lc.Timeout)
+		if err != nil {
+			return err
+		}
+
+		if timeout <= 0 {
+			return errors.New("LuaConf.Timeout must be positive")
+		}
+
+		lc.TimeoutDuration = timeout
+	}
+
+	stat, err := os.Stat(lc.Main)
+	if err != nil {
+		return fmt.Errorf("LuaConf.Main must exists: %+s", err.Error())
+	}
+
+	if stat.IsDir() {
+		return errors.New("LuaConf.Main can't point to a directory")
+	}
+
+	return nil
+}
+
+// StarlarkConf embodies data used to define the starlark file used for providing
+// user processing function for conversion of incoming records, which is run
+// deterministically within a sandbox. https://github.com/google/starlark-go.
+type StarlarkConf struct {
+	Main   string `toml:"main" json:"main"`
+	Target string `toml:"target" json:"target"`
+
+	// LoadDir sets the directory modules loaded by load statements are resolved
+	// from, which they can't be loaded from outside of. Defaults to the directory
+	// of Main.
+	LoadDir string `toml:"load_dir" json:"load_dir"`
+
+	// MaxSteps sets the maximum steps the target function may execute for a
+	// batch, after which it is cancelled. Defaults to DefaultStarlarkMaxSteps.
+	MaxSteps uint64 `toml:"max_steps" json:"max_steps"`
+}
+
+// Validate returns an error if the config is invalid.
+func (sc *StarlarkConf) Validate() error {
+	if sc.Target == "" {
+		return errors.New("StarlarkConf.Target is required")
+	}
+
+	if sc.Main == "" {
+		return errors.New("StarlarkConf.Main is required")
+	}
+
+	if sc.MaxSteps == 0 {
+		sc.MaxSteps = DefaultStarlarkMaxSteps
+	}
+
+	stat, err := os.Stat(sc.Main)
+	if err != nil {
+		return fmt.Errorf("StarlarkConf.Main must exists: %+s", err.Error())
+	}
+
+	if stat.IsDir() {
+		return errors.New("StarlarkConf.Main can't point to a directory")
+	}
+
+	if sc.LoadDir == "" {
+		sc.LoadDir = filepath.Dir(sc.Main)
+	}
+
+	stat, err = os.Stat(sc.LoadDir)
+	if err != nil {
+		return fmt.Errorf("StarlarkConf.LoadDir must exists: %+s", err.Error())
+	}
+
+	if !stat.IsDir() {
+		return errors.New("StarlarkConf.LoadDir must point to a directory")
+	}
+
+	return nil
+}
+
 // BinaryConf embodies data to be used to define the go binary used for processing
 // incoming data from the mongo collection.
 type BinaryConf struct {
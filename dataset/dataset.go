@@ -3,20 +3,34 @@ package dataset
 import (
 	"context"
 	"errors"
+	"fmt"
 )
 
 // errors ...
 var (
 	ErrNoMore   = errors.New("no more records available")
 	ErrBatchLen = errors.New("invalid batch length received")
+	ErrTooDeep  = fmt.Errorf("value nested deeper than %d levels, it may contain itself", MaxDepth)
 )
 
+// MaxDepth sets the deepest nesting of values converted out of scripts, which
+// stops values containing themselves from being converted without end.
+const MaxDepth = 1000
+
 // Proc defines an interface which embodies a a processor of
 // records.
 type Proc interface {
 	Transform(context.Context, ...map[string]interface{}) ([]map[string]interface{}, error)
 }
 
+// ProcFlush defines an interface for a Proc which holds records across calls to
+// Transform, handing them to push once the source has no more records. Held records
+// are only released once push succeeds, so they are handed again by the next flush
+// if push fails.
+type ProcFlush interface {
+	Flush(ctx context.Context, push func([]map[string]interface{}) error) error
+}
+
 // DataPull defines an interface which exposes a pull method to
 // collect specific amount of records from underline store.
 type DataPull interface {
@@ -44,6 +58,53 @@ func (dp DataPushers) Push(ctx context.Context, recs ...map[string]interface{})
 	return nil
 }
 
+// Procs implements the Proc for a slice of Proc items, where each is called
+// with the records returned by the previous one. Nil items are skipped.
+type Procs []Proc
+
+// Transform runs all Proc within slice type in order and returns when a proc meets
+// an error or when all procs have successfully transformed provided map records.
+func (ps Procs) Transform(ctx context.Context, recs ...map[string]interface{}) ([]map[string]interface{}, error) {
+	for _, proc := range ps {
+		if proc == nil {
+			continue
+		}
+
+		transformed, err := proc.Transform(ctx, recs...)
+		if err != nil {
+			return nil, err
+		}
+
+		recs = transformed
+	}
+	return recs, nil
+}
+
+// Flush flushes all Proc within slice type which implement ProcFlush in order, where
+// records flushed by a proc are transformed by the procs after it before being
+// handed to push.
+func (ps Procs) Flush(ctx context.Context, push func([]map[string]interface{}) error) error {
+	for index, proc := range ps {
+		flusher, ok := proc.(ProcFlush)
+		if !ok {
+			continue
+		}
+
+		rest := ps[index+1:]
+		if err := flusher.Flush(ctx, func(recs []map[string]interface{}) error {
+			transformed, err := rest.Transform(ctx, recs...)
+			if err != nil {
+				return err
+			}
+
+			return push(transformed)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Dataset implements a custom data processor which takes implementations
 // of the DataPull and DataPush(optional) interfaces, where the provided Procs
 // instance processes data received from the Pull and stored into the Push
@@ -58,7 +119,8 @@ type Dataset struct {
 // the puller into the pushers list.
 // Do is to be used recursively, where every call processes the next batch taking
 // from the the puller and processed, if an error occured, then that error will be
-// returned.
+// returned. Once the puller has no more records, records held by a Proc implementing
+// ProcFlush are flushed into the pushers before ErrNoMore is returned.
 func (ds Dataset) Do(ctx context.Context, pullBatch int, pushBatch int) error {
 	if pullBatch <= 0 || pushBatch <= 0 {
 		return ErrBatchLen
@@ -66,12 +128,15 @@ func (ds Dataset) Do(ctx context.Context, pullBatch int, pushBatch int) error {
 
 	recs, err := ds.Pull.Pull(ctx, pullBatch)
 	if err != nil {
+		if err == ErrNoMore {
+			return ds.flush(ctx, pushBatch)
+		}
 		return err
 	}
 
 	// if pull returns zero then we are probably done pulling, so return no more.
 	if len(recs) == 0 {
-		return ErrNoMore
+		return ds.flush(ctx, pushBatch)
 	}
 
 	procRecs, err := ds.Proc.Transform(ctx, recs...)
@@ -79,25 +144,65 @@ func (ds Dataset) Do(ctx context.Context, pullBatch int, pushBatch int) error {
 		return err
 	}
 
-	if pushBatch >= len(procRecs) || pullBatch < pushBatch {
-		return ds.Pushers.Push(ctx, procRecs...)
+	return ds.push(ctx, procRecs, pushBatch)
+}
+
+// flush pushes the records flushed by the Proc, if it implements ProcFlush,
+// returning ErrNoMore once done. Records which failed to be pushed are kept by
+// the Proc, so they are pushed again by the next call of Do.
+func (ds Dataset) flush(ctx context.Context, pushBatch int) error {
+	flusher, ok := ds.Proc.(ProcFlush)
+	if !ok {
+		return ErrNoMore
 	}
 
-	totalBatch := len(recs) / pushBatch
-	for i := 1; i <= totalBatch; i++ {
-		if pushBatch >= len(recs) {
-			if err = ds.Pushers.Push(ctx, recs...); err != nil {
-				return err
-			}
-			return nil
+	if err := flusher.Flush(ctx, func(recs []map[string]interface{}) error {
+		return ds.push(ctx, recs, pushBatch)
+	}); err != nil {
+		return err
+	}
+
+	return ErrNoMore
+}
+
+// push pushes the records into the pushers, atmost pushBatch records at a time.
+func (ds Dataset) push(ctx context.Context, recs []map[string]interface{}, pushBatch int) error {
+	for len(recs) != 0 {
+		next := recs
+		if len(next) > pushBatch {
+			next = recs[:pushBatch]
 		}
 
-		next := recs[:pushBatch]
-		recs = recs[pushBatch:]
-		if err = ds.Pushers.Push(ctx, next...); err != nil {
+		recs = recs[len(next):]
+		if err := ds.Pushers.Push(ctx, next...); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// RecordsOf appends the records of value into res, where value is the result of
+// a script decoded into go values, which is either nil, a record or a list of
+// records. Nil items of the list are skipped.
+func RecordsOf(res []map[string]interface{}, value interface{}) ([]map[string]interface{}, error) {
+	switch item := value.(type) {
+	case nil:
+		return res, nil
+	case map[string]interface{}:
+		return append(res, item), nil
+	case []interface{}:
+		for _, elem := range item {
+			switch record := elem.(type) {
+			case nil:
+			case map[string]interface{}:
+				res = append(res, record)
+			default:
+				return nil, fmt.Errorf("invalid type received: expected record but got %T", elem)
+			}
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("invalid type received: expected nil, record or list of records but got %T", value)
+	}
+}
@@ -106,6 +106,219 @@ func TestDataset(t *testing.T) {
 	}
 }
 
+func TestDatasetPushBatch(t *testing.T) {
+	pusher := &mockaPush{}
+
+	var pushed []map[string]interface{}
+	pusher.Fn = func(recs ...map[string]interface{}) error {
+		if len(recs) > 2 {
+			return fmt.Errorf("expected to have being requested to pushed atmost %d records but got %d", 2, len(recs))
+		}
+
+		pushed = append(pushed, recs...)
+		return nil
+	}
+
+	set := dataset.Dataset{
+		Pull:    mockaPull{},
+		Proc:    dataset.Procs{mockaProc{}, mockaDouble{}},
+		Pushers: dataset.DataPushers{pusher},
+	}
+
+	if err := set.Do(context.Background(), 3, 2); err != nil {
+		tests.FailedWithError(err, "Should have successfully processed records")
+	}
+	tests.Passed("Should have successfully processed records")
+
+	if len(pushed) != 6 {
+		tests.Failed("Should have pushed all 6 transformed records in batches but got %d", len(pushed))
+	}
+	tests.Passed("Should have pushed all 6 transformed records in batches")
+
+	for _, rec := range pushed {
+		if _, ok := rec["score"].(int); !ok {
+			tests.Failed("Should have pushed transformed records but got %#v", rec)
+		}
+	}
+	tests.Passed("Should have pushed transformed records")
+}
+
+func TestProcsSkipsNil(t *testing.T) {
+	procs := dataset.Procs{nil, mockaProc{}, nil, &mockaCount{}}
+
+	recs, err := procs.Transform(context.Background(), map[string]interface{}{"scores": 23})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully transformed records")
+	}
+	tests.Passed("Should have successfully transformed records")
+
+	if len(recs) != 0 {
+		tests.Failed("Should have received no records from last proc but got %d", len(recs))
+	}
+	tests.Passed("Should have received no records from last proc")
+
+	var flushed []map[string]interface{}
+	if err := procs.Flush(context.Background(), func(recs []map[string]interface{}) error {
+		flushed = append(flushed, recs...)
+		return nil
+	}); err != nil {
+		tests.FailedWithError(err, "Should have successfully flushed records")
+	}
+
+	if len(flushed) != 1 || flushed[0]["total"] != 1 {
+		tests.Failed("Should have flushed records of proc after nil procs: %#v", flushed)
+	}
+	tests.Passed("Should have flushed records of proc after nil procs")
+}
+
+func TestRecordsOf(t *testing.T) {
+	record := map[string]interface{}{"user": "alex"}
+	prior := []map[string]interface{}{{"user": "bob"}}
+
+	cases := []struct {
+		value interface{}
+		total int
+	}{
+		{value: nil, total: 0},
+		{value: record, total: 1},
+		{value: []interface{}{record, nil, record}, total: 2},
+	}
+
+	for _, item := range cases {
+		recs, err := dataset.RecordsOf(prior, item.value)
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully received records of %#v", item.value)
+		}
+
+		if len(recs) != item.total+1 || recs[0]["user"] != "bob" {
+			tests.Failed("Should have appended %d records of %#v to prior records but got %#v", item.total, item.value, recs)
+		}
+
+		for _, rec := range recs[1:] {
+			if rec["user"] != "alex" {
+				tests.Failed("Should have received records of %#v but got %#v", item.value, recs)
+			}
+		}
+		tests.Passed("Should have appended %d records of %#v to prior records", item.total, item.value)
+	}
+
+	for _, value := range []interface{}{"alex", []interface{}{record, 20}} {
+		if _, err := dataset.RecordsOf(nil, value); err == nil {
+			tests.Failed("Should have failed to receive records of %#v", value)
+		}
+		tests.Passed("Should have failed to receive records of %#v", value)
+	}
+}
+
+func TestDatasetFlush(t *testing.T) {
+	proc := &mockaCount{}
+	pusher := &mockaPush{}
+
+	var pushed []map[string]interface{}
+	pusher.Fn = func(recs ...map[string]interface{}) error {
+		pushed = append(pushed, recs...)
+		return nil
+	}
+
+	set := dataset.Dataset{
+		Pull:    &mockaLimitPull{Total: 2},
+		Proc:    proc,
+		Pushers: dataset.DataPushers{pusher},
+	}
+
+	for {
+		err := set.Do(context.Background(), 3, 3)
+		if err == dataset.ErrNoMore {
+			break
+		}
+
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully processed records")
+		}
+	}
+	tests.Passed("Should have successfully processed records")
+
+	if len(pushed) != 1 || pushed[0]["total"] != 6 {
+		tests.Failed("Should have pushed flushed records once source had no more records: %#v", pushed)
+	}
+	tests.Passed("Should have pushed flushed records once source had no more records")
+}
+
+func TestDatasetFlushFailedPush(t *testing.T) {
+	proc := &mockaCount{}
+	pusher := &mockaPush{}
+
+	failed := errors.New("push failed")
+	pusher.Fn = func(recs ...map[string]interface{}) error {
+		return failed
+	}
+
+	set := dataset.Dataset{
+		Pull:    &mockaLimitPull{Total: 1},
+		Proc:    dataset.Procs{mockaProc{}, proc},
+		Pushers: dataset.DataPushers{pusher},
+	}
+
+	if err := set.Do(context.Background(), 3, 3); err != nil {
+		tests.FailedWithError(err, "Should have successfully processed records")
+	}
+
+	if err := set.Do(context.Background(), 3, 3); err != failed {
+		tests.Failed("Should have received error of failed push of flushed records but got %v", err)
+	}
+	tests.Passed("Should have received error of failed push of flushed records")
+
+	var pushed []map[string]interface{}
+	pusher.Fn = func(recs ...map[string]interface{}) error {
+		pushed = append(pushed, recs...)
+		return nil
+	}
+
+	if err := set.Do(context.Background(), 3, 3); err != dataset.ErrNoMore {
+		tests.Failed("Should have flushed records again once source had no more records but got %v", err)
+	}
+
+	if len(pushed) != 1 || pushed[0]["total"] != 3 {
+		tests.Failed("Should have kept flushed records of failed push: %#v", pushed)
+	}
+	tests.Passed("Should have kept flushed records of failed push")
+}
+
+type mockaLimitPull struct {
+	Total int
+}
+
+func (m *mockaLimitPull) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	if m.Total == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	m.Total--
+	return mockaPull{}.Pull(ctx, batch)
+}
+
+type mockaCount struct {
+	total int
+}
+
+func (m *mockaCount) Transform(ctx context.Context, recs ...map[string]interface{}) ([]map[string]interface{}, error) {
+	m.total += len(recs)
+	return nil, nil
+}
+
+func (m *mockaCount) Flush(ctx context.Context, push func([]map[string]interface{}) error) error {
+	if m.total == 0 {
+		return nil
+	}
+
+	if err := push([]map[string]interface{}{{"total": m.total}}); err != nil {
+		return err
+	}
+
+	m.total = 0
+	return nil
+}
+
 type mockaPush struct {
 	Fn func(...map[string]interface{}) error
 }
@@ -166,3 +379,9 @@ func getScore(item interface{}) int {
 		return 0
 	}
 }
+
+type mockaDouble struct{}
+
+func (m mockaDouble) Transform(ctx context.Context, recs ...map[string]interface{}) ([]map[string]interface{}, error) {
+	return append(recs, recs...), nil
+}
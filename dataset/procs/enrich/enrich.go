@@ -0,0 +1,504 @@
+package enrich
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/faux/db/mongo"
+	"github.com/influx6/faux/metrics"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/pullers/jsonfiles"
+	"github.com/influx6/geckodataset/dataset/pullers/mongodb"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// loadBatch sets the total rows read per pull when loading json tables.
+const loadBatch = 500
+
+// lookup sources ...
+const (
+	JSONLookup  = "json"
+	CSVLookup   = "csv"
+	MongoLookup = "mongodb"
+)
+
+// missing key policies ...
+const (
+	MissingNull    = "null"
+	MissingDefault = "default"
+	MissingDrop    = "drop"
+)
+
+// Config embodies data used to define the lookup tables used by the Enricher
+// to add fields to incoming records by key.
+type Config struct {
+	Tables []LookupConfig `toml:"tables" json:"tables"`
+}
+
+// Validate returns an error if the config is invalid.
+func (ec *Config) Validate() error {
+	if len(ec.Tables) == 0 {
+		return errors.New("Config.Tables is required")
+	}
+
+	for index := range ec.Tables {
+		if err := ec.Tables[index].Validate(); err != nil {
+			return fmt.Errorf("Config.Tables[%d]: %+s", index, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// LookupConfig embodies data used to define a lookup table loaded from a json file,
+// a csv file or a mongodb collection, whose rows are matched to records by key.
+type LookupConfig struct {
+	// Source sets the type of the table source: json, csv or mongodb.
+	Source string `toml:"source" json:"source"`
+
+	// Path sets the path of the json or csv file, where csv files must start
+	// with a header row naming the fields of the table.
+	Path string `toml:"path" json:"path"`
+
+	// DB and Collection set the mongodb collection of the table.
+	DB         mongo.Config `toml:"db" json:"db"`
+	Collection string       `toml:"collection" json:"collection"`
+
+	// Key sets the field of the table rows matched against the RecordKey field
+	// of records, which defaults to Key.
+	Key       string `toml:"key" json:"key"`
+	RecordKey string `toml:"record_key" json:"record_key"`
+
+	// Fields sets the fields of the table rows added to records, defaults to
+	// all fields of the rows except Key.
+	Fields []string `toml:"fields" json:"fields"`
+
+	// Refresh sets the interval at which the table is reloaded from it's source,
+	// it is only loaded once if not set.
+	Refresh string `toml:"refresh" json:"refresh"`
+
+	// OnMissing sets the behaviour for records without a matching row: null sets
+	// the fields to null, default sets the fields to their value in Defaults and
+	// drop removes the record. Defaults to null.
+	OnMissing string                 `toml:"on_missing" json:"on_missing"`
+	Defaults  map[string]interface{} `toml:"defaults" json:"defaults"`
+
+	// RefreshInterval gets the interval value provided through the `Refresh` field.
+	RefreshInterval time.Duration `toml:"-" json:"-"`
+}
+
+// Validate returns an error if the config is invalid.
+func (lc *LookupConfig) Validate() error {
+	lc.Source = strings.ToLower(lc.Source)
+
+	switch lc.Source {
+	case JSONLookup, CSVLookup:
+		if lc.Path == "" {
+			return errors.New("LookupConfig.Path is required")
+		}
+
+		stat, err := os.Stat(lc.Path)
+		if err != nil {
+			return fmt.Errorf("LookupConfig.Path must exists: %+s", err.Error())
+		}
+
+		if stat.IsDir() {
+			return errors.New("LookupConfig.Path can't point to a directory")
+		}
+	case MongoLookup:
+		if lc.Collection == "" {
+			return errors.New("LookupConfig.Collection is required")
+		}
+
+		if err := lc.DB.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("LookupConfig.Source can only be either 'json', 'csv' or 'mongodb' not %q", lc.Source)
+	}
+
+	if lc.Key == "" {
+		return errors.New("LookupConfig.Key is required")
+	}
+
+	if lc.RecordKey == "" {
+		lc.RecordKey = lc.Key
+	}
+
+	lc.OnMissing = strings.ToLower(lc.OnMissing)
+
+	switch lc.OnMissing {
+	case "":
+		lc.OnMissing = MissingNull
+	case MissingNull, MissingDefault, MissingDrop:
+	default:
+		return fmt.Errorf("LookupConfig.OnMissing can only be either 'null', 'default' or 'drop' not %q", lc.OnMissing)
+	}
+
+	if lc.Refresh != "" {
+		refresh, err := time.ParseDuration(lc.Refresh)
+		if err != nil {
+			return err
+		}
+
+		if refresh <= 0 {
+			return errors.New("LookupConfig.Refresh must be positive")
+		}
+
+		lc.RefreshInterval = refresh
+	}
+
+	return nil
+}
+
+// Enricher implements dataset.Proc which adds fields to records from lookup tables
+// matched by key. Tables are loaded when created and reloaded at their refresh
+// interval, where a failed reload keeps the previously loaded rows and is emitted
+// into the metrics.
+type Enricher struct {
+	tables  []*table
+	metrics metrics.Metrics
+	closer  chan struct{}
+	waiter  sync.WaitGroup
+	once    sync.Once
+}
+
+// New returns a new instance of Enricher, which loads all tables of the config, where
+// failed reloads of tables are emitted into the metrics.
+func New(conf Config, m metrics.Metrics) (*Enricher, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	en := &Enricher{closer: make(chan struct{}), metrics: m}
+	for _, lookup := range conf.Tables {
+		tb := &table{config: lookup}
+		if err := tb.load(); err != nil {
+			return nil, fmt.Errorf("failed to load lookup table %q: %+s", tb.name(), err.Error())
+		}
+
+		en.tables = append(en.tables, tb)
+	}
+
+	for _, tb := range en.tables {
+		if tb.config.RefreshInterval == 0 {
+			continue
+		}
+
+		en.waiter.Add(1)
+		go en.refresh(tb)
+	}
+
+	return en, nil
+}
+
+// Close stops the reloading of all tables.
+func (en *Enricher) Close() error {
+	en.once.Do(func() {
+		close(en.closer)
+	})
+
+	en.waiter.Wait()
+	return nil
+}
+
+// Err returns the error of the last failed reload of any table, which is reset
+// once the table is reloaded successfully.
+func (en *Enricher) Err() error {
+	for _, tb := range en.tables {
+		if err := tb.err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Transform adds the fields of matching rows of all tables into the records, returning
+// the records not dropped due to missing rows.
+func (en *Enricher) Transform(ctx context.Context, records ...map[string]interface{}) ([]map[string]interface{}, error) {
+	enriched := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		keep := true
+		for _, tb := range en.tables {
+			if !tb.enrich(record) {
+				keep = false
+				break
+			}
+		}
+
+		if keep {
+			enriched = append(enriched, record)
+		}
+	}
+
+	return enriched, nil
+}
+
+// refresh reloads the table at it's refresh interval until the Enricher is closed.
+func (en *Enricher) refresh(tb *table) {
+	defer en.waiter.Done()
+
+	ticker := time.NewTicker(tb.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-en.closer:
+			return
+		case <-ticker.C:
+			if err := tb.load(); err != nil {
+				err = fmt.Errorf("failed to reload lookup table %q, keeping the loaded rows: %+s", tb.name(), err.Error())
+				en.metrics.Emit(metrics.Errorf("%s", err), metrics.With("lookup", tb.name()))
+				tb.fail(err)
+			}
+		}
+	}
+}
+
+// table holds the rows of a lookup table by their keys.
+type table struct {
+	config  LookupConfig
+	ml      sync.RWMutex
+	rows    map[string]map[string]interface{}
+	lastErr error
+}
+
+// name returns the name of the table's source used in errors.
+func (tb *table) name() string {
+	if tb.config.Source == MongoLookup {
+		return tb.config.Collection
+	}
+	return tb.config.Path
+}
+
+func (tb *table) err() error {
+	tb.ml.RLock()
+	defer tb.ml.RUnlock()
+	return tb.lastErr
+}
+
+func (tb *table) fail(err error) {
+	tb.ml.Lock()
+	defer tb.ml.Unlock()
+	tb.lastErr = err
+}
+
+// enrich adds the fields of the matching row into the record, returning false
+// if the record is to be dropped.
+func (tb *table) enrich(record map[string]interface{}) bool {
+	tb.ml.RLock()
+	defer tb.ml.RUnlock()
+
+	var row map[string]interface{}
+	if key, ok := keyOf(record[tb.config.RecordKey]); ok {
+		row = tb.rows[key]
+	}
+
+	if row == nil {
+		switch tb.config.OnMissing {
+		case MissingDrop:
+			return false
+		case MissingDefault:
+			for _, field := range tb.fields(nil) {
+				record[field] = tb.config.Defaults[field]
+			}
+		default:
+			for _, field := range tb.fields(nil) {
+				record[field] = nil
+			}
+		}
+		return true
+	}
+
+	for _, field := range tb.fields(row) {
+		record[field] = row[field]
+	}
+
+	return true
+}
+
+// fields returns the fields added to records from the giving row, which are the
+// configured fields, or all fields of the row except the key if none are configured.
+func (tb *table) fields(row map[string]interface{}) []string {
+	if len(tb.config.Fields) != 0 {
+		return tb.config.Fields
+	}
+
+	// Missing rows have no fields, so only the fields of the defaults can be set.
+	if row == nil {
+		row = tb.config.Defaults
+	}
+
+	fields := make([]string, 0, len(row))
+	for field := range row {
+		if field != tb.config.Key {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// load replaces the rows of the table with the rows read from it's source.
+func (tb *table) load() error {
+	var rows []map[string]interface{}
+	var err error
+
+	switch tb.config.Source {
+	case JSONLookup:
+		rows, err = loadJSON(tb.config.Path)
+	case CSVLookup:
+		rows, err = loadCSV(tb.config.Path)
+	case MongoLookup:
+		rows, err = loadMongo(tb.config)
+	default:
+		err = fmt.Errorf("unknown lookup source %q", tb.config.Source)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	indexed := make(map[string]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		key, ok := keyOf(row[tb.config.Key])
+		if !ok {
+			continue
+		}
+
+		indexed[key] = row
+	}
+
+	tb.ml.Lock()
+	defer tb.ml.Unlock()
+
+	tb.rows = indexed
+	tb.lastErr = nil
+	return nil
+}
+
+// keyOf returns the key of the giving value, returning false if it is null.
+// Values are formatted as text, where numbers are formatted without exponents
+// or trailing zeros, so a decoded 1234567.0 matches it's csv representation.
+func keyOf(value interface{}) (string, bool) {
+	switch item := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return item, true
+	case float64:
+		return strconv.FormatFloat(item, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(item), 'f', -1, 32), true
+	case int:
+		return strconv.Itoa(item), true
+	case int32:
+		return strconv.FormatInt(int64(item), 10), true
+	case int64:
+		return strconv.FormatInt(item, 10), true
+	case uint:
+		return strconv.FormatUint(uint64(item), 10), true
+	case uint32:
+		return strconv.FormatUint(uint64(item), 10), true
+	case uint64:
+		return strconv.FormatUint(item, 10), true
+	}
+
+	// Mongo object ids and similar types are keyed by their hex representation.
+	if hexer, ok := value.(interface{ Hex() string }); ok {
+		return hexer.Hex(), true
+	}
+
+	return fmt.Sprint(value), true
+}
+
+// loadJSON returns the rows of a json file containing a JSON array of objects
+// or newline delimited JSON objects.
+func loadJSON(path string) ([]map[string]interface{}, error) {
+	reader, err := jsonfiles.OpenJSONReader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer reader.Close()
+
+	var rows []map[string]interface{}
+	for {
+		batch, err := reader.Pull(context.Background(), loadBatch)
+		if err != nil {
+			if err == dataset.ErrNoMore {
+				return rows, nil
+			}
+			return nil, err
+		}
+
+		rows = append(rows, batch...)
+	}
+}
+
+// loadCSV returns the rows of a csv file, whose first row contains the field names.
+func loadCSV(path string) ([]map[string]interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("csv file has no header row")
+		}
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	for {
+		values, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				return rows, nil
+			}
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(header))
+		for index, field := range header {
+			row[field] = values[index]
+		}
+
+		rows = append(rows, row)
+	}
+}
+
+// loadMongo returns all documents of the table's mongodb collection, normalised like
+// the records pulled by the mongodb puller, so their keys match.
+func loadMongo(conf LookupConfig) ([]map[string]interface{}, error) {
+	session, err := mongodb.Dial(conf.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	defer session.Close()
+
+	var docs []bson.M
+	if err := session.DB(conf.DB.DB).C(conf.Collection).Find(nil).All(&docs); err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		rows = append(rows, mongodb.Normalize(doc).(map[string]interface{}))
+	}
+
+	return rows, nil
+}
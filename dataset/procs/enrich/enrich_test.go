@@ -0,0 +1,269 @@
+package enrich_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/faux/metrics"
+	"github.com/influx6/faux/metrics/custom"
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset/procs/enrich"
+)
+
+func TestEnricher(t *testing.T) {
+	en, err := enrich.New(enrich.Config{
+		Tables: []enrich.LookupConfig{
+			{
+				Source:    "json",
+				Path:      "./fixtures/regions.json",
+				Key:       "code",
+				RecordKey: "country",
+				Fields:    []string{"region"},
+				OnMissing: "default",
+				Defaults:  map[string]interface{}{"region": "Unknown"},
+			},
+			{
+				Source:    "csv",
+				Path:      "./fixtures/products.csv",
+				Key:       "id",
+				RecordKey: "product_id",
+			},
+		},
+	}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Enricher")
+	}
+	tests.Passed("Should have successfully created Enricher")
+
+	defer en.Close()
+
+	res, err := en.Transform(context.Background(),
+		map[string]interface{}{"country": "UK", "product_id": 1},
+		map[string]interface{}{"country": "FR", "product_id": float64(2)},
+		map[string]interface{}{"country": "US", "product_id": 3},
+	)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully enriched records")
+	}
+	tests.Passed("Should have successfully enriched records")
+
+	if len(res) != 3 {
+		tests.Failed("Should have received 3 records but got %d", len(res))
+	}
+	tests.Passed("Should have received 3 records")
+
+	if res[0]["region"] != "Europe" || res[0]["product"] != "Keyboard" || res[0]["category"] != "Hardware" {
+		tests.Failed("Should have added matching fields to record: %#v", res[0])
+	}
+	tests.Passed("Should have added matching fields to record")
+
+	if _, ok := res[0]["currency"]; ok {
+		tests.Failed("Should have only added configured fields to record")
+	}
+	tests.Passed("Should have only added configured fields to record")
+
+	if res[1]["region"] != "Unknown" || res[1]["product"] != "Editor" {
+		tests.Failed("Should have added default for missing key: %#v", res[1])
+	}
+	tests.Passed("Should have added default for missing key")
+
+	if res[2]["region"] != "Americas" {
+		tests.Failed("Should have added matching region to record: %#v", res[2])
+	}
+	tests.Passed("Should have added matching region to record")
+}
+
+func TestEnricherNumericKeys(t *testing.T) {
+	en, err := enrich.New(enrich.Config{
+		Tables: []enrich.LookupConfig{
+			{
+				Source:    "csv",
+				Path:      "./fixtures/products.csv",
+				Key:       "id",
+				RecordKey: "product_id",
+				OnMissing: "drop",
+			},
+		},
+	}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Enricher")
+	}
+	tests.Passed("Should have successfully created Enricher")
+
+	defer en.Close()
+
+	// Keys decoded from json are floats, which must not be keyed in exponent form.
+	res, err := en.Transform(context.Background(),
+		map[string]interface{}{"product_id": float64(1234567)},
+		map[string]interface{}{"product_id": int64(1234567)},
+		map[string]interface{}{"product_id": "1234567"},
+	)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully enriched records")
+	}
+	tests.Passed("Should have successfully enriched records")
+
+	if len(res) != 3 {
+		tests.Failed("Should have matched all numeric keys of 1e6 or more but got %d records", len(res))
+	}
+	tests.Passed("Should have matched all numeric keys of 1e6 or more")
+
+	for _, record := range res {
+		if record["product"] != "Monitor" {
+			tests.Failed("Should have added matching fields to record: %#v", record)
+		}
+	}
+	tests.Passed("Should have added matching fields to records with numeric keys")
+}
+
+func TestEnricherMissingKeys(t *testing.T) {
+	tests.Header("Should set fields to null for missing keys")
+	{
+		en, err := enrich.New(enrich.Config{
+			Tables: []enrich.LookupConfig{
+				{Source: "json", Path: "./fixtures/regions.json", Key: "code", Fields: []string{"region"}},
+			},
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created Enricher")
+		}
+		tests.Passed("Should have successfully created Enricher")
+
+		res, err := en.Transform(context.Background(), map[string]interface{}{"code": "FR"}, map[string]interface{}{})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully enriched records")
+		}
+		tests.Passed("Should have successfully enriched records")
+
+		for _, record := range res {
+			if value, ok := record["region"]; !ok || value != nil {
+				tests.Failed("Should have set region to null: %#v", record)
+			}
+		}
+		tests.Passed("Should have set region to null")
+		en.Close()
+	}
+
+	tests.Header("Should drop records with missing keys")
+	{
+		en, err := enrich.New(enrich.Config{
+			Tables: []enrich.LookupConfig{
+				{Source: "json", Path: "./fixtures/regions.json", Key: "code", OnMissing: "drop"},
+			},
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created Enricher")
+		}
+		tests.Passed("Should have successfully created Enricher")
+
+		res, err := en.Transform(context.Background(), map[string]interface{}{"code": "FR"}, map[string]interface{}{"code": "US"})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully enriched records")
+		}
+		tests.Passed("Should have successfully enriched records")
+
+		if len(res) != 1 || res[0]["code"] != "US" || res[0]["currency"] != "USD" {
+			tests.Failed("Should have only kept matched record: %#v", res)
+		}
+		tests.Passed("Should have only kept matched record")
+		en.Close()
+	}
+
+	tests.Header("Should reject unknown missing key policy")
+	{
+		_, err := enrich.New(enrich.Config{
+			Tables: []enrich.LookupConfig{
+				{Source: "json", Path: "./fixtures/regions.json", Key: "code", OnMissing: "skip"},
+			},
+		}, metrics.New())
+		if err == nil {
+			tests.Failed("Should have failed to create Enricher")
+		}
+		tests.Passed("Should have failed to create Enricher")
+	}
+}
+
+func TestEnricherRefresh(t *testing.T) {
+	dir, err := ioutil.TempDir("", "enrich")
+	if err != nil {
+		tests.FailedWithError(err, "Should have created temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rates.csv")
+	if err := ioutil.WriteFile(path, []byte("currency,rate\nGBP,1.2\n"), 0644); err != nil {
+		tests.FailedWithError(err, "Should have written lookup table")
+	}
+
+	var events bytes.Buffer
+	en, err := enrich.New(enrich.Config{
+		Tables: []enrich.LookupConfig{
+			{Source: "csv", Path: path, Key: "currency", Refresh: "20ms"},
+		},
+	}, metrics.New(custom.StackDisplay(&events)))
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Enricher")
+	}
+	tests.Passed("Should have successfully created Enricher")
+
+	defer en.Close()
+
+	res, err := en.Transform(context.Background(), map[string]interface{}{"currency": "GBP"})
+	if err != nil || res[0]["rate"] != "1.2" {
+		tests.Failed("Should have added initial rate to record: %#v", res)
+	}
+	tests.Passed("Should have added initial rate to record")
+
+	if err := ioutil.WriteFile(path, []byte("currency,rate\nGBP,1.3\n"), 0644); err != nil {
+		tests.FailedWithError(err, "Should have written lookup table")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		res, err = en.Transform(context.Background(), map[string]interface{}{"currency": "GBP"})
+		if err == nil && res[0]["rate"] == "1.3" {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			tests.Failed("Should have added refreshed rate to record: %#v", res)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+	tests.Passed("Should have added refreshed rate to record")
+
+	if err := os.Remove(path); err != nil {
+		tests.FailedWithError(err, "Should have removed lookup table")
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for en.Err() == nil {
+		if time.Now().After(deadline) {
+			tests.Failed("Should have reported failed reload")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+	tests.Passed("Should have reported failed reload")
+
+	res, err = en.Transform(context.Background(), map[string]interface{}{"currency": "GBP"})
+	if err != nil || res[0]["rate"] != "1.3" {
+		tests.Failed("Should have kept previous rows after failed reload: %#v", res)
+	}
+	tests.Passed("Should have kept previous rows after failed reload")
+
+	// Closing waits for reloads to stop, so the events are no longer written.
+	en.Close()
+
+	if !strings.Contains(events.String(), "failed to reload lookup table") {
+		tests.Failed("Should have emitted failed reload into metrics but got %q", events.String())
+	}
+	tests.Passed("Should have emitted failed reload into metrics")
+}
@@ -0,0 +1,21 @@
+function transform(rec){
+	console.log('transforming', rec, 1);
+
+	if (rec.amount < 0) {
+		host.warn(rec, 'negative amount');
+		return null;
+	}
+
+	return {
+		id: rec.id,
+		day: host.date(rec.at),
+		at: host.datetime(new Date(Date.UTC(2018, 0, 2, 3, 4, 5, 678))),
+		epoch: host.datetime(0),
+		amount: host.money(rec.amount, rec.currency),
+		region: host.env('JSOTTO_TEST_REGION'),
+	};
+};
+
+function secret(rec){
+	return {secret: host.env('JSOTTO_TEST_SECRET')};
+};
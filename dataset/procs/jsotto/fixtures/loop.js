@@ -0,0 +1,16 @@
+function ParseRecord(recsJSON){
+	var recs = JSON.parse(recsJSON);
+	if (recs[0].loop) {
+		spin();
+	}
+
+	if (recs[0].fail) {
+		recs.missing.length;
+	}
+
+	return JSON.stringify([{total: recs.length}]);
+};
+
+function spin(){
+	while (true) {}
+};
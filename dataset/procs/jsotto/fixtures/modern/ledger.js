@@ -0,0 +1,21 @@
+class Ledger {
+	constructor() {
+		this.sales = new Map();
+	}
+
+	add(user, ...amounts) {
+		const current = this.sales.get(user) || [];
+		this.sales.set(user, [...current, ...amounts]);
+	}
+
+	users() {
+		return Array.from(this.sales.keys());
+	}
+
+	summary(user) {
+		const amounts = this.sales.get(user) || [];
+		return { total: amounts.reduce((sum, amount) => sum + amount, 0), count: amounts.length };
+	}
+}
+
+module.exports = { Ledger };
@@ -0,0 +1,12 @@
+const { Ledger } = require('./ledger');
+
+const transform = (records) => {
+	const ledger = new Ledger();
+	records.forEach(({ user, sales = [] }) => ledger.add(user, ...sales));
+
+	return ledger.users().map((user) => {
+		const { total, count } = ledger.summary(user);
+		const best = records.find((rec) => rec.user === user && rec.best);
+		return { user, total, label: `${user}: ${count} sales`, best: best ? best.best : null };
+	});
+};
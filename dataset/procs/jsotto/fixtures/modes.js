@@ -0,0 +1,30 @@
+function toSale(rec){
+	if (rec.skip) {
+		return null;
+	}
+
+	if (rec.items) {
+		return rec.items.map(function(item){
+			return {user: rec.user, item: item};
+		});
+	}
+
+	return {user: rec.user, total: rec.price * rec.units};
+};
+
+function sumUnits(acc, rec){
+	acc.units += rec.units;
+	acc.orders++;
+	return acc;
+};
+
+function reshape(recordsJSON){
+	var records = JSON.parse(recordsJSON);
+	switch (records[0].shape) {
+	case "none":
+		return null;
+	case "one":
+		return records[0];
+	}
+	return records;
+};
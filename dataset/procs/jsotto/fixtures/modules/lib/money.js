@@ -0,0 +1,17 @@
+// money converts and formats amounts, keeping it's own scope.
+var rates = require('rates');
+var round = require('./round');
+var calls = 0;
+
+exports.convert = function(amount, currency){
+	calls++;
+	return round(amount * rates[currency]);
+};
+
+exports.format = function(amount){
+	return '$' + amount.toFixed(2);
+};
+
+exports.calls = function(){
+	return calls;
+};
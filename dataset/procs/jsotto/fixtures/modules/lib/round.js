@@ -0,0 +1,3 @@
+module.exports = function(value){
+	return Math.round(value * 100) / 100;
+};
@@ -0,0 +1,22 @@
+var money = require('./lib/money');
+var labels = require('labels');
+
+function transform(recordsJSON){
+	var records = JSON.parse(recordsJSON);
+	return JSON.stringify(records.map(function(rec){
+		return {
+			name: rec.name,
+			total: money.format(money.convert(rec.amount, rec.currency)),
+			label: labels.of(rec.name),
+			calls: money.calls(),
+		};
+	}));
+};
+
+function missing(){
+	try {
+		require('./lib/nothing');
+	} catch (err) {
+		return err.message;
+	}
+};
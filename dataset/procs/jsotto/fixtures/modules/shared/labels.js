@@ -0,0 +1,6 @@
+// labels shares it's round function name with money, without collisions.
+function round(name){
+	return name.toUpperCase();
+}
+
+exports.of = round;
@@ -0,0 +1,27 @@
+function summarize(records){
+	return records.map(function(rec){
+		return {
+			user: rec.user,
+			total: rec.sales.reduce(function(sum, sale){ return sum + sale; }, 0),
+			count: rec.sales.length,
+			at: new Date(Date.UTC(2018, 0, 2, 3, 4, 5)),
+			nested: [[1, 2], [rec.meta.region]],
+			ratio: 1 / 0,
+			missing: undefined,
+			fn: function(){},
+		};
+	});
+};
+
+function summarizeJSON(recordsJSON){
+	return JSON.stringify(summarize(JSON.parse(recordsJSON)));
+};
+
+function year(rec){
+	return {year: rec.at.getUTCFullYear(), id: rec.id};
+};
+
+function cyclic(records){
+	records[0].self = records[0];
+	return records;
+};
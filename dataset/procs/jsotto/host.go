@@ -0,0 +1,322 @@
+package jsotto
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/faux/metrics"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/robertkrimen/otto"
+)
+
+// geckoboard formats ...
+const (
+	// DateLayout sets the layout of date values of Geckoboard datasets.
+	DateLayout = "2006-01-02"
+
+	// DatetimeLayout sets the layout of datetime values of Geckoboard datasets.
+	DatetimeLayout = "2006-01-02T15:04:05Z"
+)
+
+// currencyDecimals holds the number of decimal places of currencies which do not
+// have 2, used to convert money amounts into the minor units of the currency.
+var currencyDecimals = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// DeadLetter embodies a record passed to host.warn by a script, which is written
+// into the dead letter file as a line of json.
+type DeadLetter struct {
+	Record   interface{} `json:"record"`
+	Message  string      `json:"message"`
+	Location string      `json:"location"`
+	At       time.Time   `json:"at"`
+}
+
+// host holds the state shared by the console and host bindings of all vms of
+// the pool.
+type host struct {
+	metrics metrics.Metrics
+	env     map[string]bool
+
+	ml          sync.Mutex
+	deadLetters *os.File
+}
+
+// newHost returns a new instance of host for the configuration, opening the dead
+// letter file if set.
+func newHost(conf config.JSOttoConf, m metrics.Metrics) (*host, error) {
+	h := &host{
+		metrics: m,
+		env:     make(map[string]bool),
+	}
+
+	for _, name := range conf.Env {
+		h.env[name] = true
+	}
+
+	if conf.DeadLetter != "" {
+		file, err := os.OpenFile(conf.DeadLetter, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		h.deadLetters = file
+	}
+
+	return h, nil
+}
+
+// Close closes the dead letter file, if any.
+func (h *host) Close() error {
+	if h.deadLetters == nil {
+		return nil
+	}
+
+	return h.deadLetters.Close()
+}
+
+// bind sets the console and host objects within the vm.
+func (h *host) bind(vm *otto.Otto) error {
+	console, err := vm.Object(`({})`)
+	if err != nil {
+		return err
+	}
+
+	for _, level := range []string{"log", "info", "debug", "warn", "error"} {
+		if err := console.Set(level, h.console(vm, level)); err != nil {
+			return err
+		}
+	}
+
+	if err := vm.Set("console", console); err != nil {
+		return err
+	}
+
+	hostObj, err := vm.Object(`({})`)
+	if err != nil {
+		return err
+	}
+
+	functions := map[string]func(otto.FunctionCall) otto.Value{
+		"warn":     func(call otto.FunctionCall) otto.Value { return h.warn(vm, call) },
+		"env":      func(call otto.FunctionCall) otto.Value { return h.getenv(vm, call) },
+		"date":     func(call otto.FunctionCall) otto.Value { return h.format(vm, call, DateLayout) },
+		"datetime": func(call otto.FunctionCall) otto.Value { return h.format(vm, call, DatetimeLayout) },
+		"money":    func(call otto.FunctionCall) otto.Value { return h.money(vm, call) },
+	}
+
+	for name, fn := range functions {
+		if err := hostObj.Set(name, fn); err != nil {
+			return err
+		}
+	}
+
+	return vm.Set("host", hostObj)
+}
+
+// console returns the console function of the level, which logs it's arguments
+// with the location of the call.
+func (h *host) console(vm *otto.Otto, level string) func(otto.FunctionCall) otto.Value {
+	return func(call otto.FunctionCall) otto.Value {
+		h.log(level, messageOf(vm, call.ArgumentList), locationOf(vm.Context()))
+		return otto.UndefinedValue()
+	}
+}
+
+// warn implements host.warn(record, message).
+func (h *host) warn(vm *otto.Otto, call otto.FunctionCall) otto.Value {
+	record, _, err := fromValue(call.Argument(0))
+	if err != nil {
+		panic(vm.MakeCustomError("Error", err.Error()))
+	}
+
+	var message string
+	if msg := call.Argument(1); msg.IsDefined() {
+		message = msg.String()
+	}
+
+	if err := h.deadLetter(record, message, locationOf(vm.Context())); err != nil {
+		panic(vm.MakeCustomError("Error", err.Error()))
+	}
+
+	return otto.UndefinedValue()
+}
+
+// getenv implements host.env(name).
+func (h *host) getenv(vm *otto.Otto, call otto.FunctionCall) otto.Value {
+	value, ok, err := h.lookupEnv(call.Argument(0).String())
+	if err != nil {
+		panic(vm.MakeCustomError("Error", err.Error()))
+	}
+
+	if !ok {
+		return otto.UndefinedValue()
+	}
+
+	return valueOf(vm, value)
+}
+
+// format implements host.date(value) and host.datetime(value).
+func (h *host) format(vm *otto.Otto, call otto.FunctionCall, layout string) otto.Value {
+	value := call.Argument(0)
+
+	var at interface{}
+	switch {
+	case value.IsNumber():
+		at, _ = value.ToFloat()
+	case value.IsString():
+		at = value.String()
+	case value.IsObject() && value.Class() == "Date":
+		ms, err := value.Object().Call("getTime")
+		if err != nil {
+			panic(vm.MakeTypeError(err.Error()))
+		}
+		at, _ = ms.ToFloat()
+	default:
+		at = value.String()
+	}
+
+	formatted, err := formatTime(at, layout)
+	if err != nil {
+		panic(vm.MakeTypeError(err.Error()))
+	}
+
+	return valueOf(vm, formatted)
+}
+
+// money implements host.money(amount, currency).
+func (h *host) money(vm *otto.Otto, call otto.FunctionCall) otto.Value {
+	amount, err := call.Argument(0).ToFloat()
+	if err != nil {
+		panic(vm.MakeTypeError(err.Error()))
+	}
+
+	var currency string
+	if value := call.Argument(1); value.IsDefined() {
+		currency = value.String()
+	}
+
+	units, err := moneyOf(amount, currency)
+	if err != nil {
+		panic(vm.MakeTypeError(err.Error()))
+	}
+
+	return valueOf(vm, units)
+}
+
+// log emits the console message of the level with the location of the call, where
+// the warn and error levels are emitted as errors.
+func (h *host) log(level string, message string, location string) {
+	if level == "warn" || level == "error" {
+		h.metrics.Emit(metrics.Errorf("%s", message), metrics.With("console", level), metrics.With("location", location))
+		return
+	}
+
+	h.metrics.Emit(metrics.Info(message), metrics.With("console", level), metrics.With("location", location))
+}
+
+// deadLetter emits the record warned by a script with the message, writing it into
+// the dead letter file, if any.
+func (h *host) deadLetter(record interface{}, message string, location string) error {
+	letter := DeadLetter{
+		Record:   record,
+		Message:  message,
+		Location: location,
+		At:       time.Now().UTC(),
+	}
+
+	h.metrics.Emit(metrics.Errorf("%s", message), metrics.With("record", record), metrics.With("location", location))
+
+	if h.deadLetters == nil {
+		return nil
+	}
+
+	h.ml.Lock()
+	defer h.ml.Unlock()
+
+	if err := json.NewEncoder(h.deadLetters).Encode(letter); err != nil {
+		return fmt.Errorf("failed to write dead letter: %+s", err.Error())
+	}
+
+	return nil
+}
+
+// lookupEnv returns the value of the environment variable and if it is set, failing
+// for variables not allowed by the configuration.
+func (h *host) lookupEnv(name string) (string, bool, error) {
+	if !h.env[name] {
+		return "", false, fmt.Errorf("environment variable %q is not allowed by JSOttoConf.Env", name)
+	}
+
+	value, ok := os.LookupEnv(name)
+	return value, ok, nil
+}
+
+// formatTime returns the milliseconds since epoch, RFC3339 datetime or date string
+// in the layout, in UTC.
+func formatTime(value interface{}, layout string) (string, error) {
+	switch at := value.(type) {
+	case float64:
+		if math.IsNaN(at) || math.IsInf(at, 0) {
+			return "", fmt.Errorf("invalid time %v", at)
+		}
+		return time.Unix(0, int64(at)*int64(time.Millisecond)).UTC().Format(layout), nil
+	case string:
+		for _, parse := range []string{time.RFC3339, "2006-01-02T15:04:05", DateLayout} {
+			if parsed, err := time.Parse(parse, at); err == nil {
+				return parsed.UTC().Format(layout), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("invalid time %q", fmt.Sprint(value))
+}
+
+// moneyOf returns the amount in the minor units of the currency, like cents, as
+// expected by money fields. Currencies default to 2 decimal places.
+func moneyOf(amount float64, currency string) (float64, error) {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("invalid money amount %v", amount)
+	}
+
+	decimals := 2
+	if places, ok := currencyDecimals[strings.ToUpper(currency)]; ok {
+		decimals = places
+	}
+
+	return math.Round(amount * math.Pow(10, float64(decimals))), nil
+}
+
+// messageOf returns the message of console arguments, separated by spaces, where
+// objects are written as json.
+func messageOf(vm *otto.Otto, args []otto.Value) string {
+	parts := make([]string, len(args))
+	for index, arg := range args {
+		parts[index] = arg.String()
+
+		if !arg.IsObject() || arg.IsFunction() || arg.Class() == "Error" {
+			continue
+		}
+
+		if encoded, err := stringify(vm, arg); err == nil && encoded.IsString() {
+			parts[index] = encoded.String()
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// valueOf returns the javascript value of a string or number, which otto converts
+// without errors.
+func valueOf(vm *otto.Otto, value interface{}) otto.Value {
+	result, _ := vm.ToValue(value)
+	return result
+}
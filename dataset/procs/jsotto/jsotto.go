@@ -2,12 +2,17 @@ package jsotto
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/hashicorp/packer/common/json"
+	"github.com/influx6/faux/metrics"
+	"github.com/influx6/geckodataset/dataset"
 	"github.com/influx6/geckodataset/dataset/config"
-	"github.com/robertkrimen/otto"
 )
 
 // JSOtto implements the the Procs interface and implements processing of
@@ -17,92 +22,372 @@ import (
 // output.
 // This allows non-go developers, to quickly write transforms in JS which
 // transforms data easily.
+// Scripts are run by the otto vm, or by the goja vm with the modern engine.
+// Vms are not safe for concurrent use, so JSOtto keeps a pool of vms
+// loaded with the same files, where each call to Transform borrows a vm,
+// allowing batches to be transformed in parallel.
+// Runs of the target function are interrupted when the context of Transform is
+// done or the configured timeout is reached, after which the interrupted vm is
+// replaced by a newly loaded vm.
+// Scripts may load modules with require, where each vm keeps it's own cache of
+// loaded modules, and use the console and host objects bound to each vm.
+// With reloading enabled, the files are checked for changes between batches,
+// where changed files are compiled into a new pool of vms which replaces the
+// current pool, while files which fail to compile leave the current pool in use.
 type JSOtto struct {
-	Fn   otto.Value
-	VM   *otto.Otto
-	Conf config.JSOttoConf
+	Conf    config.JSOttoConf
+	live    *live
+	reducer *reducer
 }
 
-// New returns a new instance of JSOtto which implements the Procs interface.
-func New(conf config.JSOttoConf) (JSOtto, error) {
-	vm := otto.New()
+// engine compiles the scripts of a program and loads vms which run them.
+type engine interface {
+	compile(file string, source string) (interface{}, error)
+	load(prog *program) (instance, error)
+}
+
+// instance implements a vm of an engine which has run the scripts of a program,
+// calling it's target function. Instances are not safe for concurrent use.
+type instance interface {
+	// batch calls the target function with the batch of records.
+	batch(records []map[string]interface{}) ([]map[string]interface{}, error)
+
+	// each calls the target function with each record, collecting the records
+	// returned by each call.
+	each(records []map[string]interface{}) ([]map[string]interface{}, error)
+
+	// reduce calls the target function with the accumulator and each record,
+	// returning the last accumulator.
+	reduce(acc interface{}, records []map[string]interface{}) (interface{}, error)
+
+	// run runs the function, interrupting the vm when the context is done.
+	run(ctx context.Context, fn func() error) error
+}
+
+// program holds the compiled scripts and the state shared by all vms of the pool,
+// used to load new vms, with the modification times of the compiled files.
+type program struct {
+	conf    config.JSOttoConf
+	engine  engine
+	scripts []interface{}
+	files   map[string]time.Time
+	modules *modules
+	host    *host
+	pool    chan instance
+}
+
+// build returns a new program of the engine which has compiled the libraries and
+// main file of the conf, with a pool of vms which have run them.
+func build(conf config.JSOttoConf, eng engine, hst *host) (*program, error) {
+	prog := &program{
+		conf:    conf,
+		engine:  eng,
+		files:   make(map[string]time.Time),
+		modules: newModules(conf.ModulePaths, eng.compile),
+		host:    hst,
+		pool:    make(chan instance, conf.PoolSize),
+	}
 
-	// Attempt to load all libraries first into vm,
-	// return error if error occured.
-	for _, library := range conf.Libraries {
-		libdata, err := ioutil.ReadFile(library)
+	// Attempt to compile all libraries first and the main file last, which are
+	// then run by every vm of the pool, return error if error occured.
+	for _, file := range append(append([]string{}, conf.Libraries...), conf.Main) {
+		// The file is stated before it is read, so a change written while
+		// reading is seen as a change of the file.
+		info, err := os.Stat(file)
 		if err != nil {
-			return JSOtto{}, err
+			return nil, err
 		}
 
-		_, err = vm.Run(libdata)
+		data, err := ioutil.ReadFile(file)
 		if err != nil {
-			return JSOtto{}, err
+			return nil, err
 		}
+
+		script, err := eng.compile(file, string(data))
+		if err != nil {
+			return nil, err
+		}
+
+		prog.scripts = append(prog.scripts, script)
+		prog.files[file] = info.ModTime()
 	}
 
-	// Attempt to load main library into vm,
-	// return error if it occurs also.
-	maindata, err := ioutil.ReadFile(conf.Main)
-	if err != nil {
+	for i := 0; i < conf.PoolSize; i++ {
+		loaded, err := prog.load()
+		if err != nil {
+			return nil, err
+		}
+
+		prog.pool <- loaded
+	}
+
+	return prog, nil
+}
+
+// load returns a new vm of the engine which has run the scripts.
+func (prog *program) load() (instance, error) {
+	return prog.engine.load(prog)
+}
+
+// live holds the program whose pool of vms transforms batches, which is replaced
+// when reloading finds it's files changed.
+type live struct {
+	ml      sync.Mutex
+	program *program
+	checked time.Time
+	seen    map[string]time.Time
+}
+
+// changed returns true if any file of the program, including the module files it
+// has loaded, was modified since it was last seen, recording it's new modification
+// time, so files which failed to reload are only reloaded once changed again.
+func (l *live) changed() bool {
+	files := l.program.modules.files()
+	for file, modified := range l.program.files {
+		files[file] = modified
+	}
+
+	if l.seen == nil {
+		l.seen = make(map[string]time.Time)
+	}
+
+	var changed bool
+	for file, modified := range files {
+		if seen, ok := l.seen[file]; ok {
+			modified = seen
+		}
+
+		// Files missing for a moment, like when being replaced by an editor,
+		// are checked again later.
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+
+		if !info.ModTime().Equal(modified) {
+			l.seen[file] = info.ModTime()
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// reducer holds the accumulator of the reduce mode as a go value, which is
+// shared by all vms of the pool.
+type reducer struct {
+	ml      sync.Mutex
+	initial interface{}
+	acc     interface{}
+	pending bool
+}
+
+// InterruptError is returned by Transform when a run of the target function is
+// interrupted, containing the location of the script it was interrupted at.
+type InterruptError struct {
+	// Err is the error of the context that caused the interruption, which is
+	// context.DeadlineExceeded when the timeout is reached.
+	Err error
+
+	// Location is the innermost known script location of the interruption, with
+	// Stack containing the locations of the calls leading to it. Otto only tracks
+	// locations at function calls, so a loop without calls is located at the call
+	// of the function containing it, while goja locates it within the function.
+	Location string
+	Stack    []string
+}
+
+// Error returns a description of the interruption and where it happened.
+func (ie *InterruptError) Error() string {
+	reason := "interrupted"
+	if ie.Err == context.DeadlineExceeded {
+		reason = "timed out"
+	}
+
+	if len(ie.Stack) == 0 {
+		return fmt.Sprintf("javascript %s at %s: %+s", reason, ie.Location, ie.Err.Error())
+	}
+
+	return fmt.Sprintf("javascript %s at %s: %+s\n    at %s", reason, ie.Location, ie.Err.Error(), strings.Join(ie.Stack, "\n    at "))
+}
+
+// New returns a new instance of JSOtto which implements the Procs interface, where
+// the console and warnings of scripts are emitted into the metrics.
+func New(conf config.JSOttoConf, m metrics.Metrics) (JSOtto, error) {
+	if err := conf.Validate(); err != nil {
 		return JSOtto{}, err
 	}
 
-	_, err = vm.Run(maindata)
+	if conf.PoolSize <= 0 {
+		conf.PoolSize = runtime.NumCPU()
+	}
+
+	var eng engine = newOttoEngine()
+	if conf.Engine == config.JSModernEngine {
+		eng = modernEngine{}
+	}
+
+	hst, err := newHost(conf, m)
 	if err != nil {
 		return JSOtto{}, err
 	}
 
-	fn, err := vm.Get(conf.Target)
+	prog, err := build(conf, eng, hst)
 	if err != nil {
+		hst.Close()
 		return JSOtto{}, err
 	}
 
-	if !fn.IsFunction() {
-		return JSOtto{}, errors.New("JSOttoConf.Target must be a function")
+	jso := JSOtto{
+		live: &live{program: prog, checked: time.Now()},
+		Conf: conf,
 	}
 
-	return JSOtto{
-		Fn:   fn,
-		VM:   vm,
-		Conf: conf,
-	}, nil
+	if conf.Mode == config.JSReduceMode {
+		initial := conf.Initial
+		if initial == nil {
+			initial = map[string]interface{}{}
+		}
+
+		jso.reducer = &reducer{initial: initial, acc: initial}
+	}
+
+	return jso, nil
+}
+
+// Close releases the resources of the JSOtto, like the dead letter file.
+func (jso JSOtto) Close() error {
+	jso.live.ml.Lock()
+	defer jso.live.ml.Unlock()
+
+	return jso.live.program.host.Close()
+}
+
+// acquire returns the program whose vms transform the next batch, which is first
+// reloaded when reloading is enabled and it's files changed since last checked.
+// Vms borrowed from a replaced program are returned to it's pool once done, which
+// is then left to be collected.
+func (jso JSOtto) acquire() *program {
+	// The program is never replaced without reloading.
+	if !jso.Conf.Reload {
+		return jso.live.program
+	}
+
+	jso.live.ml.Lock()
+	defer jso.live.ml.Unlock()
+
+	if time.Since(jso.live.checked) < jso.Conf.ReloadDuration {
+		return jso.live.program
+	}
+
+	jso.live.checked = time.Now()
+
+	if !jso.live.changed() {
+		return jso.live.program
+	}
+
+	current := jso.live.program
+	prog, err := build(jso.Conf, current.engine, current.host)
+	if err != nil {
+		current.host.metrics.Emit(metrics.Errorf("failed to reload javascript, keeping the loaded scripts: %+s", err), metrics.With("main", jso.Conf.Main))
+		return current
+	}
+
+	jso.live.program = prog
+	jso.live.seen = nil
+	return prog
 }
 
 // Transforms takes incoming records which it transforms into json then calls appropriate
+// target function with a vm borrowed from the pool, waiting for one if none is free.
+// In reduce mode no records are returned, as the accumulator is only returned by Flush.
 func (jso JSOtto) Transform(ctx context.Context, records ...map[string]interface{}) ([]map[string]interface{}, error) {
-	jsonr, err := jso.VM.Get("JSON")
-	if err != nil {
-		return nil, err
+	if jso.Conf.TimeoutDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, jso.Conf.TimeoutDuration)
+		defer cancel()
 	}
 
-	recJSON, err := jsonr.Object().Call("stringify", records)
-	if err != nil {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	resJSON, err := jso.Fn.Call(jso.Fn, recJSON)
-	if err != nil {
-		return nil, err
+	// Batches are reduced one at a time, in the order they are received.
+	if jso.reducer != nil {
+		jso.reducer.ml.Lock()
+		defer jso.reducer.ml.Unlock()
+	}
+
+	prog := jso.acquire()
+
+	var loaded instance
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case loaded = <-prog.pool:
+	}
+
+	var res []map[string]interface{}
+	err := loaded.run(ctx, func() error {
+		var err error
+		switch jso.Conf.Mode {
+		case config.JSRecordMode:
+			res, err = loaded.each(records)
+		case config.JSReduceMode:
+			var acc interface{}
+			if acc, err = loaded.reduce(jso.reducer.acc, records); err == nil {
+				jso.reducer.acc = acc
+				jso.reducer.pending = true
+			}
+		default:
+			res, err = loaded.batch(records)
+		}
+		return err
+	})
+
+	if _, ok := err.(*InterruptError); ok {
+		// An interrupted vm may be left in an inconsistent state, so it is
+		// replaced with a new vm loaded with the same scripts.
+		if replaced, lerr := prog.load(); lerr == nil {
+			loaded = replaced
+		}
 	}
 
-	resJSONExported, err := resJSON.Export()
+	prog.pool <- loaded
+
 	if err != nil {
 		return nil, err
 	}
 
-	if resJSONEx, ok := resJSONExported.(string); ok {
-		var rex []map[string]interface{}
-		if err := json.Unmarshal([]byte(resJSONEx), &rex); err != nil {
-			return nil, err
-		}
+	return res, nil
+}
 
-		return rex, nil
+// Flush hands the records of the accumulator of the reduce mode to push, if any
+// record was reduced since the last flush. The accumulator is only reset once push
+// succeeds, so a failed push leaves it to be flushed again.
+func (jso JSOtto) Flush(ctx context.Context, push func([]map[string]interface{}) error) error {
+	if jso.reducer == nil {
+		return nil
 	}
 
-	if resJSONEx, ok := resJSONExported.([]map[string]interface{}); ok {
-		return resJSONEx, nil
+	jso.reducer.ml.Lock()
+	defer jso.reducer.ml.Unlock()
+
+	if !jso.reducer.pending {
+		return nil
 	}
 
-	return nil, errors.New("invalid type received")
+	recs, err := dataset.RecordsOf(nil, jso.reducer.acc)
+	if err != nil {
+		return err
+	}
+
+	if err := push(recs); err != nil {
+		return err
+	}
+
+	jso.reducer.acc = jso.reducer.initial
+	jso.reducer.pending = false
+
+	return nil
 }
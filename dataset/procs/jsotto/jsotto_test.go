@@ -1,50 +1,788 @@
 package jsotto_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"context"
 
+	"github.com/influx6/faux/metrics"
 	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
 	"github.com/influx6/geckodataset/dataset/config"
 	"github.com/influx6/geckodataset/dataset/procs/jsotto"
 )
 
+// engines holds the javascript engines all tests are run against.
+var engines = []string{config.JSOttoEngine, config.JSModernEngine}
+
+// forEngines runs the test against each javascript engine.
+func forEngines(t *testing.T, test func(t *testing.T, engine string)) {
+	for _, engine := range engines {
+		engine := engine
+		t.Run(engine, func(t *testing.T) {
+			test(t, engine)
+		})
+	}
+}
+
 func TestJSOtto(t *testing.T) {
-	jt, err := jsotto.New(config.JSOttoConf{
-		Main:   "./fixtures/main.js",
-		Target: "ParseRecord",
+	forEngines(t, func(t *testing.T, engine string) {
+		jt, err := jsotto.New(config.JSOttoConf{
+			Engine: engine,
+			Main:   "./fixtures/main.js",
+			Target: "ParseRecord",
+		}, metrics.New())
+
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+		tests.Passed("Should have successfully created JSOtto instance")
+
+		res, err := jt.Transform(context.Background(), map[string]interface{}{
+			"age":  20,
+			"name": "Alex Woldart",
+		})
+
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully transformed data")
+		}
+		tests.Passed("Should have successfully transformed data")
+
+		if len(res) == 0 {
+			tests.Failed("Should have received atleast 1 record")
+		}
+		tests.Passed("Should have received atleast 1 record")
+
+		total, ok := res[0]["total"].(float64)
+		if !ok {
+			tests.Failed("Should have found 'total' key in result")
+		}
+		tests.Passed("Should have found 'total' key in result")
+
+		if total != 1 {
+			tests.Failed("Should have matched total to 1")
+		}
+		tests.Passed("Should have matched total to 1")
+
+	})
+}
+
+func TestJSOttoPool(t *testing.T) {
+	forEngines(t, func(t *testing.T, engine string) {
+		jt, err := jsotto.New(config.JSOttoConf{
+			Engine:   engine,
+			Main:     "./fixtures/main.js",
+			Target:   "ParseRecord",
+			PoolSize: 3,
+		}, metrics.New())
+
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+		tests.Passed("Should have successfully created JSOtto instance")
+
+		var waiter sync.WaitGroup
+		errs := make(chan error, 20)
+
+		for i := 0; i < 20; i++ {
+			waiter.Add(1)
+			go func(total int) {
+				defer waiter.Done()
+
+				records := make([]map[string]interface{}, total)
+				for index := range records {
+					records[index] = map[string]interface{}{"age": index}
+				}
+
+				res, err := jt.Transform(context.Background(), records...)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if len(res) != 1 || res[0]["total"] != float64(total) {
+					errs <- fmt.Errorf("expected total of %d but got %#v", total, res)
+				}
+			}(i + 1)
+		}
+
+		waiter.Wait()
+		close(errs)
+
+		for err := range errs {
+			tests.FailedWithError(err, "Should have successfully transformed batches in parallel")
+		}
+		tests.Passed("Should have successfully transformed batches in parallel")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		single, err := jsotto.New(config.JSOttoConf{
+			Engine:   engine,
+			Main:     "./fixtures/main.js",
+			Target:   "ParseRecord",
+			PoolSize: 1,
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+
+		if _, err := single.Transform(ctx, map[string]interface{}{"age": 1}); err != context.Canceled {
+			tests.Failed("Should have returned context error when cancelled")
+		}
+		tests.Passed("Should have returned context error when cancelled")
+	})
+}
+
+func TestJSOttoInterrupt(t *testing.T) {
+	forEngines(t, func(t *testing.T, engine string) {
+		jt, err := jsotto.New(config.JSOttoConf{
+			Engine:   engine,
+			Main:     "./fixtures/loop.js",
+			Target:   "ParseRecord",
+			Timeout:  "100ms",
+			PoolSize: 1,
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+		tests.Passed("Should have successfully created JSOtto instance")
+
+		tests.Header("Should interrupt scripts running past the timeout")
+		{
+			_, err := jt.Transform(context.Background(), map[string]interface{}{"loop": true})
+			interrupted, ok := err.(*jsotto.InterruptError)
+			if !ok {
+				tests.Failed("Should have received InterruptError but got %#v", err)
+			}
+			tests.Passed("Should have received InterruptError")
+
+			if interrupted.Err != context.DeadlineExceeded {
+				tests.Failed("Should have been interrupted by the timeout")
+			}
+			tests.Passed("Should have been interrupted by the timeout")
+
+			// Otto only tracks locations at calls, so the loop is located at the
+			// call of spin, while goja locates it within spin.
+			location := map[string]string{config.JSOttoEngine: "loop.js:4", config.JSModernEngine: "loop.js:14"}[engine]
+			if !strings.Contains(interrupted.Location, location) {
+				tests.Failed("Should have received location of the loop but got %q", interrupted.Location)
+			}
+			tests.Passed("Should have received location of the loop")
+
+			if !strings.Contains(err.Error(), "timed out") || !strings.Contains(err.Error(), "spin") {
+				tests.Failed("Should have described interruption with stack: %s", err.Error())
+			}
+			tests.Passed("Should have described interruption with stack")
+		}
+
+		tests.Header("Should interrupt scripts when the context is cancelled")
+		{
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				cancel()
+			}()
+
+			_, err := jt.Transform(ctx, map[string]interface{}{"loop": true})
+			interrupted, ok := err.(*jsotto.InterruptError)
+			if !ok || interrupted.Err != context.Canceled {
+				tests.Failed("Should have been interrupted by cancellation but got %#v", err)
+			}
+			tests.Passed("Should have been interrupted by cancellation")
+		}
+
+		tests.Header("Should keep transforming records after interruptions")
+		{
+			res, err := jt.Transform(context.Background(), map[string]interface{}{"age": 20})
+			if err != nil {
+				tests.FailedWithError(err, "Should have successfully transformed data")
+			}
+
+			if len(res) != 1 || res[0]["total"] != float64(1) {
+				tests.Failed("Should have received total of records")
+			}
+			tests.Passed("Should have successfully transformed data after interruptions")
+		}
+
+		tests.Header("Should describe script errors with their stack")
+		{
+			_, err := jt.Transform(context.Background(), map[string]interface{}{"fail": true})
+			if err == nil || !strings.Contains(err.Error(), "TypeError") || !strings.Contains(err.Error(), "loop.js:8") {
+				tests.Failed("Should have received script error with location but got %v", err)
+			}
+			tests.Passed("Should have received script error with location")
+		}
+	})
+}
+
+func TestJSOttoRecordMode(t *testing.T) {
+	forEngines(t, func(t *testing.T, engine string) {
+		jt, err := jsotto.New(config.JSOttoConf{
+			Engine: engine,
+			Main:   "./fixtures/modes.js",
+			Target: "toSale",
+			Mode:   "record",
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+		tests.Passed("Should have successfully created JSOtto instance")
+
+		res, err := jt.Transform(context.Background(),
+			map[string]interface{}{"user": "alex", "price": 2.5, "units": 4},
+			map[string]interface{}{"user": "bob", "skip": true},
+			map[string]interface{}{"user": "cole", "items": []interface{}{"pen", "ink"}},
+		)
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully transformed data")
+		}
+		tests.Passed("Should have successfully transformed data")
+
+		if len(res) != 3 {
+			tests.Failed("Should have dropped and exploded records but got %#v", res)
+		}
+		tests.Passed("Should have dropped and exploded records")
+
+		if res[0]["total"] != float64(10) {
+			tests.Failed("Should have received total of record but got %#v", res[0])
+		}
+		tests.Passed("Should have received total of record")
+
+		if res[1]["item"] != "pen" || res[2]["item"] != "ink" || res[2]["user"] != "cole" {
+			tests.Failed("Should have received exploded records but got %#v", res[1:])
+		}
+		tests.Passed("Should have received exploded records")
+	})
+}
+
+func TestJSOttoBatchReturnShapes(t *testing.T) {
+	forEngines(t, func(t *testing.T, engine string) {
+		jt, err := jsotto.New(config.JSOttoConf{
+			Engine: engine,
+			Main:   "./fixtures/modes.js",
+			Target: "reshape",
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+		tests.Passed("Should have successfully created JSOtto instance")
+
+		for shape, total := range map[string]int{"many": 2, "one": 1, "none": 0} {
+			res, err := jt.Transform(context.Background(),
+				map[string]interface{}{"user": "alex", "shape": shape},
+				map[string]interface{}{"user": "bob", "shape": shape},
+			)
+			if err != nil {
+				tests.FailedWithError(err, "Should have successfully transformed data returned as %q", shape)
+			}
+
+			if len(res) != total {
+				tests.Failed("Should have received %d records returned as %q but got %#v", total, shape, res)
+			}
+
+			if total > 0 && res[0]["user"] != "alex" {
+				tests.Failed("Should have received records returned as %q but got %#v", shape, res)
+			}
+			tests.Passed("Should have received %d records returned as %q", total, shape)
+		}
+	})
+}
+
+func TestJSOttoReduceMode(t *testing.T) {
+	forEngines(t, func(t *testing.T, engine string) {
+		jt, err := jsotto.New(config.JSOttoConf{
+			Engine:  engine,
+			Main:    "./fixtures/modes.js",
+			Target:  "sumUnits",
+			Mode:    "reduce",
+			Initial: map[string]interface{}{"units": 0, "orders": 0},
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+		tests.Passed("Should have successfully created JSOtto instance")
+
+		for _, units := range []int{3, 4} {
+			res, err := jt.Transform(context.Background(), map[string]interface{}{"units": units}, map[string]interface{}{"units": 1})
+			if err != nil {
+				tests.FailedWithError(err, "Should have successfully reduced data")
+			}
+
+			if len(res) != 0 {
+				tests.Failed("Should not have received records before flush")
+			}
+		}
+		tests.Passed("Should have successfully reduced data")
+
+		failed := errors.New("push failed")
+		if err := jt.Flush(context.Background(), func([]map[string]interface{}) error { return failed }); err != failed {
+			tests.Failed("Should have received error of failed push but got %v", err)
+		}
+		tests.Passed("Should have received error of failed push")
+
+		var res []map[string]interface{}
+		err = jt.Flush(context.Background(), func(recs []map[string]interface{}) error {
+			res = append(res, recs...)
+			return nil
+		})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully flushed accumulator")
+		}
+		tests.Passed("Should have successfully flushed accumulator")
+
+		if len(res) != 1 || res[0]["units"] != float64(9) || res[0]["orders"] != float64(4) {
+			tests.Failed("Should have kept accumulator of all batches after failed push but got %#v", res)
+		}
+		tests.Passed("Should have kept accumulator of all batches after failed push")
+
+		res = nil
+		jt.Flush(context.Background(), func(recs []map[string]interface{}) error {
+			res = append(res, recs...)
+			return nil
+		})
+		if len(res) != 0 {
+			tests.Failed("Should not have received accumulator without new records")
+		}
+		tests.Passed("Should not have received accumulator without new records")
+	})
+}
+
+func TestJSOttoRequire(t *testing.T) {
+	forEngines(t, func(t *testing.T, engine string) {
+		jt, err := jsotto.New(config.JSOttoConf{
+			Engine:      engine,
+			Main:        "./fixtures/modules/main.js",
+			Target:      "transform",
+			ModulePaths: []string{"./fixtures/modules/shared"},
+			PoolSize:    1,
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+		tests.Passed("Should have successfully created JSOtto instance")
+
+		res, err := jt.Transform(context.Background(), map[string]interface{}{
+			"name":     "alex",
+			"amount":   10,
+			"currency": "EUR",
+		}, map[string]interface{}{
+			"name":     "wale",
+			"amount":   3.333,
+			"currency": "USD",
+		})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully transformed records")
+		}
+		tests.Passed("Should have successfully transformed records")
+
+		expected := []map[string]interface{}{
+			{"name": "alex", "total": "$12.50", "label": "ALEX", "calls": float64(1)},
+			{"name": "wale", "total": "$3.33", "label": "WALE", "calls": float64(2)},
+		}
+		if !reflect.DeepEqual(res, expected) {
+			tests.Failed("Should have received records transformed by modules: %#v", res)
+		}
+		tests.Passed("Should have received records transformed by modules")
+
+		// Modules are cached, so their state is kept across batches.
+		res, err = jt.Transform(context.Background(), map[string]interface{}{
+			"name":     "alex",
+			"amount":   1,
+			"currency": "GBP",
+		})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully transformed records")
+		}
+
+		if res[0]["calls"] != float64(3) || res[0]["total"] != "$1.50" {
+			tests.Failed("Should have used cached modules: %#v", res[0])
+		}
+		tests.Passed("Should have used cached modules")
+
+		missing, err := jsotto.New(config.JSOttoConf{
+			Engine: engine,
+			Main:   "./fixtures/modules/main.js",
+			Target: "missing",
+		}, metrics.New())
+		if err == nil || !strings.Contains(err.Error(), `cannot find module "rates"`) {
+			tests.Failed("Should have failed to find module without module paths: %+q", err)
+		}
+		tests.Passed("Should have failed to find module without module paths")
+
+		if missing, err = jsotto.New(config.JSOttoConf{
+			Engine:      engine,
+			Main:        "./fixtures/modules/main.js",
+			Target:      "missing",
+			ModulePaths: []string{"./fixtures/modules/shared"},
+			Mode:        config.JSRecordMode,
+		}, metrics.New()); err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+
+		if _, err := missing.Transform(context.Background(), map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "expected nil, record or list of records but got string") {
+			tests.Failed("Should have caught error of missing module as a string: %+q", err)
+		}
+		tests.Passed("Should have caught error of missing module within script")
 	})
+}
+
+func TestJSOttoHost(t *testing.T) {
+	forEngines(t, func(t *testing.T, engine string) {
+		dir, err := ioutil.TempDir("", "jsotto")
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created temporary directory")
+		}
+		defer os.RemoveAll(dir)
+
+		os.Setenv("JSOTTO_TEST_REGION", "emea")
+		os.Setenv("JSOTTO_TEST_SECRET", "hidden")
+		defer os.Unsetenv("JSOTTO_TEST_REGION")
+		defer os.Unsetenv("JSOTTO_TEST_SECRET")
+
+		deadLetters := filepath.Join(dir, "dead.json")
+		jt, err := jsotto.New(config.JSOttoConf{
+			Engine:     engine,
+			Main:       "./fixtures/host.js",
+			Target:     "transform",
+			Mode:       config.JSRecordMode,
+			Env:        []string{"JSOTTO_TEST_REGION"},
+			DeadLetter: deadLetters,
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+		tests.Passed("Should have successfully created JSOtto instance")
+
+		res, err := jt.Transform(context.Background(), map[string]interface{}{
+			"id":       1,
+			"at":       "2018-03-04T23:30:00-02:00",
+			"amount":   12.345,
+			"currency": "usd",
+		}, map[string]interface{}{
+			"id":     2,
+			"amount": -1,
+		}, map[string]interface{}{
+			"id":       3,
+			"at":       "2018-03-04",
+			"amount":   1200,
+			"currency": "JPY",
+		})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully transformed records")
+		}
+		tests.Passed("Should have successfully transformed records")
+
+		expected := []map[string]interface{}{
+			{"id": float64(1), "day": "2018-03-05", "at": "2018-01-02T03:04:05Z", "epoch": "1970-01-01T00:00:00Z", "amount": float64(1235), "region": "emea"},
+			{"id": float64(3), "day": "2018-03-04", "at": "2018-01-02T03:04:05Z", "epoch": "1970-01-01T00:00:00Z", "amount": float64(1200), "region": "emea"},
+		}
+		if !reflect.DeepEqual(res, expected) {
+			tests.Failed("Should have received records formatted by host: %#v", res)
+		}
+		tests.Passed("Should have received records formatted by host")
+
+		if err := jt.Close(); err != nil {
+			tests.FailedWithError(err, "Should have successfully closed JSOtto instance")
+		}
+
+		data, err := ioutil.ReadFile(deadLetters)
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully read dead letters")
+		}
+
+		var letter jsotto.DeadLetter
+		if err := json.Unmarshal(data, &letter); err != nil {
+			tests.FailedWithError(err, "Should have successfully decoded dead letter")
+		}
+
+		record, ok := letter.Record.(map[string]interface{})
+		if !ok || record["id"] != float64(2) || letter.Message != "negative amount" || !strings.Contains(letter.Location, "host.js") {
+			tests.Failed("Should have written warned record into dead letters: %s", data)
+		}
+		tests.Passed("Should have written warned record into dead letters")
+
+		secret, err := jsotto.New(config.JSOttoConf{
+			Engine: engine,
+			Main:   "./fixtures/host.js",
+			Target: "secret",
+			Mode:   config.JSRecordMode,
+			Env:    []string{"JSOTTO_TEST_REGION"},
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+
+		if _, err := secret.Transform(context.Background(), map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "not allowed") {
+			tests.Failed("Should have failed to read environment variable not allowed: %+q", err)
+		}
+		tests.Passed("Should have failed to read environment variable not allowed")
+	})
+}
+
+func TestJSOttoReload(t *testing.T) {
+	forEngines(t, func(t *testing.T, engine string) {
+		dir, err := ioutil.TempDir("", "jsotto")
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created temporary directory")
+		}
+		defer os.RemoveAll(dir)
+
+		// Files are written with increasing modification times, so changes are
+		// seen regardless of the resolution of the file system.
+		modified := time.Now().Add(-time.Hour)
+		write := func(name string, source string) {
+			file := filepath.Join(dir, name)
+			if err := ioutil.WriteFile(file, []byte(source), 0644); err != nil {
+				tests.FailedWithError(err, "Should have successfully written %q", name)
+			}
+
+			modified = modified.Add(time.Second)
+			if err := os.Chtimes(file, modified, modified); err != nil {
+				tests.FailedWithError(err, "Should have successfully changed time of %q", name)
+			}
+		}
+
+		main := "var label = require('./label');\nfunction transform(rec){\n\treturn {version: %d, label: label};\n};"
+		write("label.js", "module.exports = 'one';")
+		write("main.js", fmt.Sprintf(main, 1))
+
+		jt, err := jsotto.New(config.JSOttoConf{
+			Engine:         engine,
+			Main:           filepath.Join(dir, "main.js"),
+			Target:         "transform",
+			Mode:           config.JSRecordMode,
+			Reload:         true,
+			ReloadInterval: "1ns",
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+		tests.Passed("Should have successfully created JSOtto instance")
+
+		expect := func(version float64, label string, message string) {
+			res, err := jt.Transform(context.Background(), map[string]interface{}{})
+			if err != nil {
+				tests.FailedWithError(err, "Should have successfully transformed record")
+			}
+
+			expected := []map[string]interface{}{{"version": version, "label": label}}
+			if !reflect.DeepEqual(res, expected) {
+				tests.Failed("%s: %#v", message, res)
+			}
+			tests.Passed(message)
+		}
+
+		expect(1, "one", "Should have transformed record with loaded script")
+
+		write("main.js", fmt.Sprintf(main, 2))
+		expect(2, "one", "Should have transformed record with reloaded main script")
+
+		write("label.js", "module.exports = 'two';")
+		expect(2, "two", "Should have transformed record with reloaded module")
+
+		write("main.js", "function transform(rec){")
+		expect(2, "two", "Should have kept loaded script when changed script fails to compile")
+
+		write("main.js", fmt.Sprintf(main, 3))
+		expect(3, "two", "Should have transformed record with fixed script")
+	})
+}
 
+func TestJSOttoModernEngine(t *testing.T) {
+	if _, err := jsotto.New(config.JSOttoConf{
+		Main:   "./fixtures/modern/main.js",
+		Target: "transform",
+	}, metrics.New()); err == nil {
+		tests.Failed("Should have failed to compile ES2015 script with otto")
+	}
+	tests.Passed("Should have failed to compile ES2015 script with otto")
+
+	jt, err := jsotto.New(config.JSOttoConf{
+		Engine: config.JSModernEngine,
+		Main:   "./fixtures/modern/main.js",
+		Target: "transform",
+		Native: true,
+	}, metrics.New())
 	if err != nil {
-		tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		tests.FailedWithError(err, "Should have successfully created JSOtto instance with modern engine")
 	}
-	tests.Passed("Should have successfully created JSOtto instance")
+	tests.Passed("Should have successfully created JSOtto instance with modern engine")
 
 	res, err := jt.Transform(context.Background(), map[string]interface{}{
-		"age":  20,
-		"name": "Alex Woldart",
+		"user":  "alex",
+		"sales": []interface{}{10, 20},
+	}, map[string]interface{}{
+		"user": "wale",
+	}, map[string]interface{}{
+		"user":  "alex",
+		"sales": []interface{}{5},
+		"best":  "march",
 	})
-
 	if err != nil {
-		tests.FailedWithError(err, "Should have successfully transformed data")
+		tests.FailedWithError(err, "Should have successfully transformed records with ES2015 script")
 	}
-	tests.Passed("Should have successfully transformed data")
+	tests.Passed("Should have successfully transformed records with ES2015 script")
 
-	if len(res) == 0 {
-		tests.Failed("Should have received atleast 1 record")
+	expected := []map[string]interface{}{
+		{"user": "alex", "total": float64(35), "label": "alex: 3 sales", "best": "march"},
+		{"user": "wale", "total": float64(0), "label": "wale: 0 sales", "best": nil},
 	}
-	tests.Passed("Should have received atleast 1 record")
-
-	total, ok := res[0]["total"].(float64)
-	if !ok {
-		tests.Failed("Should have found 'total' key in result")
+	if !reflect.DeepEqual(res, expected) {
+		tests.Failed("Should have received records transformed by ES2015 script: %#v", res)
 	}
-	tests.Passed("Should have found 'total' key in result")
+	tests.Passed("Should have received records transformed by ES2015 script")
+}
 
-	if total != 1 {
-		tests.Failed("Should have matched total to 1")
+func salesRecords(total int) []map[string]interface{} {
+	records := make([]map[string]interface{}, total)
+	for index := range records {
+		records[index] = map[string]interface{}{
+			"user":  fmt.Sprintf("user-%d", index),
+			"sales": []int{index, 20, 30},
+			"meta":  map[string]interface{}{"region": "emea", "active": true},
+		}
 	}
-	tests.Passed("Should have matched total to 1")
+	return records
+}
+
+func TestJSOttoNative(t *testing.T) {
+	forEngines(t, func(t *testing.T, engine string) {
+		native, err := jsotto.New(config.JSOttoConf{
+			Engine: engine,
+			Main:   "./fixtures/native.js",
+			Target: "summarize",
+			Native: true,
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+
+		stringified, err := jsotto.New(config.JSOttoConf{
+			Engine: engine,
+			Main:   "./fixtures/native.js",
+			Target: "summarizeJSON",
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+		tests.Passed("Should have successfully created JSOtto instances")
+
+		nativeRes, err := native.Transform(context.Background(), salesRecords(3)...)
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully transformed native records")
+		}
+		tests.Passed("Should have successfully transformed native records")
+
+		jsonRes, err := stringified.Transform(context.Background(), salesRecords(3)...)
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully transformed json records")
+		}
+		tests.Passed("Should have successfully transformed json records")
+
+		if !reflect.DeepEqual(nativeRes, jsonRes) {
+			tests.Failed("Should have received the same records as json:\n%#v\n%#v", nativeRes, jsonRes)
+		}
+		tests.Passed("Should have received the same records as json")
+
+		if nativeRes[1]["total"] != float64(51) || nativeRes[1]["at"] != "2018-01-02T03:04:05.000Z" || nativeRes[1]["ratio"] != nil {
+			tests.Failed("Should have normalised values: %#v", nativeRes[1])
+		}
+		tests.Passed("Should have normalised values")
+
+		if _, ok := nativeRes[1]["missing"]; ok {
+			tests.Failed("Should have left out undefined fields")
+		}
+		tests.Passed("Should have left out undefined fields")
+
+		records, err := jsotto.New(config.JSOttoConf{
+			Engine: engine,
+			Main:   "./fixtures/native.js",
+			Target: "year",
+			Mode:   "record",
+			Native: true,
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+
+		res, err := records.Transform(context.Background(), map[string]interface{}{
+			"id": int64(7),
+			"at": time.Date(2017, 5, 1, 0, 0, 0, 0, time.UTC),
+		})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully transformed native record")
+		}
 
+		if len(res) != 1 || res[0]["year"] != float64(2017) || res[0]["id"] != float64(7) {
+			tests.Failed("Should have passed times as dates: %#v", res)
+		}
+		tests.Passed("Should have passed times as dates")
+
+		cyclic, err := jsotto.New(config.JSOttoConf{
+			Engine: engine,
+			Main:   "./fixtures/native.js",
+			Target: "cyclic",
+			Native: true,
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created JSOtto instance")
+		}
+
+		if _, err := cyclic.Transform(context.Background(), salesRecords(2)...); err != dataset.ErrTooDeep {
+			tests.Failed("Should have failed to convert record containing itself but got %v", err)
+		}
+		tests.Passed("Should have failed to convert record containing itself")
+	})
+}
+
+func BenchmarkJSOttoBatchJSON(b *testing.B) {
+	benchmarkJSOtto(b, config.JSOttoConf{
+		Main:     "./fixtures/native.js",
+		Target:   "summarizeJSON",
+		PoolSize: 1,
+	})
+}
+
+func BenchmarkJSOttoBatchNative(b *testing.B) {
+	benchmarkJSOtto(b, config.JSOttoConf{
+		Main:     "./fixtures/native.js",
+		Target:   "summarize",
+		PoolSize: 1,
+		Native:   true,
+	})
+}
+
+func benchmarkJSOtto(b *testing.B, conf config.JSOttoConf) {
+	records := salesRecords(500)
+
+	for _, engine := range engines {
+		conf.Engine = engine
+
+		b.Run(engine, func(b *testing.B) {
+			jt, err := jsotto.New(conf, metrics.New())
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := jt.Transform(context.Background(), records...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
 }
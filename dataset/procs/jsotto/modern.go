@@ -0,0 +1,286 @@
+package jsotto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/influx6/geckodataset/dataset"
+)
+
+// modernEngine implements the modern engine, which runs ES2015+ scripts with the
+// goja vm.
+type modernEngine struct{}
+
+// compile returns the compiled goja program of the source.
+func (modernEngine) compile(file string, source string) (interface{}, error) {
+	return goja.Compile(file, source, false)
+}
+
+// modernVM holds a goja vm loaded with the javascript files and it's target function.
+type modernVM struct {
+	vm        *goja.Runtime
+	fn        goja.Callable
+	native    bool
+	date      goja.Value
+	parse     goja.Callable
+	stringify goja.Callable
+}
+
+// load returns a new vm which has run the scripts, with the target function. The
+// global require function of the vm resolves relative ids from the directory of
+// the main file.
+func (modernEngine) load(prog *program) (instance, error) {
+	vm := goja.New()
+
+	jsonr := vm.Get("JSON").ToObject(vm)
+	parse, _ := goja.AssertFunction(jsonr.Get("parse"))
+	stringify, _ := goja.AssertFunction(jsonr.Get("stringify"))
+
+	mv := &modernVM{
+		vm:        vm,
+		native:    prog.conf.Native,
+		date:      vm.Get("Date"),
+		parse:     parse,
+		stringify: stringify,
+	}
+
+	if err := prog.host.bindModern(mv); err != nil {
+		return nil, err
+	}
+
+	requirer, err := newModernRequirer(mv, prog.modules)
+	if err != nil {
+		return nil, err
+	}
+
+	require, err := requirer.function(filepath.Dir(prog.conf.Main))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vm.Set("require", require); err != nil {
+		return nil, err
+	}
+
+	for _, script := range prog.scripts {
+		if _, err := vm.RunProgram(script.(*goja.Program)); err != nil {
+			// Errors of scripts, including those of required modules, are
+			// described with the stack of where they occurred.
+			if jserr, ok := err.(*goja.Exception); ok {
+				return nil, errors.New(strings.TrimSpace(jserr.String()))
+			}
+			return nil, err
+		}
+	}
+
+	fn, ok := goja.AssertFunction(vm.Get(prog.conf.Target))
+	if !ok {
+		return nil, errors.New("JSOttoConf.Target must be a function")
+	}
+
+	mv.fn = fn
+	return mv, nil
+}
+
+// batch calls the target function with the records as a json string, or as an
+// array of native objects.
+func (mv *modernVM) batch(records []map[string]interface{}) ([]map[string]interface{}, error) {
+	var arg goja.Value
+	if mv.native {
+		arg = mv.toValue(records)
+	} else {
+		encoded, err := json.Marshal(records)
+		if err != nil {
+			return nil, err
+		}
+		arg = mv.vm.ToValue(string(encoded))
+	}
+
+	res, err := mv.fn(goja.Undefined(), arg)
+	if err != nil {
+		return nil, err
+	}
+
+	if resJSON, ok := res.Export().(string); ok {
+		var rex []map[string]interface{}
+		if err := json.Unmarshal([]byte(resJSON), &rex); err != nil {
+			return nil, err
+		}
+
+		return rex, nil
+	}
+
+	value, err := mv.output(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataset.RecordsOf(nil, value)
+}
+
+// each calls the target function with each record, collecting the records
+// returned by each call.
+func (mv *modernVM) each(records []map[string]interface{}) ([]map[string]interface{}, error) {
+	items, err := mv.input(records)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []map[string]interface{}
+	for index := range records {
+		item := items.ToObject(mv.vm).Get(strconv.Itoa(index))
+
+		result, err := mv.fn(goja.Undefined(), item)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := mv.output(result)
+		if err != nil {
+			return nil, err
+		}
+
+		if res, err = dataset.RecordsOf(res, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// reduce calls the target function with the accumulator and each record, returning
+// the last accumulator.
+func (mv *modernVM) reduce(initial interface{}, records []map[string]interface{}) (interface{}, error) {
+	items, err := mv.input(records)
+	if err != nil {
+		return nil, err
+	}
+
+	acc, err := mv.input(initial)
+	if err != nil {
+		return nil, err
+	}
+
+	for index := range records {
+		item := items.ToObject(mv.vm).Get(strconv.Itoa(index))
+		if acc, err = mv.fn(goja.Undefined(), acc, item); err != nil {
+			return nil, err
+		}
+	}
+
+	return mv.output(acc)
+}
+
+// input returns the javascript value of the go value, which is a native value
+// or a value parsed from it's json.
+func (mv *modernVM) input(value interface{}) (goja.Value, error) {
+	if mv.native {
+		return mv.toValue(value), nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return mv.parse(goja.Undefined(), mv.vm.ToValue(string(encoded)))
+}
+
+// output returns the go value of the javascript value, which is read from the
+// native value or decoded from it's json.
+func (mv *modernVM) output(value goja.Value) (interface{}, error) {
+	if mv.native {
+		result, _, err := mv.fromValue(value)
+		return result, err
+	}
+
+	encoded, err := mv.stringify(goja.Undefined(), value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Values without json, like undefined, are null.
+	if goja.IsUndefined(encoded) {
+		return nil, nil
+	}
+
+	var result interface{}
+	if err := json.Unmarshal([]byte(encoded.String()), &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// run runs the function against the vm, interrupting the vm when the context is
+// done, where errors of scripts are described with the stack of where they occurred.
+func (mv *modernVM) run(ctx context.Context, fn func() error) error {
+	done := make(chan struct{})
+	watcher := make(chan struct{})
+
+	go func() {
+		defer close(watcher)
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			mv.vm.Interrupt(ctx.Err())
+		}
+	}()
+
+	err := fn()
+
+	close(done)
+	<-watcher
+
+	// Clear any interruption which arrived after the run had finished, so
+	// it does not interrupt the next run of the vm.
+	mv.vm.ClearInterrupt()
+
+	switch jserr := err.(type) {
+	case nil:
+		return nil
+	case *goja.InterruptedError:
+		location, stack := stackOf(jserr.Stack())
+		return &InterruptError{
+			Err:      ctx.Err(),
+			Location: location,
+			Stack:    stack,
+		}
+	case *goja.Exception:
+		return errors.New(strings.TrimSpace(jserr.String()))
+	}
+
+	return err
+}
+
+// stackOf returns the innermost known script location of the stack frames, with
+// the locations of all frames.
+func stackOf(frames []goja.StackFrame) (string, []string) {
+	location := "<unknown>"
+	stack := make([]string, 0, len(frames))
+	for index := range frames {
+		frame := &frames[index]
+
+		position := frame.Position()
+		if position.Line == 0 {
+			stack = append(stack, fmt.Sprintf("%s (<native code>)", frame.FuncName()))
+			continue
+		}
+
+		at := fmt.Sprintf("%s:%d:%d", position.Filename, position.Line, position.Column)
+		if location == "<unknown>" {
+			location = at
+		}
+
+		stack = append(stack, fmt.Sprintf("%s (%s)", frame.FuncName(), at))
+	}
+
+	return location, stack
+}
@@ -0,0 +1,245 @@
+package jsotto
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// modernRequirer implements the require function of a goja vm, caching the modules
+// it has loaded by their file.
+type modernRequirer struct {
+	mv      *modernVM
+	modules *modules
+	cache   map[string]*goja.Object
+	started map[string]bool
+	fn      goja.Callable
+}
+
+// newModernRequirer returns a new instance of modernRequirer for the vm.
+func newModernRequirer(mv *modernVM, mods *modules) (*modernRequirer, error) {
+	r := &modernRequirer{
+		mv:      mv,
+		modules: mods,
+		cache:   make(map[string]*goja.Object),
+		started: make(map[string]bool),
+	}
+
+	source, err := mv.vm.RunString(requireSource)
+	if err != nil {
+		return nil, err
+	}
+
+	define, _ := goja.AssertFunction(source)
+	requirer, err := define(goja.Undefined(), mv.vm.ToValue(r.load), mv.vm.ToValue(r.start), mv.vm.ToValue(r.unload))
+	if err != nil {
+		return nil, err
+	}
+
+	r.fn, _ = goja.AssertFunction(requirer)
+	return r, nil
+}
+
+// function returns a javascript require function which resolves relative ids from dir.
+func (r *modernRequirer) function(dir string) (goja.Value, error) {
+	return r.fn(goja.Undefined(), r.mv.vm.ToValue(dir))
+}
+
+// load returns the module of the id required from dir, like requirer.load.
+func (r *modernRequirer) load(call goja.FunctionCall) goja.Value {
+	module, err := r.module(call.Argument(0).String(), call.Argument(1).String())
+	if err != nil {
+		panic(r.mv.vm.NewGoError(err))
+	}
+
+	return module
+}
+
+// module returns the module of the id required from dir.
+func (r *modernRequirer) module(id string, dir string) (*goja.Object, error) {
+	file, err := r.modules.resolve(id, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if module, ok := r.cache[file]; ok {
+		return module, nil
+	}
+
+	src, err := r.modules.source(file)
+	if err != nil {
+		return nil, err
+	}
+
+	module := r.mv.vm.NewObject()
+	module.Set("exports", r.mv.vm.NewObject())
+	module.Set("loaded", false)
+	module.Set("id", file)
+	module.Set("filename", file)
+	module.Set("dirname", filepath.Dir(file))
+
+	if src.script == nil {
+		exports, err := r.mv.parse(goja.Undefined(), r.mv.vm.ToValue(src.json))
+		if err != nil {
+			return nil, err
+		}
+
+		module.Set("exports", exports)
+		module.Set("loaded", true)
+		r.started[file] = true
+	}
+
+	r.cache[file] = module
+	return module, nil
+}
+
+// start returns the wrapper function of the module file the first time it is called
+// for the file, like requirer.start.
+func (r *modernRequirer) start(call goja.FunctionCall) goja.Value {
+	file := call.Argument(0).String()
+	if r.started[file] {
+		return goja.Undefined()
+	}
+
+	src, err := r.modules.source(file)
+	if err != nil {
+		panic(r.mv.vm.NewGoError(err))
+	}
+
+	wrapper, err := r.mv.vm.RunProgram(src.script.(*goja.Program))
+	if err != nil {
+		panic(r.mv.vm.NewGoError(err))
+	}
+
+	r.started[file] = true
+	return wrapper
+}
+
+// unload removes the module file from the cache, like requirer.unload.
+func (r *modernRequirer) unload(call goja.FunctionCall) goja.Value {
+	file := call.Argument(0).String()
+	delete(r.cache, file)
+	delete(r.started, file)
+	return goja.Undefined()
+}
+
+// bindModern sets the console and host objects within the goja vm.
+func (h *host) bindModern(mv *modernVM) error {
+	vm := mv.vm
+
+	console := vm.NewObject()
+	for _, level := range []string{"log", "info", "debug", "warn", "error"} {
+		level := level
+		console.Set(level, func(call goja.FunctionCall) goja.Value {
+			location, _ := stackOf(vm.CaptureCallStack(0, nil))
+			h.log(level, mv.messageOf(call.Arguments), location)
+			return goja.Undefined()
+		})
+	}
+
+	if err := vm.Set("console", console); err != nil {
+		return err
+	}
+
+	hostObj := vm.NewObject()
+
+	hostObj.Set("warn", func(call goja.FunctionCall) goja.Value {
+		record, _, err := mv.fromValue(call.Argument(0))
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+
+		var message string
+		if msg := call.Argument(1); !goja.IsUndefined(msg) {
+			message = msg.String()
+		}
+
+		location, _ := stackOf(vm.CaptureCallStack(0, nil))
+		if err := h.deadLetter(record, message, location); err != nil {
+			panic(vm.NewGoError(err))
+		}
+
+		return goja.Undefined()
+	})
+
+	hostObj.Set("env", func(call goja.FunctionCall) goja.Value {
+		value, ok, err := h.lookupEnv(call.Argument(0).String())
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+
+		if !ok {
+			return goja.Undefined()
+		}
+
+		return vm.ToValue(value)
+	})
+
+	for name, layout := range map[string]string{"date": DateLayout, "datetime": DatetimeLayout} {
+		layout := layout
+		hostObj.Set(name, func(call goja.FunctionCall) goja.Value {
+			formatted, err := formatTime(mv.timeOf(call.Argument(0)), layout)
+			if err != nil {
+				panic(vm.NewTypeError(err.Error()))
+			}
+
+			return vm.ToValue(formatted)
+		})
+	}
+
+	hostObj.Set("money", func(call goja.FunctionCall) goja.Value {
+		var currency string
+		if value := call.Argument(1); !goja.IsUndefined(value) {
+			currency = value.String()
+		}
+
+		units, err := moneyOf(call.Argument(0).ToFloat(), currency)
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+
+		return vm.ToValue(units)
+	})
+
+	return vm.Set("host", hostObj)
+}
+
+// timeOf returns the milliseconds since epoch of a Date or number, or the string
+// of other values, as expected by formatTime.
+func (mv *modernVM) timeOf(value goja.Value) interface{} {
+	if obj, ok := value.(*goja.Object); ok && obj.ClassName() == "Date" {
+		return mv.call(obj, "getTime").ToFloat()
+	}
+
+	switch value.Export().(type) {
+	case int64, float64:
+		return value.ToFloat()
+	}
+
+	return value.String()
+}
+
+// messageOf returns the message of console arguments, separated by spaces, where
+// objects are written as json.
+func (mv *modernVM) messageOf(args []goja.Value) string {
+	parts := make([]string, len(args))
+	for index, arg := range args {
+		parts[index] = arg.String()
+
+		obj, ok := arg.(*goja.Object)
+		if !ok || obj.ClassName() == "Error" {
+			continue
+		}
+
+		if _, ok := goja.AssertFunction(obj); ok {
+			continue
+		}
+
+		if encoded, err := mv.stringify(goja.Undefined(), obj); err == nil && !goja.IsUndefined(encoded) {
+			parts[index] = encoded.String()
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
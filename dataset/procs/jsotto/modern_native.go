@@ -0,0 +1,185 @@
+package jsotto
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/influx6/geckodataset/dataset"
+)
+
+// toValue returns the native javascript value of the giving go value, where maps become
+// objects, slices become arrays and times become dates, instead of goja's go backed values.
+func (mv *modernVM) toValue(value interface{}) goja.Value {
+	switch item := value.(type) {
+	case nil:
+		return goja.Null()
+	case goja.Value:
+		return item
+	case string, bool, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return mv.vm.ToValue(item)
+	case time.Time:
+		date, err := mv.vm.New(mv.date, mv.vm.ToValue(float64(item.UnixNano())/float64(time.Millisecond)))
+		if err != nil {
+			return goja.Null()
+		}
+		return date
+	case map[string]interface{}:
+		obj := mv.vm.NewObject()
+		for field, elem := range item {
+			obj.Set(field, mv.toValue(elem))
+		}
+		return obj
+	case []map[string]interface{}:
+		elems := make([]interface{}, len(item))
+		for index, elem := range item {
+			elems[index] = mv.toValue(elem)
+		}
+		return mv.vm.NewArray(elems...)
+	case []interface{}:
+		elems := make([]interface{}, len(item))
+		for index, elem := range item {
+			elems[index] = mv.toValue(elem)
+		}
+		return mv.vm.NewArray(elems...)
+	}
+
+	// Ids like mongo's object ids are passed as their hex representation.
+	if hexer, ok := value.(interface{ Hex() string }); ok {
+		return mv.vm.ToValue(hexer.Hex())
+	}
+
+	ref := reflect.ValueOf(value)
+	switch ref.Kind() {
+	case reflect.Ptr:
+		if ref.IsNil() {
+			return goja.Null()
+		}
+		return mv.toValue(ref.Elem().Interface())
+	case reflect.Map:
+		if ref.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		obj := mv.vm.NewObject()
+		for _, key := range ref.MapKeys() {
+			obj.Set(key.String(), mv.toValue(ref.MapIndex(key).Interface()))
+		}
+		return obj
+	case reflect.Slice, reflect.Array:
+		// Bytes are left to goja, like strings.
+		if ref.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+
+		elems := make([]interface{}, ref.Len())
+		for index := range elems {
+			elems[index] = mv.toValue(ref.Index(index).Interface())
+		}
+		return mv.vm.NewArray(elems...)
+	case reflect.String:
+		return mv.vm.ToValue(ref.String())
+	}
+
+	return mv.vm.ToValue(value)
+}
+
+// fromValue returns the go value of the javascript value, normalised like the values
+// of the otto engine. The returned bool is false for values left out of objects, like
+// functions and undefined values.
+func (mv *modernVM) fromValue(value goja.Value) (interface{}, bool, error) {
+	return mv.fromValueAt(value, 0)
+}
+
+// fromValueAt returns the go value of the javascript value nested at depth, failing
+// with dataset.ErrTooDeep past dataset.MaxDepth, like for objects containing themselves.
+func (mv *modernVM) fromValueAt(value goja.Value, depth int) (interface{}, bool, error) {
+	if depth > dataset.MaxDepth {
+		return nil, false, dataset.ErrTooDeep
+	}
+
+	switch {
+	case value == nil || goja.IsUndefined(value):
+		return nil, false, nil
+	case goja.IsNull(value):
+		return nil, true, nil
+	}
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		switch item := value.Export().(type) {
+		case bool, string:
+			return item, true, nil
+		case int64:
+			return float64(item), true, nil
+		case float64:
+			// JSON has no representation of these, so they become null.
+			if math.IsNaN(item) || math.IsInf(item, 0) {
+				return nil, true, nil
+			}
+			return item, true, nil
+		}
+
+		// Symbols are left out, like JSON.
+		return nil, false, nil
+	}
+
+	if _, ok := goja.AssertFunction(obj); ok {
+		return nil, false, nil
+	}
+
+	switch obj.ClassName() {
+	case "Array":
+		result := make([]interface{}, obj.Get("length").ToInteger())
+		for index := range result {
+			// Like JSON, undefined elements become null.
+			elem, _, err := mv.fromValueAt(obj.Get(strconv.Itoa(index)), depth+1)
+			if err != nil {
+				return nil, false, err
+			}
+			result[index] = elem
+		}
+		return result, true, nil
+	case "Date":
+		ms := mv.call(obj, "getTime").ToFloat()
+		if math.IsNaN(ms) {
+			return nil, true, nil
+		}
+
+		at := time.Unix(0, int64(ms)*int64(time.Millisecond)).UTC()
+		return at.Format(isoLayout), true, nil
+	case "Number", "String", "Boolean":
+		return mv.fromValueAt(mv.call(obj, "valueOf"), depth)
+	}
+
+	result := make(map[string]interface{})
+	for _, key := range obj.Keys() {
+		converted, ok, err := mv.fromValueAt(obj.Get(key), depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if ok {
+			result[key] = converted
+		}
+	}
+	return result, true, nil
+}
+
+// call returns the result of calling the method of the object, or undefined if it
+// fails.
+func (mv *modernVM) call(obj *goja.Object, method string) goja.Value {
+	fn, ok := goja.AssertFunction(obj.Get(method))
+	if !ok {
+		return goja.Undefined()
+	}
+
+	result, err := fn(obj)
+	if err != nil {
+		return goja.Undefined()
+	}
+
+	return result
+}
@@ -0,0 +1,270 @@
+package jsotto
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/robertkrimen/otto"
+)
+
+// nativeSource defines the functions used to create native javascript values
+// without parsing any source for each value.
+const nativeSource = `({
+	object: function() { return {}; },
+	array: function() { return Array.prototype.slice.call(arguments); },
+	date: function(ms) { return new Date(ms); }
+})`
+
+// isoLayout sets the layout of dates exported from javascript, matching the
+// output of Date.prototype.toISOString used by JSON.stringify.
+const isoLayout = "2006-01-02T15:04:05.000Z"
+
+// natives holds the functions of a vm used to create native javascript values.
+type natives struct {
+	object otto.Value
+	array  otto.Value
+	date   otto.Value
+}
+
+// newNatives returns the natives of the giving vm.
+func newNatives(vm *otto.Otto) (natives, error) {
+	source, err := vm.Object(nativeSource)
+	if err != nil {
+		return natives{}, err
+	}
+
+	var nt natives
+	if nt.object, err = source.Get("object"); err != nil {
+		return natives{}, err
+	}
+
+	if nt.array, err = source.Get("array"); err != nil {
+		return natives{}, err
+	}
+
+	if nt.date, err = source.Get("date"); err != nil {
+		return natives{}, err
+	}
+
+	return nt, nil
+}
+
+// toValue returns the native javascript value of the giving go value, where maps become
+// objects, slices become arrays and times become dates, instead of otto's go backed values.
+func (nt natives) toValue(vm *otto.Otto, value interface{}) (otto.Value, error) {
+	switch item := value.(type) {
+	case nil:
+		return otto.NullValue(), nil
+	case otto.Value:
+		return item, nil
+	case string, bool, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return vm.ToValue(item)
+	case time.Time:
+		return nt.date.Call(otto.UndefinedValue(), float64(item.UnixNano())/float64(time.Millisecond))
+	case map[string]interface{}:
+		return nt.fromMap(vm, item)
+	case []map[string]interface{}:
+		elems := make([]interface{}, len(item))
+		for index, elem := range item {
+			converted, err := nt.fromMap(vm, elem)
+			if err != nil {
+				return otto.Value{}, err
+			}
+			elems[index] = converted
+		}
+		return nt.array.Call(otto.UndefinedValue(), elems...)
+	case []interface{}:
+		elems := make([]interface{}, len(item))
+		for index, elem := range item {
+			converted, err := nt.toValue(vm, elem)
+			if err != nil {
+				return otto.Value{}, err
+			}
+			elems[index] = converted
+		}
+		return nt.array.Call(otto.UndefinedValue(), elems...)
+	}
+
+	// Ids like mongo's object ids are passed as their hex representation.
+	if hexer, ok := value.(interface{ Hex() string }); ok {
+		return vm.ToValue(hexer.Hex())
+	}
+
+	ref := reflect.ValueOf(value)
+	switch ref.Kind() {
+	case reflect.Ptr:
+		if ref.IsNil() {
+			return otto.NullValue(), nil
+		}
+		return nt.toValue(vm, ref.Elem().Interface())
+	case reflect.Map:
+		if ref.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		obj, err := nt.object.Call(otto.UndefinedValue())
+		if err != nil {
+			return otto.Value{}, err
+		}
+
+		for _, key := range ref.MapKeys() {
+			converted, err := nt.toValue(vm, ref.MapIndex(key).Interface())
+			if err != nil {
+				return otto.Value{}, err
+			}
+
+			if err := obj.Object().Set(key.String(), converted); err != nil {
+				return otto.Value{}, err
+			}
+		}
+		return obj, nil
+	case reflect.Slice, reflect.Array:
+		// Bytes are left to otto, like strings.
+		if ref.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+
+		elems := make([]interface{}, ref.Len())
+		for index := range elems {
+			converted, err := nt.toValue(vm, ref.Index(index).Interface())
+			if err != nil {
+				return otto.Value{}, err
+			}
+			elems[index] = converted
+		}
+		return nt.array.Call(otto.UndefinedValue(), elems...)
+	case reflect.String:
+		return vm.ToValue(ref.String())
+	}
+
+	return vm.ToValue(value)
+}
+
+// fromMap returns a native javascript object with the fields of the map.
+func (nt natives) fromMap(vm *otto.Otto, record map[string]interface{}) (otto.Value, error) {
+	obj, err := nt.object.Call(otto.UndefinedValue())
+	if err != nil {
+		return otto.Value{}, err
+	}
+
+	target := obj.Object()
+	for field, value := range record {
+		converted, err := nt.toValue(vm, value)
+		if err != nil {
+			return otto.Value{}, err
+		}
+
+		if err := target.Set(field, converted); err != nil {
+			return otto.Value{}, err
+		}
+	}
+
+	return obj, nil
+}
+
+// fromValue returns the go value of the javascript value, normalised like values
+// decoded from JSON.stringify: numbers are float64, dates are ISO strings, arrays
+// are []interface{} and objects are map[string]interface{}. The returned bool is
+// false for values left out of objects, like functions and undefined values.
+func fromValue(value otto.Value) (interface{}, bool, error) {
+	return fromValueAt(value, 0)
+}
+
+// fromValueAt returns the go value of the javascript value nested at depth,
+// failing with dataset.ErrTooDeep past dataset.MaxDepth, like for objects
+// containing themselves.
+func fromValueAt(value otto.Value, depth int) (interface{}, bool, error) {
+	if depth > dataset.MaxDepth {
+		return nil, false, dataset.ErrTooDeep
+	}
+
+	switch {
+	case value.IsUndefined():
+		return nil, false, nil
+	case value.IsNull():
+		return nil, true, nil
+	case value.IsBoolean():
+		result, err := value.ToBoolean()
+		return result, true, err
+	case value.IsNumber():
+		result, err := value.ToFloat()
+		if err != nil {
+			return nil, false, err
+		}
+
+		// JSON has no representation of these, so they become null.
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			return nil, true, nil
+		}
+		return result, true, nil
+	case value.IsString():
+		return value.String(), true, nil
+	case value.IsFunction():
+		return nil, false, nil
+	case !value.IsObject():
+		return nil, false, nil
+	}
+
+	obj := value.Object()
+	switch obj.Class() {
+	case "Array", "GoArray", "GoSlice":
+		lengthValue, err := obj.Get("length")
+		if err != nil {
+			return nil, false, err
+		}
+
+		length, err := lengthValue.ToInteger()
+		if err != nil {
+			return nil, false, err
+		}
+
+		result := make([]interface{}, length)
+		for index := range result {
+			elem, err := obj.Get(strconv.Itoa(index))
+			if err != nil {
+				return nil, false, err
+			}
+
+			// Like JSON, undefined elements become null.
+			if result[index], _, err = fromValueAt(elem, depth+1); err != nil {
+				return nil, false, err
+			}
+		}
+		return result, true, nil
+	case "Date":
+		ms, err := value.ToFloat()
+		if err != nil || math.IsNaN(ms) {
+			return nil, true, nil
+		}
+
+		at := time.Unix(0, int64(ms)*int64(time.Millisecond)).UTC()
+		return at.Format(isoLayout), true, nil
+	case "Number", "String", "Boolean":
+		primitive, err := obj.Call("valueOf")
+		if err != nil {
+			return nil, false, err
+		}
+		return fromValueAt(primitive, depth)
+	}
+
+	result := make(map[string]interface{})
+	for _, key := range obj.Keys() {
+		field, err := obj.Get(key)
+		if err != nil {
+			return nil, false, err
+		}
+
+		converted, ok, err := fromValueAt(field, depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if ok {
+			result[key] = converted
+		}
+	}
+	return result, true, nil
+}
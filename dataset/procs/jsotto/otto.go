@@ -0,0 +1,320 @@
+package jsotto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/packer/common/json"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/robertkrimen/otto"
+)
+
+// ottoEngine implements the otto engine, which runs ES5 scripts with the otto vm.
+type ottoEngine struct {
+	compiler *otto.Otto
+}
+
+// newOttoEngine returns a new instance of ottoEngine.
+func newOttoEngine() ottoEngine {
+	return ottoEngine{compiler: otto.New()}
+}
+
+// compile returns the compiled otto script of the source.
+func (oe ottoEngine) compile(file string, source string) (interface{}, error) {
+	return oe.compiler.Compile(file, source)
+}
+
+// ottoVM holds a vm loaded with the javascript files and it's target function.
+type ottoVM struct {
+	vm      *otto.Otto
+	fn      otto.Value
+	natives natives
+	native  bool
+}
+
+// load returns a new vm which has run the scripts, with the target function. The
+// global require function of the vm resolves relative ids from the directory of
+// the main file.
+func (oe ottoEngine) load(prog *program) (instance, error) {
+	vm := otto.New()
+	vm.Interrupt = make(chan func(), 1)
+
+	if err := prog.host.bind(vm); err != nil {
+		return nil, err
+	}
+
+	requirer, err := newRequirer(vm, prog.modules)
+	if err != nil {
+		return nil, err
+	}
+
+	require, err := requirer.function(filepath.Dir(prog.conf.Main))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vm.Set("require", require); err != nil {
+		return nil, err
+	}
+
+	for _, script := range prog.scripts {
+		if _, err := vm.Run(script.(*otto.Script)); err != nil {
+			// Errors of scripts, including those of required modules, are
+			// described with the stack of where they occurred.
+			if jserr, ok := err.(*otto.Error); ok {
+				return nil, errors.New(jserr.String())
+			}
+			return nil, err
+		}
+	}
+
+	fn, err := vm.Get(prog.conf.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fn.IsFunction() {
+		return nil, errors.New("JSOttoConf.Target must be a function")
+	}
+
+	nt, err := newNatives(vm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ottoVM{vm: vm, fn: fn, natives: nt, native: prog.conf.Native}, nil
+}
+
+// batch calls the target function with the records as a json string, or as an
+// array of native objects.
+func (ov *ottoVM) batch(records []map[string]interface{}) ([]map[string]interface{}, error) {
+	var arg otto.Value
+	var err error
+	if ov.native {
+		arg, err = ov.natives.toValue(ov.vm, records)
+	} else {
+		arg, err = stringify(ov.vm, records)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	resJSON, err := ov.fn.Call(ov.fn, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	if resJSON.IsString() {
+		var rex []map[string]interface{}
+		if err := json.Unmarshal([]byte(resJSON.String()), &rex); err != nil {
+			return nil, err
+		}
+
+		return rex, nil
+	}
+
+	value, err := ov.output(resJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataset.RecordsOf(nil, value)
+}
+
+// each calls the target function with each record, collecting the records
+// returned by each call.
+func (ov *ottoVM) each(records []map[string]interface{}) ([]map[string]interface{}, error) {
+	items, err := ov.input(records)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []map[string]interface{}
+	for index := range records {
+		item, err := items.Object().Get(strconv.Itoa(index))
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := ov.fn.Call(otto.UndefinedValue(), item)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := ov.output(result)
+		if err != nil {
+			return nil, err
+		}
+
+		if res, err = dataset.RecordsOf(res, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// reduce calls the target function with the accumulator and each record, returning
+// the last accumulator.
+func (ov *ottoVM) reduce(initial interface{}, records []map[string]interface{}) (interface{}, error) {
+	items, err := ov.input(records)
+	if err != nil {
+		return nil, err
+	}
+
+	acc, err := ov.input(initial)
+	if err != nil {
+		return nil, err
+	}
+
+	for index := range records {
+		item, err := items.Object().Get(strconv.Itoa(index))
+		if err != nil {
+			return nil, err
+		}
+
+		if acc, err = ov.fn.Call(otto.UndefinedValue(), acc, item); err != nil {
+			return nil, err
+		}
+	}
+
+	return ov.output(acc)
+}
+
+// input returns the javascript value of the go value, which is a native value
+// or a value parsed from it's json.
+func (ov *ottoVM) input(value interface{}) (otto.Value, error) {
+	if ov.native {
+		return ov.natives.toValue(ov.vm, value)
+	}
+
+	encoded, err := stringify(ov.vm, value)
+	if err != nil {
+		return otto.Value{}, err
+	}
+
+	jsonr, err := ov.vm.Get("JSON")
+	if err != nil {
+		return otto.Value{}, err
+	}
+
+	return jsonr.Object().Call("parse", encoded)
+}
+
+// output returns the go value of the javascript value, which is read from the
+// native value or decoded from it's json.
+func (ov *ottoVM) output(value otto.Value) (interface{}, error) {
+	if ov.native {
+		result, _, err := fromValue(value)
+		return result, err
+	}
+
+	encoded, err := stringify(ov.vm, value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Values without json, like undefined, are null.
+	if !encoded.IsString() {
+		return nil, nil
+	}
+
+	var result interface{}
+	if err := json.Unmarshal([]byte(encoded.String()), &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// stringify returns the json string of the value within the vm.
+func stringify(vm *otto.Otto, value interface{}) (otto.Value, error) {
+	jsonr, err := vm.Get("JSON")
+	if err != nil {
+		return otto.Value{}, err
+	}
+
+	return jsonr.Object().Call("stringify", value)
+}
+
+// halt is used to panic within an interrupted vm, carrying the context
+// of the vm when interrupted.
+type halt struct {
+	context otto.Context
+}
+
+// run runs the function against the vm, interrupting the vm when the context is
+// done, where errors of scripts are described with the stack of where they occurred.
+func (ov *ottoVM) run(ctx context.Context, fn func() error) (err error) {
+	done := make(chan struct{})
+	watcher := make(chan struct{})
+
+	go func() {
+		defer close(watcher)
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			ov.vm.Interrupt <- func() {
+				panic(halt{context: ov.vm.Context()})
+			}
+		}
+	}()
+
+	defer func() {
+		close(done)
+		<-watcher
+
+		// Drop any interruption which arrived after the run had finished, so
+		// it does not halt the next run of the vm.
+		select {
+		case <-ov.vm.Interrupt:
+		default:
+		}
+
+		if recovered := recover(); recovered != nil {
+			stopped, ok := recovered.(halt)
+			if !ok {
+				panic(recovered)
+			}
+
+			err = &InterruptError{
+				Err:      ctx.Err(),
+				Location: locationOf(stopped.context),
+				Stack:    stopped.context.Stacktrace,
+			}
+		}
+	}()
+
+	if err := fn(); err != nil {
+		switch jserr := err.(type) {
+		case *otto.Error:
+			return errors.New(jserr.String())
+		case otto.Error:
+			return errors.New(jserr.String())
+		}
+		return err
+	}
+
+	return nil
+}
+
+// locationOf returns the innermost known script location of the context.
+func locationOf(ctx otto.Context) string {
+	if ctx.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d", ctx.Filename, ctx.Line, ctx.Column)
+	}
+
+	for _, location := range ctx.Stacktrace {
+		if !strings.Contains(location, "<unknown>") && !strings.Contains(location, "<native code>") {
+			return location
+		}
+	}
+
+	return "<unknown>"
+}
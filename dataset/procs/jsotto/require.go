@@ -0,0 +1,276 @@
+package jsotto
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robertkrimen/otto"
+)
+
+// moduleWrapper wraps the source of a module within a function, giving the module
+// it's own scope. The source starts on the first line, so the locations of errors
+// match the module file.
+const moduleWrapper = "(function (exports, require, module, __filename, __dirname) {%s\n})"
+
+// modules resolves and compiles the files required by scripts, where compiled files
+// are shared by all vms of the pool, as each vm runs them within it's own scope.
+type modules struct {
+	ml      sync.Mutex
+	paths   []string
+	compile func(file string, source string) (interface{}, error)
+	sources map[string]*moduleSource
+}
+
+// moduleSource holds the compiled wrapper of a javascript module, compiled for the
+// engine, or the content of a json module, with the modification time of it's file.
+type moduleSource struct {
+	script   interface{}
+	json     string
+	modified time.Time
+}
+
+// newModules returns a new instance of modules which resolves modules which are not
+// relative to the requiring file from the giving paths, compiling them with compile.
+func newModules(paths []string, compile func(string, string) (interface{}, error)) *modules {
+	return &modules{
+		paths:   paths,
+		compile: compile,
+		sources: make(map[string]*moduleSource),
+	}
+}
+
+// resolve returns the file of the module id required from dir. Ids starting with
+// './', '../' or '/' are resolved relative to dir, while other ids are searched
+// within the paths in order. Like node, the id may leave out the '.js' or '.json'
+// extension or name a directory with an 'index.js' file.
+func (m *modules) resolve(id string, dir string) (string, error) {
+	var bases []string
+	switch {
+	case filepath.IsAbs(id):
+		bases = append(bases, id)
+	case id == "." || id == ".." || strings.HasPrefix(id, "./") || strings.HasPrefix(id, "../"):
+		bases = append(bases, filepath.Join(dir, id))
+	default:
+		for _, path := range m.paths {
+			bases = append(bases, filepath.Join(path, id))
+		}
+	}
+
+	for _, base := range bases {
+		for _, candidate := range []string{base, base + ".js", base + ".json", filepath.Join(base, "index.js")} {
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return filepath.Clean(candidate), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("cannot find module %q from %q", id, dir)
+}
+
+// source returns the source of the module file, which is read and compiled once.
+func (m *modules) source(file string) (*moduleSource, error) {
+	m.ml.Lock()
+	defer m.ml.Unlock()
+
+	if src, ok := m.sources[file]; ok {
+		return src, nil
+	}
+
+	// The file is stated before it is read, so a change written while reading
+	// is seen as a change of the file.
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &moduleSource{modified: info.ModTime()}
+	if filepath.Ext(file) == ".json" {
+		src.json = string(data)
+	} else {
+		if src.script, err = m.compile(file, fmt.Sprintf(moduleWrapper, data)); err != nil {
+			return nil, err
+		}
+	}
+
+	m.sources[file] = src
+	return src, nil
+}
+
+// files returns the modification times of the module files loaded so far.
+func (m *modules) files() map[string]time.Time {
+	m.ml.Lock()
+	defer m.ml.Unlock()
+
+	files := make(map[string]time.Time, len(m.sources))
+	for file, src := range m.sources {
+		files[file] = src.modified
+	}
+
+	return files
+}
+
+// requireSource defines the require functions of a vm, where modules are loaded by
+// go and their wrappers run by javascript, so errors thrown by a module reach the
+// requiring script as they were thrown.
+const requireSource = `(function(load, start, unload) {
+	return function requirer(dir) {
+		return function require(id) {
+			var module = load(id, dir);
+			var run = start(module.filename);
+			if (run) {
+				try {
+					run.call(module.exports, module.exports, requirer(module.dirname), module, module.filename, module.dirname);
+				} catch (err) {
+					unload(module.filename);
+					throw err;
+				}
+				module.loaded = true;
+			}
+			return module.exports;
+		};
+	};
+})`
+
+// requirer implements the require function of a vm, caching the modules it has
+// loaded by their file.
+type requirer struct {
+	vm      *otto.Otto
+	modules *modules
+	cache   map[string]*otto.Object
+	started map[string]bool
+	fn      otto.Value
+}
+
+// newRequirer returns a new instance of requirer for the vm.
+func newRequirer(vm *otto.Otto, mods *modules) (*requirer, error) {
+	r := &requirer{
+		vm:      vm,
+		modules: mods,
+		cache:   make(map[string]*otto.Object),
+		started: make(map[string]bool),
+	}
+
+	source, err := vm.Eval(requireSource)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.fn, err = source.Call(otto.UndefinedValue(), r.load, r.start, r.unload); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// function returns a javascript require function which resolves relative ids from dir.
+func (r *requirer) function(dir string) (otto.Value, error) {
+	return r.fn.Call(otto.UndefinedValue(), dir)
+}
+
+// load returns the module of the id required from dir, which is loaded and cached
+// if it is not cached. Json modules are parsed into their exports, while javascript
+// modules are left to be started. Errors are thrown as javascript errors, so scripts
+// may catch them.
+func (r *requirer) load(call otto.FunctionCall) otto.Value {
+	module, err := r.module(call.Argument(0).String(), call.Argument(1).String())
+	if err != nil {
+		panic(r.vm.MakeCustomError("Error", err.Error()))
+	}
+
+	return module.Value()
+}
+
+// module returns the module of the id required from dir.
+func (r *requirer) module(id string, dir string) (*otto.Object, error) {
+	file, err := r.modules.resolve(id, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if module, ok := r.cache[file]; ok {
+		return module, nil
+	}
+
+	src, err := r.modules.source(file)
+	if err != nil {
+		return nil, err
+	}
+
+	module, err := r.vm.Object(`({exports: {}, loaded: false})`)
+	if err != nil {
+		return nil, err
+	}
+
+	for field, value := range map[string]string{"id": file, "filename": file, "dirname": filepath.Dir(file)} {
+		if err := module.Set(field, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if src.script == nil {
+		jsonr, err := r.vm.Get("JSON")
+		if err != nil {
+			return nil, err
+		}
+
+		exports, err := jsonr.Object().Call("parse", src.json)
+		if err != nil {
+			return nil, fmt.Errorf("invalid json module %q: %+s", file, err.Error())
+		}
+
+		if err := module.Set("exports", exports); err != nil {
+			return nil, err
+		}
+
+		if err := module.Set("loaded", true); err != nil {
+			return nil, err
+		}
+
+		r.started[file] = true
+	}
+
+	r.cache[file] = module
+	return module, nil
+}
+
+// start returns the wrapper function of the module file the first time it is called
+// for the file, returning undefined afterwards. Like node, modules are cached before
+// they run, so cyclic requires receive the exports the module has set so far.
+func (r *requirer) start(call otto.FunctionCall) otto.Value {
+	file := call.Argument(0).String()
+	if r.started[file] {
+		return otto.UndefinedValue()
+	}
+
+	src, err := r.modules.source(file)
+	if err != nil {
+		panic(r.vm.MakeCustomError("Error", err.Error()))
+	}
+
+	wrapper, err := r.vm.Run(src.script.(*otto.Script))
+	if err != nil {
+		panic(r.vm.MakeCustomError("Error", err.Error()))
+	}
+
+	r.started[file] = true
+	return wrapper
+}
+
+// unload removes the module file from the cache, after it failed to run, so it is
+// run again if required again.
+func (r *requirer) unload(call otto.FunctionCall) otto.Value {
+	file := call.Argument(0).String()
+	delete(r.cache, file)
+	delete(r.started, file)
+	return otto.UndefinedValue()
+}
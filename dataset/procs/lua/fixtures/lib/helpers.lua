@@ -0,0 +1,8 @@
+-- sum returns the total of the numbers of the list.
+function sum(items)
+	local total = 0
+	for _, item in ipairs(items) do
+		total = total + item
+	end
+	return total
+end
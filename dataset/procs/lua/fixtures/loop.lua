@@ -0,0 +1,9 @@
+-- transform never returns for a batch starting with a looping record, so it
+-- must be interrupted.
+function transform(records)
+	local count = 0
+	while records[1].loop do
+		count = count + 1
+	end
+	return records
+end
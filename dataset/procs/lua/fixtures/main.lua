@@ -0,0 +1,33 @@
+-- transform returns a record for each record of the batch, with the total of
+-- it's scores, leaving out records without a name.
+function transform(records)
+	local out = {}
+	for _, record in ipairs(records) do
+		if record.name ~= nil then
+			print('transforming', record.name)
+			table.insert(out, {
+				name = string.upper(record.name),
+				total = sum(record.scores),
+				tags = {'lua', record.kind},
+				meta = {count = #record.scores, at = record.at},
+			})
+		end
+	end
+	return out
+end
+
+-- none returns an empty table, which is taken as no records.
+function none(records)
+	return {}
+end
+
+-- first returns the first record of the batch as a single record.
+function first(records)
+	return records[1]
+end
+
+-- cyclic returns the first record of the batch holding itself.
+function cyclic(records)
+	records[1].self = records[1]
+	return records
+end
@@ -0,0 +1,15 @@
+-- transform returns the types of the globals offered to scripts.
+function transform(records)
+	return {
+		os = type(os),
+		io = type(io),
+		debug = type(debug),
+		package = type(package),
+		require = type(require),
+		dofile = type(dofile),
+		loadfile = type(loadfile),
+		string = type(string),
+		table = type(table),
+		math = type(math),
+	}
+end
@@ -0,0 +1,237 @@
+package lua
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/influx6/faux/metrics"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	glua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Lua implements the Procs interface and implements processing of map records
+// received from a external input source with lua scripts. It uses a loaded lua
+// file and associated libraries with a target function which when called
+// accepts the batch of records as a table of tables and returns a table of the
+// transformed records.
+// Scripts run within a sandbox, which only offers the base, table, string, math
+// and coroutine libraries, without access to files, the os or other modules,
+// where print writes into the metrics.
+// Lua vms are not safe for concurrent use, so Lua keeps a pool of vms loaded with
+// the same files, where each call to Transform borrows a vm, allowing batches to
+// be transformed in parallel. Runs of the target function are interrupted once
+// the configured timeout is reached or the context of Transform is done, after
+// which the interrupted vm is replaced by a newly loaded vm.
+type Lua struct {
+	Conf    config.LuaConf
+	metrics metrics.Metrics
+	protos  []*glua.FunctionProto
+	pool    chan *glua.LState
+}
+
+// sandboxLibs sets the libraries opened within the vms, leaving out the package,
+// io, os, debug and channel libraries.
+var sandboxLibs = []struct {
+	name string
+	open glua.LGFunction
+}{
+	{glua.BaseLibName, glua.OpenBase},
+	{glua.TabLibName, glua.OpenTable},
+	{glua.StringLibName, glua.OpenString},
+	{glua.MathLibName, glua.OpenMath},
+	{glua.CoroutineLibName, glua.OpenCoroutine},
+}
+
+// unsafeGlobals sets the functions of the base library removed from the vms, as
+// they load files, modules or write to stdout.
+var unsafeGlobals = []string{"dofile", "loadfile", "require", "module", "_printregs"}
+
+// New returns a new instance of Lua which implements the Procs interface, where
+// the prints of scripts are emitted into the metrics.
+func New(conf config.LuaConf, m metrics.Metrics) (Lua, error) {
+	if err := conf.Validate(); err != nil {
+		return Lua{}, err
+	}
+
+	if conf.PoolSize <= 0 {
+		conf.PoolSize = runtime.NumCPU()
+	}
+
+	lu := Lua{
+		Conf:    conf,
+		metrics: m,
+		pool:    make(chan *glua.LState, conf.PoolSize),
+	}
+
+	// Attempt to compile all libraries first and the main file last, which are
+	// then run by every vm of the pool, return error if error occured.
+	for _, file := range append(append([]string{}, conf.Libraries...), conf.Main) {
+		proto, err := compile(file)
+		if err != nil {
+			return Lua{}, err
+		}
+
+		lu.protos = append(lu.protos, proto)
+	}
+
+	for i := 0; i < conf.PoolSize; i++ {
+		state, err := lu.load()
+		if err != nil {
+			for len(lu.pool) != 0 {
+				(<-lu.pool).Close()
+			}
+			return Lua{}, err
+		}
+
+		lu.pool <- state
+	}
+
+	return lu, nil
+}
+
+// Close closes the vms of the pool, waiting for vms borrowed by running calls to
+// Transform to be returned.
+func (lu Lua) Close() error {
+	for i := 0; i < lu.Conf.PoolSize; i++ {
+		(<-lu.pool).Close()
+	}
+	return nil
+}
+
+// compile returns the compiled function of the lua file.
+func compile(file string) (*glua.FunctionProto, error) {
+	reader, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	chunk, err := parse.Parse(reader, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return glua.Compile(chunk, file)
+}
+
+// load returns a new sandboxed vm which has run the compiled files.
+func (lu Lua) load() (*glua.LState, error) {
+	state := glua.NewState(glua.Options{SkipOpenLibs: true})
+
+	for _, lib := range sandboxLibs {
+		if err := state.CallByParam(glua.P{Fn: state.NewFunction(lib.open), Protect: true}, glua.LString(lib.name)); err != nil {
+			state.Close()
+			return nil, err
+		}
+	}
+
+	for _, name := range unsafeGlobals {
+		state.SetGlobal(name, glua.LNil)
+	}
+
+	state.SetGlobal("print", state.NewFunction(lu.print))
+
+	for _, proto := range lu.protos {
+		state.Push(state.NewFunctionFromProto(proto))
+		if err := state.PCall(0, glua.MultRet, nil); err != nil {
+			state.Close()
+			return nil, err
+		}
+	}
+
+	if _, ok := state.GetGlobal(lu.Conf.Target).(*glua.LFunction); !ok {
+		state.Close()
+		return nil, fmt.Errorf("LuaConf.Target %q must be a function", lu.Conf.Target)
+	}
+
+	return state, nil
+}
+
+// print emits the arguments of a print call into the metrics, separated by tabs
+// like the print of lua, with the script location of the call.
+func (lu Lua) print(state *glua.LState) int {
+	parts := make([]string, state.GetTop())
+	for index := range parts {
+		parts[index] = state.ToStringMeta(state.Get(index + 1)).String()
+	}
+
+	location := strings.TrimSuffix(state.Where(1), ": ")
+	lu.metrics.Emit(metrics.Info(strings.Join(parts, "\t")), metrics.With("lua", "print"), metrics.With("location", location))
+	return 0
+}
+
+// Transforms takes incoming records which it converts into a table of tables then
+// calls the target function with a vm borrowed from the pool, waiting for one if
+// none is free.
+func (lu Lua) Transform(ctx context.Context, records ...map[string]interface{}) ([]map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, lu.Conf.TimeoutDuration)
+	defer cancel()
+
+	var state *glua.LState
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case state = <-lu.pool:
+	}
+
+	res, err := lu.call(ctx, state, records)
+	if err != nil && ctx.Err() != nil {
+		// A cancelled state stops mid-call, keeping whatever the script left on
+		// it's stack and globals, so it is swapped for a freshly loaded state.
+		if replaced, lerr := lu.load(); lerr == nil {
+			state.Close()
+			state = replaced
+		}
+
+		reason := "interrupted"
+		if ctx.Err() == context.DeadlineExceeded {
+			reason = "timed out"
+		}
+
+		err = fmt.Errorf("lua %s: %+s", reason, err.Error())
+	}
+
+	lu.pool <- state
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// call calls the target function of the vm with the records, which is interrupted
+// once the context is done.
+func (lu Lua) call(ctx context.Context, state *glua.LState, records []map[string]interface{}) ([]map[string]interface{}, error) {
+	state.SetContext(ctx)
+	defer state.RemoveContext()
+
+	err := state.CallByParam(glua.P{
+		Fn:      state.GetGlobal(lu.Conf.Target),
+		NRet:    1,
+		Protect: true,
+	}, toValue(state, records))
+	if err != nil {
+		return nil, err
+	}
+
+	result := state.Get(-1)
+	state.Pop(1)
+
+	// Empty tables are taken as an empty list of records, not as a record.
+	if table, ok := result.(*glua.LTable); ok && isEmpty(table) {
+		return nil, nil
+	}
+
+	value, _, err := fromValue(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataset.RecordsOf(nil, value)
+}
@@ -0,0 +1,208 @@
+package lua_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/faux/metrics"
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/influx6/geckodataset/dataset/procs/lua"
+)
+
+func TestLua(t *testing.T) {
+	lu, err := lua.New(config.LuaConf{
+		Main:      "./fixtures/main.lua",
+		Target:    "transform",
+		Libraries: []string{"./fixtures/lib/helpers.lua"},
+	}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Lua instance")
+	}
+	tests.Passed("Should have successfully created Lua instance")
+
+	at := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	res, err := lu.Transform(context.Background(), map[string]interface{}{
+		"name":   "alex",
+		"kind":   "user",
+		"scores": []interface{}{1, 2.5, int64(3)},
+		"at":     at,
+	}, map[string]interface{}{
+		"kind": "anonymous",
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully transformed records")
+	}
+	tests.Passed("Should have successfully transformed records")
+
+	expected := []map[string]interface{}{
+		{
+			"name":  "ALEX",
+			"total": float64(6.5),
+			"tags":  []interface{}{"lua", "user"},
+			"meta":  map[string]interface{}{"count": float64(3), "at": "2018-01-02T03:04:05.000Z"},
+		},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		tests.Failed("Should have converted records between go and lua: %#v", res)
+	}
+	tests.Passed("Should have converted records between go and lua")
+
+	none, err := lua.New(config.LuaConf{
+		Main:      "./fixtures/main.lua",
+		Target:    "none",
+		Libraries: []string{"./fixtures/lib/helpers.lua"},
+	}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Lua instance")
+	}
+
+	if res, err := none.Transform(context.Background(), map[string]interface{}{"name": "alex"}); err != nil || len(res) != 0 {
+		tests.Failed("Should have received no records for empty table: %#v %v", res, err)
+	}
+	tests.Passed("Should have received no records for empty table")
+
+	first, err := lua.New(config.LuaConf{
+		Main:      "./fixtures/main.lua",
+		Target:    "first",
+		Libraries: []string{"./fixtures/lib/helpers.lua"},
+	}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Lua instance")
+	}
+
+	res, err = first.Transform(context.Background(), map[string]interface{}{"name": "alex"}, map[string]interface{}{"name": "bob"})
+	if err != nil || !reflect.DeepEqual(res, []map[string]interface{}{{"name": "alex"}}) {
+		tests.Failed("Should have received single record returned as table: %#v %v", res, err)
+	}
+	tests.Passed("Should have received single record returned as table")
+}
+
+func TestLuaSandbox(t *testing.T) {
+	lu, err := lua.New(config.LuaConf{
+		Main:   "./fixtures/sandbox.lua",
+		Target: "transform",
+	}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Lua instance")
+	}
+	tests.Passed("Should have successfully created Lua instance")
+
+	res, err := lu.Transform(context.Background(), map[string]interface{}{})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully transformed records")
+	}
+
+	expected := []map[string]interface{}{{
+		"os":       "nil",
+		"io":       "nil",
+		"debug":    "nil",
+		"package":  "nil",
+		"require":  "nil",
+		"dofile":   "nil",
+		"loadfile": "nil",
+		"string":   "table",
+		"table":    "table",
+		"math":     "table",
+	}}
+	if !reflect.DeepEqual(res, expected) {
+		tests.Failed("Should have only offered sandboxed libraries: %#v", res)
+	}
+	tests.Passed("Should have only offered sandboxed libraries")
+}
+
+func TestLuaTimeout(t *testing.T) {
+	lu, err := lua.New(config.LuaConf{
+		Main:     "./fixtures/loop.lua",
+		Target:   "transform",
+		Timeout:  "100ms",
+		PoolSize: 1,
+	}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Lua instance")
+	}
+	tests.Passed("Should have successfully created Lua instance")
+
+	tests.Header("Should interrupt scripts running past the timeout")
+	{
+		_, err := lu.Transform(context.Background(), map[string]interface{}{"loop": true})
+		if err == nil || !strings.Contains(err.Error(), "timed out") || !strings.Contains(err.Error(), "loop.lua:") {
+			tests.Failed("Should have received timeout error with location but got %v", err)
+		}
+		tests.Passed("Should have received timeout error with location")
+	}
+
+	tests.Header("Should interrupt scripts when the context is cancelled")
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+		}()
+
+		_, err := lu.Transform(ctx, map[string]interface{}{"loop": true})
+		if err == nil || !strings.Contains(err.Error(), "interrupted") {
+			tests.Failed("Should have been interrupted by cancellation but got %v", err)
+		}
+		tests.Passed("Should have been interrupted by cancellation")
+	}
+
+	tests.Header("Should keep transforming records after interruptions")
+	{
+		res, err := lu.Transform(context.Background(), map[string]interface{}{"loop": false})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully transformed records")
+		}
+
+		if len(res) != 1 || res[0]["loop"] != false {
+			tests.Failed("Should have received records: %#v", res)
+		}
+		tests.Passed("Should have successfully transformed records after interruptions")
+	}
+}
+
+func TestLuaErrors(t *testing.T) {
+	if _, err := lua.New(config.LuaConf{Main: "./fixtures/main.lua", Target: "missing"}, metrics.New()); err == nil {
+		tests.Failed("Should have failed to create Lua instance with missing target")
+	}
+	tests.Passed("Should have failed to create Lua instance with missing target")
+
+	lu, err := lua.New(config.LuaConf{Main: "./fixtures/main.lua", Target: "transform"}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Lua instance")
+	}
+
+	// sum is defined by the helpers library, which is not loaded.
+	_, err = lu.Transform(context.Background(), map[string]interface{}{"name": "alex", "scores": []interface{}{1}})
+	if err == nil || !strings.Contains(err.Error(), "main.lua:") {
+		tests.Failed("Should have received script error with location but got %v", err)
+	}
+	tests.Passed("Should have received script error with location")
+
+	cyclic, err := lua.New(config.LuaConf{Main: "./fixtures/main.lua", Target: "cyclic", PoolSize: 1}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Lua instance")
+	}
+
+	if _, err := cyclic.Transform(context.Background(), map[string]interface{}{"name": "alex"}); err != dataset.ErrTooDeep {
+		tests.Failed("Should have failed to convert table containing itself but got %v", err)
+	}
+	tests.Passed("Should have failed to convert table containing itself")
+
+	closed := make(chan error, 1)
+	go func() { closed <- cyclic.Close() }()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully closed Lua instance")
+		}
+	case <-time.After(time.Second):
+		tests.Failed("Should have closed Lua instance once it's vms are returned")
+	}
+	tests.Passed("Should have successfully closed Lua instance")
+}
@@ -0,0 +1,193 @@
+package lua
+
+import (
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/influx6/geckodataset/dataset"
+	glua "github.com/yuin/gopher-lua"
+)
+
+// isoLayout sets the layout times are passed into lua as, matching the dates
+// exported by the javascript procs.
+const isoLayout = "2006-01-02T15:04:05.000Z"
+
+// toValue returns the lua value of the giving go value, where maps become tables
+// keyed by their fields, slices become sequences and times become iso strings.
+func toValue(state *glua.LState, value interface{}) glua.LValue {
+	switch item := value.(type) {
+	case nil:
+		return glua.LNil
+	case glua.LValue:
+		return item
+	case bool:
+		return glua.LBool(item)
+	case string:
+		return glua.LString(item)
+	case float64:
+		return glua.LNumber(item)
+	case int:
+		return glua.LNumber(item)
+	case int64:
+		return glua.LNumber(item)
+	case time.Time:
+		return glua.LString(item.UTC().Format(isoLayout))
+	case map[string]interface{}:
+		table := state.CreateTable(0, len(item))
+		for field, elem := range item {
+			table.RawSetString(field, toValue(state, elem))
+		}
+		return table
+	case []map[string]interface{}:
+		table := state.CreateTable(len(item), 0)
+		for _, elem := range item {
+			table.Append(toValue(state, elem))
+		}
+		return table
+	case []interface{}:
+		table := state.CreateTable(len(item), 0)
+		for index, elem := range item {
+			// Nils can't be appended without ending the sequence.
+			table.RawSetInt(index+1, toValue(state, elem))
+		}
+		return table
+	}
+
+	// Ids like mongo's object ids are passed as their hex representation.
+	if hexer, ok := value.(interface{ Hex() string }); ok {
+		return glua.LString(hexer.Hex())
+	}
+
+	ref := reflect.ValueOf(value)
+	switch ref.Kind() {
+	case reflect.Ptr:
+		if ref.IsNil() {
+			return glua.LNil
+		}
+		return toValue(state, ref.Elem().Interface())
+	case reflect.Map:
+		if ref.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		table := state.CreateTable(0, ref.Len())
+		for _, key := range ref.MapKeys() {
+			table.RawSetString(key.String(), toValue(state, ref.MapIndex(key).Interface()))
+		}
+		return table
+	case reflect.Slice, reflect.Array:
+		// Bytes are passed as strings.
+		if ref.Type().Elem().Kind() == reflect.Uint8 {
+			return glua.LString(string(ref.Bytes()))
+		}
+
+		table := state.CreateTable(ref.Len(), 0)
+		for index := 0; index < ref.Len(); index++ {
+			table.RawSetInt(index+1, toValue(state, ref.Index(index).Interface()))
+		}
+		return table
+	case reflect.String:
+		return glua.LString(ref.String())
+	case reflect.Bool:
+		return glua.LBool(ref.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return glua.LNumber(ref.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return glua.LNumber(ref.Uint())
+	case reflect.Float32, reflect.Float64:
+		return glua.LNumber(ref.Float())
+	}
+
+	return glua.LNil
+}
+
+// fromValue returns the go value of the lua value, normalised like the values of
+// the javascript procs, where numbers are floats. Tables which are sequences become
+// slices, while other tables become maps keyed by their string or number keys. The
+// returned bool is false for values left out of tables, like functions.
+func fromValue(value glua.LValue) (interface{}, bool, error) {
+	return fromValueAt(value, 0)
+}
+
+// fromValueAt returns the go value of the lua value nested at depth, failing with
+// dataset.ErrTooDeep past dataset.MaxDepth, like for tables containing themselves.
+func fromValueAt(value glua.LValue, depth int) (interface{}, bool, error) {
+	switch item := value.(type) {
+	case *glua.LNilType:
+		return nil, true, nil
+	case glua.LBool:
+		return bool(item), true, nil
+	case glua.LString:
+		return string(item), true, nil
+	case glua.LNumber:
+		// JSON has no representation of these, so they become nil.
+		if math.IsNaN(float64(item)) || math.IsInf(float64(item), 0) {
+			return nil, true, nil
+		}
+		return float64(item), true, nil
+	case *glua.LTable:
+		if depth > dataset.MaxDepth {
+			return nil, false, dataset.ErrTooDeep
+		}
+
+		result, err := fromTable(item, depth)
+		return result, true, err
+	}
+
+	return nil, false, nil
+}
+
+// fromTable returns the slice of a sequence, or the map of any other table, where
+// empty tables become empty maps.
+func fromTable(table *glua.LTable, depth int) (interface{}, error) {
+	var keys int
+	table.ForEach(func(glua.LValue, glua.LValue) { keys++ })
+
+	if size := table.MaxN(); size > 0 && size == keys {
+		result := make([]interface{}, size)
+		for index := range result {
+			elem, _, err := fromValueAt(table.RawGetInt(index+1), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			result[index] = elem
+		}
+		return result, nil
+	}
+
+	var err error
+	result := make(map[string]interface{}, keys)
+	table.ForEach(func(key glua.LValue, elem glua.LValue) {
+		if err != nil {
+			return
+		}
+
+		switch key.(type) {
+		case glua.LString, glua.LNumber:
+		default:
+			return
+		}
+
+		converted, ok, cerr := fromValueAt(elem, depth+1)
+		if cerr != nil {
+			err = cerr
+			return
+		}
+
+		if ok {
+			result[key.String()] = converted
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// isEmpty returns true if the table has no keys.
+func isEmpty(table *glua.LTable) bool {
+	key, _ := table.Next(glua.LNil)
+	return key == glua.LNil
+}
@@ -0,0 +1,170 @@
+package starlark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/influx6/faux/metrics"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	star "go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Starlark implements the Procs interface and implements processing of map
+// records received from a external input source with a starlark file. It calls
+// a target function of the file with the batch of records as a list of dicts,
+// which returns a list of dicts of the transformed records.
+// Starlark has no access to files, the network or the clock, and it's values are
+// frozen once the file is loaded, so transforms are deterministic and the target
+// function can be called for batches in parallel. Files may load helper modules
+// with load statements, which are resolved within the configured directory.
+// Each call of the target function is cancelled once it executes more than the
+// configured steps or the context of Transform is done.
+type Starlark struct {
+	Conf    config.StarlarkConf
+	metrics metrics.Metrics
+	fn      star.Callable
+}
+
+// fileOptions sets the dialect of starlark files, which allows the set builtin,
+// while leaving out while loops, recursion and the reassignment of globals.
+var fileOptions = &syntax.FileOptions{Set: true}
+
+// New returns a new instance of Starlark which implements the Procs interface,
+// where the prints of files are emitted into the metrics.
+func New(conf config.StarlarkConf, m metrics.Metrics) (Starlark, error) {
+	if err := conf.Validate(); err != nil {
+		return Starlark{}, err
+	}
+
+	sl := Starlark{
+		Conf:    conf,
+		metrics: m,
+	}
+
+	ld := &loader{
+		dir:     conf.LoadDir,
+		modules: make(map[string]*module),
+	}
+	ld.thread = func(name string) *star.Thread {
+		return sl.thread(name, ld.load)
+	}
+
+	globals, err := star.ExecFileOptions(fileOptions, ld.thread(conf.Main), conf.Main, nil, nil)
+	if err != nil {
+		return Starlark{}, errorOf(err)
+	}
+
+	fn, ok := globals[conf.Target].(star.Callable)
+	if !ok {
+		return Starlark{}, fmt.Errorf("StarlarkConf.Target %q must be a function", conf.Target)
+	}
+
+	sl.fn = fn
+	return sl, nil
+}
+
+// thread returns a new thread limited to the configured steps, which loads modules
+// with load and prints into the metrics.
+func (sl Starlark) thread(name string, load func(*star.Thread, string) (star.StringDict, error)) *star.Thread {
+	thread := &star.Thread{
+		Name: name,
+		Load: load,
+		Print: func(thread *star.Thread, msg string) {
+			// The innermost frame is the print builtin, so the script location
+			// is of the frame calling it.
+			location := thread.CallFrame(1).Pos.String()
+			sl.metrics.Emit(metrics.Info(msg), metrics.With("starlark", "print"), metrics.With("location", location))
+		},
+	}
+
+	thread.SetMaxExecutionSteps(sl.Conf.MaxSteps)
+	return thread
+}
+
+// Transforms takes incoming records which it converts into a list of dicts then
+// calls the target function, which is cancelled once the context is done.
+func (sl Starlark) Transform(ctx context.Context, records ...map[string]interface{}) ([]map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Modules are only loaded by the file, so calls can't load modules.
+	thread := sl.thread(sl.Conf.Target, nil)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		}
+	}()
+
+	result, err := star.Call(thread, sl.fn, star.Tuple{toValue(records)}, nil)
+	if err != nil {
+		return nil, errorOf(err)
+	}
+
+	value, _, err := fromValue(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataset.RecordsOf(nil, value)
+}
+
+// errorOf returns the error of a starlark evaluation, described with the stack of
+// where it occurred.
+func errorOf(err error) error {
+	if evalErr, ok := err.(*star.EvalError); ok {
+		return errors.New(evalErr.Backtrace())
+	}
+
+	return err
+}
+
+// loader loads the modules of load statements from a directory, where each module
+// is executed once and shared by all files which load it.
+type loader struct {
+	dir     string
+	thread  func(name string) *star.Thread
+	modules map[string]*module
+}
+
+// module holds the globals of a loaded module, or the error of loading it.
+type module struct {
+	globals star.StringDict
+	err     error
+}
+
+// load returns the globals of the module, resolved within the directory of the
+// loader. Modules are loaded by the file within New, so the loader is not safe
+// for concurrent use.
+func (ld *loader) load(_ *star.Thread, name string) (star.StringDict, error) {
+	if mod, ok := ld.modules[name]; ok {
+		if mod == nil {
+			return nil, fmt.Errorf("cycle in load graph of module %q", name)
+		}
+		return mod.globals, mod.err
+	}
+
+	file := filepath.Join(ld.dir, filepath.FromSlash(name))
+	rel, err := filepath.Rel(ld.dir, file)
+	if err != nil || filepath.IsAbs(name) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("module %q must be within %q", name, ld.dir)
+	}
+
+	// A nil module marks the module as loading, to catch cycles.
+	ld.modules[name] = nil
+
+	globals, err := star.ExecFileOptions(fileOptions, ld.thread(name), file, nil, nil)
+	ld.modules[name] = &module{globals: globals, err: err}
+	return globals, err
+}
@@ -0,0 +1,174 @@
+package starlark_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/faux/metrics"
+	"github.com/influx6/faux/metrics/custom"
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/config"
+	"github.com/influx6/geckodataset/dataset/procs/starlark"
+)
+
+func TestStarlark(t *testing.T) {
+	var prints bytes.Buffer
+	sl, err := starlark.New(config.StarlarkConf{
+		Main:   "./fixtures/main.star",
+		Target: "transform",
+	}, metrics.New(custom.StackDisplay(&prints)))
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Starlark instance")
+	}
+	tests.Passed("Should have successfully created Starlark instance")
+
+	at := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	res, err := sl.Transform(context.Background(), map[string]interface{}{
+		"name":   "alex",
+		"kind":   "user",
+		"scores": []interface{}{1, 2.5, int64(3)},
+		"at":     at,
+	}, map[string]interface{}{
+		"kind": "anonymous",
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully transformed records")
+	}
+	tests.Passed("Should have successfully transformed records")
+
+	expected := []map[string]interface{}{
+		{
+			"name":  "ALEX",
+			"total": float64(6.5),
+			"tags":  []interface{}{"starlark", "user"},
+			"meta":  map[string]interface{}{"count": float64(3), "at": "2018-01-02T03:04:05.000Z"},
+		},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		tests.Failed("Should have converted records between go and starlark: %#v", res)
+	}
+	tests.Passed("Should have converted records between go and starlark")
+
+	if !strings.Contains(prints.String(), "main.star:11:") {
+		tests.Failed("Should have emitted print with location of the call but got %q", prints.String())
+	}
+	tests.Passed("Should have emitted print with location of the call")
+
+	first, err := starlark.New(config.StarlarkConf{
+		Main:   "./fixtures/main.star",
+		Target: "first",
+	}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Starlark instance")
+	}
+
+	res, err = first.Transform(context.Background(), map[string]interface{}{"name": "alex"}, map[string]interface{}{"name": "bob"})
+	if err != nil || !reflect.DeepEqual(res, []map[string]interface{}{{"name": "alex"}}) {
+		tests.Failed("Should have received single record returned as dict: %#v %v", res, err)
+	}
+	tests.Passed("Should have received single record returned as dict")
+
+	cyclic, err := starlark.New(config.StarlarkConf{
+		Main:   "./fixtures/main.star",
+		Target: "cyclic",
+	}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Starlark instance")
+	}
+
+	if _, err := cyclic.Transform(context.Background(), map[string]interface{}{"name": "alex"}); err != dataset.ErrTooDeep {
+		tests.Failed("Should have failed to convert list containing itself but got %v", err)
+	}
+	tests.Passed("Should have failed to convert list containing itself")
+}
+
+func TestStarlarkLoad(t *testing.T) {
+	if _, err := starlark.New(config.StarlarkConf{
+		Main:   "./fixtures/main.star",
+		Target: "transform",
+		// The helpers are not found from the lib directory itself.
+		LoadDir: "./fixtures/lib",
+	}, metrics.New()); err == nil || !strings.Contains(err.Error(), "lib/helpers.star") {
+		tests.Failed("Should have failed to load module outside of load directory: %v", err)
+	}
+	tests.Passed("Should have failed to load module outside of load directory")
+
+	if _, err := starlark.New(config.StarlarkConf{
+		Main:   "./fixtures/escape.star",
+		Target: "transform",
+	}, metrics.New()); err == nil || !strings.Contains(err.Error(), "must be within") {
+		tests.Failed("Should have failed to load module escaping load directory: %v", err)
+	}
+	tests.Passed("Should have failed to load module escaping load directory")
+
+	if _, err := starlark.New(config.StarlarkConf{
+		Main:   "./fixtures/main.star",
+		Target: "missing",
+	}, metrics.New()); err == nil {
+		tests.Failed("Should have failed to create Starlark instance with missing target")
+	}
+	tests.Passed("Should have failed to create Starlark instance with missing target")
+}
+
+func TestStarlarkSteps(t *testing.T) {
+	sl, err := starlark.New(config.StarlarkConf{
+		Main:     "./fixtures/loop.star",
+		Target:   "transform",
+		MaxSteps: 10000,
+	}, metrics.New())
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created Starlark instance")
+	}
+	tests.Passed("Should have successfully created Starlark instance")
+
+	tests.Header("Should cancel calls executing past the steps")
+	{
+		_, err := sl.Transform(context.Background(), map[string]interface{}{"loop": true})
+		if err == nil || !strings.Contains(err.Error(), "too many steps") || !strings.Contains(err.Error(), "loop.star:") {
+			tests.Failed("Should have received steps error with location but got %v", err)
+		}
+		tests.Passed("Should have received steps error with location")
+	}
+
+	tests.Header("Should cancel calls when the context is cancelled")
+	{
+		unlimited, err := starlark.New(config.StarlarkConf{
+			Main:     "./fixtures/loop.star",
+			Target:   "transform",
+			MaxSteps: 1 << 62,
+		}, metrics.New())
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created Starlark instance")
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+		}()
+
+		_, err = unlimited.Transform(ctx, map[string]interface{}{"loop": true})
+		if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+			tests.Failed("Should have been cancelled by the context but got %v", err)
+		}
+		tests.Passed("Should have been cancelled by the context")
+	}
+
+	tests.Header("Should keep transforming records after cancellations")
+	{
+		res, err := sl.Transform(context.Background(), map[string]interface{}{"loop": false})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully transformed records")
+		}
+
+		if len(res) != 1 || res[0]["loop"] != false {
+			tests.Failed("Should have received records: %#v", res)
+		}
+		tests.Passed("Should have successfully transformed records after cancellations")
+	}
+}
@@ -0,0 +1,177 @@
+package starlark
+
+import (
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/influx6/geckodataset/dataset"
+	star "go.starlark.net/starlark"
+)
+
+// isoLayout sets the layout times are passed into starlark as, matching the dates
+// exported by the javascript procs.
+const isoLayout = "2006-01-02T15:04:05.000Z"
+
+// toValue returns the starlark value of the giving go value, where maps become
+// dicts, slices become lists and times become iso strings.
+func toValue(value interface{}) star.Value {
+	switch item := value.(type) {
+	case nil:
+		return star.None
+	case star.Value:
+		return item
+	case bool:
+		return star.Bool(item)
+	case string:
+		return star.String(item)
+	case float64:
+		return star.Float(item)
+	case int:
+		return star.MakeInt(item)
+	case int64:
+		return star.MakeInt64(item)
+	case time.Time:
+		return star.String(item.UTC().Format(isoLayout))
+	case map[string]interface{}:
+		dict := star.NewDict(len(item))
+		for field, elem := range item {
+			dict.SetKey(star.String(field), toValue(elem))
+		}
+		return dict
+	case []map[string]interface{}:
+		elems := make([]star.Value, len(item))
+		for index, elem := range item {
+			elems[index] = toValue(elem)
+		}
+		return star.NewList(elems)
+	case []interface{}:
+		elems := make([]star.Value, len(item))
+		for index, elem := range item {
+			elems[index] = toValue(elem)
+		}
+		return star.NewList(elems)
+	}
+
+	// Ids like mongo's object ids are passed as their hex representation.
+	if hexer, ok := value.(interface{ Hex() string }); ok {
+		return star.String(hexer.Hex())
+	}
+
+	ref := reflect.ValueOf(value)
+	switch ref.Kind() {
+	case reflect.Ptr:
+		if ref.IsNil() {
+			return star.None
+		}
+		return toValue(ref.Elem().Interface())
+	case reflect.Map:
+		if ref.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		dict := star.NewDict(ref.Len())
+		for _, key := range ref.MapKeys() {
+			dict.SetKey(star.String(key.String()), toValue(ref.MapIndex(key).Interface()))
+		}
+		return dict
+	case reflect.Slice, reflect.Array:
+		// Bytes are passed as strings.
+		if ref.Type().Elem().Kind() == reflect.Uint8 {
+			return star.String(string(ref.Bytes()))
+		}
+
+		elems := make([]star.Value, ref.Len())
+		for index := range elems {
+			elems[index] = toValue(ref.Index(index).Interface())
+		}
+		return star.NewList(elems)
+	case reflect.String:
+		return star.String(ref.String())
+	case reflect.Bool:
+		return star.Bool(ref.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return star.MakeInt64(ref.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return star.MakeUint64(ref.Uint())
+	case reflect.Float32, reflect.Float64:
+		return star.Float(ref.Float())
+	}
+
+	return star.None
+}
+
+// fromValue returns the go value of the starlark value, normalised like the values
+// of the javascript procs, where ints and floats become floats, lists and tuples
+// become slices and dicts become maps keyed by the strings of their keys. The
+// returned bool is false for values left out of dicts, like functions.
+func fromValue(value star.Value) (interface{}, bool, error) {
+	return fromValueAt(value, 0)
+}
+
+// fromValueAt returns the go value of the starlark value nested at depth, failing
+// with dataset.ErrTooDeep past dataset.MaxDepth, like for lists containing themselves.
+func fromValueAt(value star.Value, depth int) (interface{}, bool, error) {
+	switch item := value.(type) {
+	case star.NoneType:
+		return nil, true, nil
+	case star.Bool:
+		return bool(item), true, nil
+	case star.String:
+		return string(item), true, nil
+	case star.Int:
+		result, ok := fromFloat(float64(item.Float()))
+		return result, ok, nil
+	case star.Float:
+		result, ok := fromFloat(float64(item))
+		return result, ok, nil
+	}
+
+	if depth > dataset.MaxDepth {
+		return nil, false, dataset.ErrTooDeep
+	}
+
+	switch item := value.(type) {
+	case star.Indexable:
+		// Lists and tuples, while strings are matched above.
+		result := make([]interface{}, item.Len())
+		for index := range result {
+			elem, _, err := fromValueAt(item.Index(index), depth+1)
+			if err != nil {
+				return nil, false, err
+			}
+			result[index] = elem
+		}
+		return result, true, nil
+	case *star.Dict:
+		result := make(map[string]interface{}, item.Len())
+		for _, entry := range item.Items() {
+			key, ok := entry[0].(star.String)
+			if !ok {
+				key = star.String(entry[0].String())
+			}
+
+			converted, ok, err := fromValueAt(entry[1], depth+1)
+			if err != nil {
+				return nil, false, err
+			}
+
+			if ok {
+				result[string(key)] = converted
+			}
+		}
+		return result, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// fromFloat returns the float, where NaN and infinities become nil, as JSON has no
+// representation of them.
+func fromFloat(value float64) (interface{}, bool) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, true
+	}
+
+	return value, true
+}
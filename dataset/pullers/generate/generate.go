@@ -0,0 +1,404 @@
+package generate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/geckodataset/dataset"
+)
+
+// field generator types ...
+const (
+	// SequenceField generates numbers from Start increased by Step for each record.
+	SequenceField = "sequence"
+
+	// IntField generates random integers between Min and Max, inclusive.
+	IntField = "int"
+
+	// FloatField generates random floats between Min and Max, rounded to Precision
+	// decimal places.
+	FloatField = "float"
+
+	// EnumField generates values picked from Values, using Weights if provided.
+	EnumField = "enum"
+
+	// DateField and DatetimeField generate random dates between From and To,
+	// formatted as dates (2006-01-02) or RFC3339 datetimes.
+	DateField     = "date"
+	DatetimeField = "datetime"
+
+	// NameField, FirstNameField and LastNameField generate fake person names.
+	NameField      = "name"
+	FirstNameField = "first_name"
+	LastNameField  = "last_name"
+)
+
+// DefaultWindow indicates the default window of generated dates, ending at
+// the time the generator is created.
+const DefaultWindow = time.Hour * 24 * 30
+
+// dateLayout sets the layout of generated dates and From/To values.
+const dateLayout = "2006-01-02"
+
+// FieldSpec embodies the definition of how values of a record field are generated.
+type FieldSpec struct {
+	Name string `toml:"name" json:"name"`
+	Type string `toml:"type" json:"type"`
+
+	// Start and Step set the first value and increment of sequence fields,
+	// where Step defaults to 1.
+	Start float64 `toml:"start" json:"start"`
+	Step  float64 `toml:"step" json:"step"`
+
+	// Min and Max set the range of int and float fields, while Precision sets
+	// the decimal places of float fields, defaulting to 2.
+	Min       float64 `toml:"min" json:"min"`
+	Max       float64 `toml:"max" json:"max"`
+	Precision *int    `toml:"precision" json:"precision"`
+
+	// Values and Weights set the values of enum fields and their relative weights,
+	// where values are picked with equal chances if no weights are provided.
+	Values  []interface{} `toml:"values" json:"values"`
+	Weights []float64     `toml:"weights" json:"weights"`
+
+	// From and To set the window of date and datetime fields, either as dates
+	// (2006-01-02) or RFC3339 datetimes. Defaults to the DefaultWindow till now.
+	From string `toml:"from" json:"from"`
+	To   string `toml:"to" json:"to"`
+
+	from time.Time
+	to   time.Time
+}
+
+// Validate returns an error if the spec is invalid.
+func (fs *FieldSpec) Validate() error {
+	if fs.Name == "" {
+		return errors.New("FieldSpec.Name is required")
+	}
+
+	fs.Type = strings.ToLower(fs.Type)
+
+	switch fs.Type {
+	case SequenceField:
+		if fs.Step == 0 {
+			fs.Step = 1
+		}
+	case IntField, FloatField:
+		if fs.Max < fs.Min {
+			return fmt.Errorf("FieldSpec.Max of %q can't be less than FieldSpec.Min", fs.Name)
+		}
+
+		if fs.Precision == nil {
+			precision := 2
+			fs.Precision = &precision
+		}
+
+		if *fs.Precision < 0 {
+			return fmt.Errorf("FieldSpec.Precision of %q can't be negative", fs.Name)
+		}
+	case EnumField:
+		if len(fs.Values) == 0 {
+			return fmt.Errorf("FieldSpec.Values of %q is required", fs.Name)
+		}
+
+		if len(fs.Weights) != 0 && len(fs.Weights) != len(fs.Values) {
+			return fmt.Errorf("FieldSpec.Weights of %q must have a weight for each value", fs.Name)
+		}
+
+		var total float64
+		for _, weight := range fs.Weights {
+			if weight < 0 {
+				return fmt.Errorf("FieldSpec.Weights of %q can't be negative", fs.Name)
+			}
+			total += weight
+		}
+
+		if len(fs.Weights) != 0 && total == 0 {
+			return fmt.Errorf("FieldSpec.Weights of %q can't all be zero", fs.Name)
+		}
+	case DateField, DatetimeField:
+		to := time.Now().UTC()
+		if fs.To != "" {
+			parsed, err := parseTime(fs.To)
+			if err != nil {
+				return fmt.Errorf("FieldSpec.To of %q is invalid: %+s", fs.Name, err.Error())
+			}
+			to = parsed
+		}
+
+		from := to.Add(-DefaultWindow)
+		if fs.From != "" {
+			parsed, err := parseTime(fs.From)
+			if err != nil {
+				return fmt.Errorf("FieldSpec.From of %q is invalid: %+s", fs.Name, err.Error())
+			}
+			from = parsed
+		}
+
+		if to.Before(from) {
+			return fmt.Errorf("FieldSpec.To of %q can't be before FieldSpec.From", fs.Name)
+		}
+
+		fs.from, fs.to = from, to
+	case NameField, FirstNameField, LastNameField:
+	default:
+		return fmt.Errorf("FieldSpec.Type of %q can only be either 'sequence', 'int', 'float', 'enum', 'date', 'datetime', 'name', 'first_name' or 'last_name' not %q", fs.Name, fs.Type)
+	}
+
+	return nil
+}
+
+// Config embodies the configuration used by Generator to produce records.
+type Config struct {
+	// Spec sets the fields of generated records.
+	Spec []FieldSpec `toml:"spec" json:"spec"`
+
+	// Seed sets the seed of the random values, where generators with the same
+	// seed and spec produce the same records. A random seed is used if zero.
+	Seed int64 `toml:"seed" json:"seed"`
+
+	// Total sets the total records generated, after which no more records are
+	// available. Records are generated until the context is done if zero.
+	Total int `toml:"total" json:"total"`
+
+	// Rate sets the maximum records generated per second, unlimited if zero.
+	Rate float64 `toml:"rate" json:"rate"`
+}
+
+// Validate returns an error if the config is invalid.
+func (c *Config) Validate() error {
+	if len(c.Spec) == 0 {
+		return errors.New("Config.Spec is required")
+	}
+
+	for index := range c.Spec {
+		if err := c.Spec[index].Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Total < 0 {
+		return errors.New("Config.Total can't be negative")
+	}
+
+	if c.Rate < 0 {
+		return errors.New("Config.Rate can't be negative")
+	}
+
+	if c.Total == 0 && c.Rate == 0 {
+		return errors.New("Config.Total or Config.Rate is required")
+	}
+
+	return nil
+}
+
+// Generator implements the dataset.DataPull interface for producing synthetic
+// records from a spec of fields.
+type Generator struct {
+	config Config
+	ml     sync.Mutex
+	random *rand.Rand
+	total  int
+	start  time.Time
+}
+
+// New returns a new instance of Generator for the giving config.
+func New(config Config) (*Generator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &Generator{
+		config: config,
+		random: rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+// Total returns the total records generated.
+func (g *Generator) Total() int {
+	g.ml.Lock()
+	defer g.ml.Unlock()
+	return g.total
+}
+
+// Pull returns the next batch of generated records. If the generator has a rate,
+// then Pull waits till the next record is due, returning only the records due.
+func (g *Generator) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	g.ml.Lock()
+	defer g.ml.Unlock()
+
+	if batch == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	if g.config.Total > 0 {
+		remaining := g.config.Total - g.total
+		if remaining <= 0 {
+			return nil, dataset.ErrNoMore
+		}
+
+		if batch > remaining {
+			batch = remaining
+		}
+	}
+
+	if g.config.Rate > 0 {
+		allowed, err := g.wait(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		batch = allowed
+	}
+
+	records := make([]map[string]interface{}, batch)
+	for index := range records {
+		records[index] = g.record()
+		g.total++
+	}
+
+	return records, nil
+}
+
+// wait blocks until at least a record can be generated within the rate, returning
+// the number of records of the batch due by then. The rate is measured from the
+// first pull, so it holds on average across batches.
+func (g *Generator) wait(ctx context.Context, batch int) (int, error) {
+	if g.start.IsZero() {
+		g.start = time.Now()
+	}
+
+	// Records are due one after the other, at the interval of the rate.
+	due := g.start.Add(time.Duration(float64(g.total) / g.config.Rate * float64(time.Second)))
+	if delay := time.Until(due); delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	elapsed := time.Since(g.start).Seconds()
+	allowed := int(elapsed*g.config.Rate) + 1 - g.total
+	if allowed < 1 {
+		allowed = 1
+	}
+
+	if batch > allowed {
+		batch = allowed
+	}
+
+	return batch, nil
+}
+
+// record returns a new record with values generated for all fields of the spec.
+func (g *Generator) record() map[string]interface{} {
+	record := make(map[string]interface{}, len(g.config.Spec))
+	for _, spec := range g.config.Spec {
+		record[spec.Name] = g.value(spec)
+	}
+	return record
+}
+
+// value returns a generated value for the giving field spec.
+func (g *Generator) value(spec FieldSpec) interface{} {
+	switch spec.Type {
+	case SequenceField:
+		return spec.Start + spec.Step*float64(g.total)
+	case IntField:
+		min, max := int64(math.Ceil(spec.Min)), int64(math.Floor(spec.Max))
+		if max <= min {
+			return min
+		}
+		return min + g.random.Int63n(max-min+1)
+	case FloatField:
+		value := spec.Min + g.random.Float64()*(spec.Max-spec.Min)
+		scale := math.Pow(10, float64(*spec.Precision))
+		return math.Round(value*scale) / scale
+	case EnumField:
+		return spec.Values[g.pick(spec.Weights, len(spec.Values))]
+	case DateField, DatetimeField:
+		window := spec.to.Sub(spec.from)
+		at := spec.from
+		if window > 0 {
+			at = at.Add(time.Duration(g.random.Int63n(int64(window) + 1)))
+		}
+
+		if spec.Type == DateField {
+			return at.Format(dateLayout)
+		}
+		return at.Format(time.RFC3339)
+	case FirstNameField:
+		return firstNames[g.random.Intn(len(firstNames))]
+	case LastNameField:
+		return lastNames[g.random.Intn(len(lastNames))]
+	case NameField:
+		first := firstNames[g.random.Intn(len(firstNames))]
+		return first + " " + lastNames[g.random.Intn(len(lastNames))]
+	default:
+		return nil
+	}
+}
+
+// pick returns the index of a value picked using the giving weights, or with
+// equal chances if no weights are provided.
+func (g *Generator) pick(weights []float64, total int) int {
+	if len(weights) == 0 {
+		return g.random.Intn(total)
+	}
+
+	var sum float64
+	for _, weight := range weights {
+		sum += weight
+	}
+
+	target := g.random.Float64() * sum
+	for index, weight := range weights {
+		if target < weight {
+			return index
+		}
+		target -= weight
+	}
+
+	// Rounding may leave the target past the last weight, which then belongs
+	// to the last value with a weight.
+	for index := len(weights) - 1; index > 0; index-- {
+		if weights[index] > 0 {
+			return index
+		}
+	}
+	return 0
+}
+
+// parseTime returns the time of the giving date or RFC3339 datetime.
+func parseTime(value string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at.UTC(), nil
+	}
+	return time.Parse(dateLayout, value)
+}
+
+var firstNames = []string{
+	"Alex", "Amara", "Ben", "Carlos", "Chen", "Daniel", "Elena", "Fatima", "George", "Hana",
+	"Ibrahim", "Isla", "James", "Kenji", "Laura", "Liam", "Maria", "Mohammed", "Nina", "Noah",
+	"Olivia", "Omar", "Priya", "Rosa", "Samuel", "Sofia", "Thomas", "Yuki", "Zara", "Zoe",
+}
+
+var lastNames = []string{
+	"Adeyemi", "Anderson", "Brown", "Costa", "Dubois", "Garcia", "Hansen", "Ito", "Jones", "Khan",
+	"Kowalski", "Lee", "Martin", "Miller", "Moreau", "Nguyen", "Novak", "Okafor", "Patel", "Rossi",
+	"Schmidt", "Silva", "Smith", "Tanaka", "Taylor", "Wang", "Williams", "Wilson", "Yilmaz", "Zhang",
+}
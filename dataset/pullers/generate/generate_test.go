@@ -0,0 +1,158 @@
+package generate_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/pullers/generate"
+)
+
+func spec() []generate.FieldSpec {
+	return []generate.FieldSpec{
+		{Name: "id", Type: "sequence", Start: 100},
+		{Name: "units", Type: "int", Min: 1, Max: 5},
+		{Name: "price", Type: "float", Min: 10, Max: 20},
+		{Name: "plan", Type: "enum", Values: []interface{}{"free", "pro", "legacy"}, Weights: []float64{3, 1, 0}},
+		{Name: "day", Type: "date", From: "2018-01-01", To: "2018-01-31"},
+		{Name: "at", Type: "datetime", From: "2018-01-01T00:00:00Z", To: "2018-01-02T00:00:00Z"},
+		{Name: "customer", Type: "name"},
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	gen, err := generate.New(generate.Config{Spec: spec(), Seed: 42, Total: 50})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created generator")
+	}
+	tests.Passed("Should have successfully created generator")
+
+	records, err := gen.Pull(context.Background(), 30)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully pulled records")
+	}
+	tests.Passed("Should have successfully pulled records")
+
+	rest, err := gen.Pull(context.Background(), 30)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully pulled records")
+	}
+	tests.Passed("Should have successfully pulled records")
+
+	if len(records) != 30 || len(rest) != 20 {
+		tests.Failed("Should have received only total records but got %d and %d", len(records), len(rest))
+	}
+	tests.Passed("Should have received only total records")
+
+	if _, err := gen.Pull(context.Background(), 30); err != dataset.ErrNoMore {
+		tests.Failed("Should have received dataset.ErrNoMore after total records")
+	}
+	tests.Passed("Should have received dataset.ErrNoMore after total records")
+
+	for index, record := range append(records, rest...) {
+		if record["id"] != float64(100+index) {
+			tests.Failed("Should have received sequence %d but got %v", 100+index, record["id"])
+		}
+
+		if units := record["units"].(int64); units < 1 || units > 5 {
+			tests.Failed("Should have received units within range but got %d", units)
+		}
+
+		if price := record["price"].(float64); price < 10 || price > 20 {
+			tests.Failed("Should have received price within range but got %f", price)
+		}
+
+		if record["plan"] == "legacy" {
+			tests.Failed("Should never have received value without weight")
+		}
+
+		if day := record["day"].(string); day < "2018-01-01" || day > "2018-01-31" {
+			tests.Failed("Should have received day within window but got %s", day)
+		}
+
+		at, err := time.Parse(time.RFC3339, record["at"].(string))
+		if err != nil || at.Day() < 1 || at.After(time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC)) {
+			tests.Failed("Should have received datetime within window but got %s", record["at"])
+		}
+
+		if record["customer"] == "" {
+			tests.Failed("Should have received customer name")
+		}
+	}
+	tests.Passed("Should have received values within spec")
+}
+
+func TestGeneratorSeed(t *testing.T) {
+	first, err := generate.New(generate.Config{Spec: spec(), Seed: 7, Total: 20})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created generator")
+	}
+
+	second, err := generate.New(generate.Config{Spec: spec(), Seed: 7, Total: 20})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created generator")
+	}
+	tests.Passed("Should have successfully created generators")
+
+	left, _ := first.Pull(context.Background(), 20)
+	right, _ := second.Pull(context.Background(), 20)
+	if !reflect.DeepEqual(left, right) {
+		tests.Failed("Should have generated the same records for the same seed")
+	}
+	tests.Passed("Should have generated the same records for the same seed")
+}
+
+func TestGeneratorRate(t *testing.T) {
+	gen, err := generate.New(generate.Config{Spec: spec(), Seed: 1, Rate: 100})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created generator")
+	}
+	tests.Passed("Should have successfully created generator")
+
+	start := time.Now()
+	for gen.Total() < 30 {
+		if _, err := gen.Pull(context.Background(), 10); err != nil {
+			tests.FailedWithError(err, "Should have successfully pulled records")
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		tests.Failed("Should have generated records within rate but took %s", elapsed)
+	}
+	tests.Passed("Should have generated records within rate")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	slow, err := generate.New(generate.Config{Spec: spec(), Rate: 0.001})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created generator")
+	}
+
+	slow.Pull(ctx, 1)
+	if _, err := slow.Pull(ctx, 1); err != context.Canceled {
+		tests.Failed("Should have returned context error while waiting")
+	}
+	tests.Passed("Should have returned context error while waiting")
+}
+
+func TestGeneratorConfig(t *testing.T) {
+	configs := []generate.Config{
+		{Total: 10},
+		{Spec: []generate.FieldSpec{{Name: "id", Type: "sequence"}}},
+		{Spec: []generate.FieldSpec{{Name: "id", Type: "uuid"}}, Total: 10},
+		{Spec: []generate.FieldSpec{{Name: "n", Type: "int", Min: 5, Max: 1}}, Total: 10},
+		{Spec: []generate.FieldSpec{{Name: "plan", Type: "enum", Values: []interface{}{"a"}, Weights: []float64{1, 2}}}, Total: 10},
+		{Spec: []generate.FieldSpec{{Name: "day", Type: "date", From: "2018-02-01", To: "2018-01-01"}}, Total: 10},
+	}
+
+	for _, config := range configs {
+		if _, err := generate.New(config); err == nil {
+			tests.Failed("Should have failed to validate config: %#v", config)
+		}
+	}
+	tests.Passed("Should have failed to validate invalid configs")
+}
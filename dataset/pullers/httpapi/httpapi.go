@@ -0,0 +1,537 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/geckodataset/dataset"
+)
+
+const (
+	// DefaultTimeout indicates the default timeout for each request.
+	DefaultTimeout = time.Second * 30
+
+	// DefaultRetryWait indicates the default time waited before a failed
+	// request is retried, which increases with every attempt.
+	DefaultRetryWait = time.Second
+)
+
+// pagination types ...
+const (
+	NoPagination     = ""
+	PagePagination   = "page"
+	OffsetPagination = "offset"
+	CursorPagination = "cursor"
+	LinkPagination   = "link"
+)
+
+// auth types ...
+const (
+	BearerAuth = "bearer"
+	BasicAuth  = "basic"
+	HeaderAuth = "header"
+)
+
+// AuthConfig embodies the authentication used for requests.
+type AuthConfig struct {
+	// Type sets the authentication type: bearer, basic or header.
+	Type string `toml:"type" json:"type"`
+
+	// Token sets the bearer token or header value, or TokenEnv sets the
+	// environment variable to read it from.
+	Token    string `toml:"token" json:"token"`
+	TokenEnv string `toml:"token_env" json:"token_env"`
+
+	// Header sets the header name used for header authentication.
+	Header string `toml:"header" json:"header"`
+
+	// Username and Password set the basic authentication credentials, where
+	// PasswordEnv sets the environment variable to read the password from.
+	Username    string `toml:"username" json:"username"`
+	Password    string `toml:"password" json:"password"`
+	PasswordEnv string `toml:"password_env" json:"password_env"`
+}
+
+// Validate returns an error if the config is invalid.
+func (ac AuthConfig) Validate() error {
+	switch strings.ToLower(ac.Type) {
+	case "":
+		return nil
+	case BearerAuth:
+		return nil
+	case HeaderAuth:
+		if ac.Header == "" {
+			return errors.New("AuthConfig.Header is required for header authentication")
+		}
+		return nil
+	case BasicAuth:
+		if ac.Username == "" {
+			return errors.New("AuthConfig.Username is required for basic authentication")
+		}
+		return nil
+	default:
+		return fmt.Errorf("AuthConfig.Type can only be either 'bearer', 'basic' or 'header' not %q", ac.Type)
+	}
+}
+
+// apply sets the authentication on the giving request.
+func (ac AuthConfig) apply(req *http.Request) {
+	token := ac.Token
+	if ac.TokenEnv != "" {
+		token = strings.TrimSpace(os.Getenv(ac.TokenEnv))
+	}
+
+	switch strings.ToLower(ac.Type) {
+	case BearerAuth:
+		req.Header.Set("Authorization", "Bearer "+token)
+	case HeaderAuth:
+		req.Header.Set(ac.Header, token)
+	case BasicAuth:
+		password := ac.Password
+		if ac.PasswordEnv != "" {
+			password = strings.TrimSpace(os.Getenv(ac.PasswordEnv))
+		}
+		req.SetBasicAuth(ac.Username, password)
+	}
+}
+
+// PaginationConfig embodies the strategy used to request pages of records.
+type PaginationConfig struct {
+	// Type sets the pagination strategy: page, offset, cursor or link. If empty
+	// then only a single request is made.
+	Type string `toml:"type" json:"type"`
+
+	// PageParam sets the query parameter for the page number, starting from
+	// StartPage, which defaults to 1.
+	PageParam string `toml:"page_param" json:"page_param"`
+	StartPage int    `toml:"start_page" json:"start_page"`
+
+	// OffsetParam sets the query parameter for the offset of records.
+	OffsetParam string `toml:"offset_param" json:"offset_param"`
+
+	// LimitParam sets the query parameter for the total records expected per
+	// request, which is set to the pull batch.
+	LimitParam string `toml:"limit_param" json:"limit_param"`
+
+	// CursorParam sets the query parameter for the cursor token, read from
+	// the response at the JSONPath CursorPath.
+	CursorParam string `toml:"cursor_param" json:"cursor_param"`
+	CursorPath  string `toml:"cursor_path" json:"cursor_path"`
+}
+
+// Validate returns an error if the config is invalid.
+func (pc *PaginationConfig) Validate() error {
+	pc.Type = strings.ToLower(pc.Type)
+
+	switch pc.Type {
+	case NoPagination, LinkPagination:
+	case PagePagination:
+		if pc.PageParam == "" {
+			pc.PageParam = "page"
+		}
+
+		if pc.StartPage == 0 {
+			pc.StartPage = 1
+		}
+	case OffsetPagination:
+		if pc.OffsetParam == "" {
+			pc.OffsetParam = "offset"
+		}
+
+		if pc.LimitParam == "" {
+			pc.LimitParam = "limit"
+		}
+	case CursorPagination:
+		if pc.CursorPath == "" {
+			return errors.New("PaginationConfig.CursorPath is required for cursor pagination")
+		}
+
+		if pc.CursorParam == "" {
+			pc.CursorParam = "cursor"
+		}
+	default:
+		return fmt.Errorf("PaginationConfig.Type can only be either 'page', 'offset', 'cursor' or 'link' not %q", pc.Type)
+	}
+
+	return nil
+}
+
+// Config embodies the configuration used by HTTPPull to request records
+// from a REST API.
+type Config struct {
+	URL     string            `toml:"url" json:"url"`
+	Method  string            `toml:"method" json:"method"`
+	Body    string            `toml:"body" json:"body"`
+	Headers map[string]string `toml:"headers" json:"headers"`
+
+	// Records sets the JSONPath to the records within the response.
+	Records string `toml:"records" json:"records"`
+
+	Auth       AuthConfig       `toml:"auth" json:"auth"`
+	Pagination PaginationConfig `toml:"pagination" json:"pagination"`
+
+	// Timeout sets the timeout of each request.
+	Timeout string `toml:"timeout" json:"timeout"`
+
+	// Retries sets the total retries of a request which failed due to network
+	// errors or server errors, waiting RetryWait multiplied by the attempt.
+	Retries   int    `toml:"retries" json:"retries"`
+	RetryWait string `toml:"retry_wait" json:"retry_wait"`
+
+	RequestTimeout time.Duration `toml:"-" json:"-"`
+	RetryDelay     time.Duration `toml:"-" json:"-"`
+}
+
+// Validate returns an error if the config is invalid.
+func (c *Config) Validate() error {
+	if c.URL == "" {
+		return errors.New("Config.URL is required")
+	}
+
+	if _, err := url.Parse(c.URL); err != nil {
+		return err
+	}
+
+	if c.Method == "" {
+		c.Method = http.MethodGet
+	}
+
+	c.RequestTimeout = DefaultTimeout
+	if c.Timeout != "" {
+		timeout, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return err
+		}
+		c.RequestTimeout = timeout
+	}
+
+	c.RetryDelay = DefaultRetryWait
+	if c.RetryWait != "" {
+		wait, err := time.ParseDuration(c.RetryWait)
+		if err != nil {
+			return err
+		}
+		c.RetryDelay = wait
+	}
+
+	if err := c.Auth.Validate(); err != nil {
+		return err
+	}
+
+	return c.Pagination.Validate()
+}
+
+// HTTPPull implements the dataset.DataPull interface for pulling records from a REST
+// API, where the next page of records is requested using the configured pagination
+// strategy once all records from the last page have been pulled.
+type HTTPPull struct {
+	Client *http.Client
+
+	config  Config
+	ml      sync.Mutex
+	done    bool
+	page    int
+	offset  int
+	cursor  string
+	nextURL string
+	records []map[string]interface{}
+}
+
+// New returns a new instance of HTTPPull.
+func New(config Config) (*HTTPPull, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &HTTPPull{
+		Client: http.DefaultClient,
+		config: config,
+		page:   config.Pagination.StartPage,
+	}, nil
+}
+
+// Pull returns the next batch of records, requesting the next page if all records
+// from the last page have been pulled.
+func (hp *HTTPPull) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	hp.ml.Lock()
+	defer hp.ml.Unlock()
+
+	if batch == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	if len(hp.records) == 0 && !hp.done {
+		if err := hp.fetch(ctx, batch); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(hp.records) == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	if batch >= len(hp.records) {
+		records := hp.records
+		hp.records = nil
+		return records, nil
+	}
+
+	next := hp.records[:batch]
+	hp.records = hp.records[batch:]
+	return next, nil
+}
+
+// fetch requests the next page of records and moves the pagination forward.
+func (hp *HTTPPull) fetch(ctx context.Context, batch int) error {
+	target, err := hp.target(batch)
+	if err != nil {
+		return err
+	}
+
+	body, header, err := hp.do(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return err
+	}
+
+	found, err := Lookup(payload, hp.config.Records)
+	if err != nil {
+		return err
+	}
+
+	records, err := toRecords(found)
+	if err != nil {
+		return err
+	}
+
+	hp.records = records
+
+	switch hp.config.Pagination.Type {
+	// Servers may return less records than the limit requested, so page and
+	// offset pagination are only done once an empty page is received.
+	case PagePagination:
+		hp.page++
+	case OffsetPagination:
+		hp.offset += len(records)
+	case CursorPagination:
+		cursor, err := cursorOf(body, hp.config.Pagination.CursorPath)
+		if err != nil {
+			return err
+		}
+
+		hp.cursor = cursor
+		hp.done = hp.cursor == ""
+	case LinkPagination:
+		next := nextLink(header.Get("Link"))
+		if next != "" {
+			base, err := url.Parse(target)
+			if err != nil {
+				return err
+			}
+
+			nextURL, err := base.Parse(next)
+			if err != nil {
+				return err
+			}
+
+			next = nextURL.String()
+		}
+
+		hp.nextURL = next
+		hp.done = next == ""
+	default:
+		hp.done = true
+	}
+
+	if len(records) == 0 {
+		hp.done = true
+	}
+
+	return nil
+}
+
+// cursorOf returns the cursor found at the path of the body, where numbers are
+// kept as written, since large numbers decoded as float64 are formatted with
+// an exponent.
+func cursorOf(body []byte, path string) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return "", err
+	}
+
+	cursor, err := Lookup(payload, path)
+	if err != nil {
+		return "", err
+	}
+
+	if cursor == nil {
+		return "", nil
+	}
+
+	return fmt.Sprint(cursor), nil
+}
+
+// target returns the url for the next request.
+func (hp *HTTPPull) target(batch int) (string, error) {
+	if hp.nextURL != "" {
+		return hp.nextURL, nil
+	}
+
+	target, err := url.Parse(hp.config.URL)
+	if err != nil {
+		return "", err
+	}
+
+	pagination := hp.config.Pagination
+
+	query := target.Query()
+	if pagination.Type != NoPagination && pagination.LimitParam != "" {
+		query.Set(pagination.LimitParam, strconv.Itoa(batch))
+	}
+
+	switch pagination.Type {
+	case PagePagination:
+		query.Set(pagination.PageParam, strconv.Itoa(hp.page))
+	case OffsetPagination:
+		query.Set(pagination.OffsetParam, strconv.Itoa(hp.offset))
+	case CursorPagination:
+		if hp.cursor != "" {
+			query.Set(pagination.CursorParam, hp.cursor)
+		}
+	}
+
+	target.RawQuery = query.Encode()
+	return target.String(), nil
+}
+
+// do issues the request to the giving url, retrying on network or server errors.
+func (hp *HTTPPull) do(ctx context.Context, target string) ([]byte, http.Header, error) {
+	for attempt := 0; ; attempt++ {
+		body, header, retry, err := hp.request(ctx, target)
+		if err == nil {
+			return body, header, nil
+		}
+
+		if !retry || attempt >= hp.config.Retries {
+			return nil, nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(hp.config.RetryDelay * time.Duration(attempt+1)):
+		}
+	}
+}
+
+// request issues a single request, returning true if the request can be retried
+// when it failed.
+func (hp *HTTPPull) request(ctx context.Context, target string) ([]byte, http.Header, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, hp.config.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(hp.config.Method, target, bytes.NewBufferString(hp.config.Body))
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	for name, value := range hp.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	hp.config.Auth.apply(req)
+
+	res, err := hp.Client.Do(req)
+	if err != nil {
+		return nil, nil, true, err
+	}
+
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, true, err
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		return nil, nil, true, fmt.Errorf("request to %q failed with status %d: %s", target, res.StatusCode, body)
+	}
+
+	if res.StatusCode >= 400 {
+		return nil, nil, false, fmt.Errorf("request to %q failed with status %d: %s", target, res.StatusCode, body)
+	}
+
+	return body, res.Header, false, nil
+}
+
+// toRecords returns the giving value as a list of records.
+func toRecords(value interface{}) ([]map[string]interface{}, error) {
+	switch item := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return []map[string]interface{}{item}, nil
+	case []interface{}:
+		records := make([]map[string]interface{}, 0, len(item))
+		for _, elem := range item {
+			record, ok := elem.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected records to be objects but got %T", elem)
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	default:
+		return nil, fmt.Errorf("expected records to be an array of objects but got %T", value)
+	}
+}
+
+// nextLink returns the url of the `next` relation within a Link header.
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "rel=") {
+				continue
+			}
+
+			for _, rel := range strings.Fields(strings.Trim(strings.TrimPrefix(param, "rel="), `"`)) {
+				if rel == "next" {
+					return strings.Trim(target, "<>")
+				}
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,308 @@
+package httpapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/pullers/httpapi"
+)
+
+// totalSales is the total records served by the test servers.
+const totalSales = 7
+
+func sales(start int, end int) []map[string]interface{} {
+	var records []map[string]interface{}
+	for i := start; i < end && i < totalSales; i++ {
+		records = append(records, map[string]interface{}{"id": i, "user": fmt.Sprintf("user-%d", i)})
+	}
+	return records
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+func pullAll(t *testing.T, config httpapi.Config, batch int) []map[string]interface{} {
+	puller, err := httpapi.New(config)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created puller")
+	}
+	tests.Passed("Should have successfully created puller")
+
+	var records []map[string]interface{}
+	for {
+		recs, err := puller.Pull(context.Background(), batch)
+		if err != nil {
+			if err == dataset.ErrNoMore {
+				break
+			}
+			tests.FailedWithError(err, "Should have successfully pulled records")
+		}
+
+		if len(recs) > batch {
+			tests.Failed("Should have received at most %d records but got %d", batch, len(recs))
+		}
+
+		records = append(records, recs...)
+	}
+
+	return records
+}
+
+func TestHTTPPullWithPagePagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		writeJSON(w, map[string]interface{}{
+			"data": map[string]interface{}{"items": sales((page-1)*size, page*size)},
+		})
+	}))
+	defer server.Close()
+
+	records := pullAll(t, httpapi.Config{
+		URL:     server.URL,
+		Records: "$.data.items",
+		Auth: httpapi.AuthConfig{
+			Type:  httpapi.BearerAuth,
+			Token: "secret",
+		},
+		Pagination: httpapi.PaginationConfig{
+			Type:       httpapi.PagePagination,
+			LimitParam: "per_page",
+		},
+	}, 3)
+
+	if len(records) != totalSales {
+		tests.Failed("Should have received %d records but got %d", totalSales, len(records))
+	}
+	tests.Passed("Should have received %d records", totalSales)
+}
+
+func TestHTTPPullWithOffsetPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		writeJSON(w, sales(offset, offset+limit))
+	}))
+	defer server.Close()
+
+	records := pullAll(t, httpapi.Config{
+		URL: server.URL,
+		Pagination: httpapi.PaginationConfig{
+			Type: httpapi.OffsetPagination,
+		},
+	}, 2)
+
+	if len(records) != totalSales {
+		tests.Failed("Should have received %d records but got %d", totalSales, len(records))
+	}
+	tests.Passed("Should have received %d records", totalSales)
+}
+
+func TestHTTPPullWithCappedOffsetPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		writeJSON(w, sales(offset, offset+2))
+	}))
+	defer server.Close()
+
+	records := pullAll(t, httpapi.Config{
+		URL: server.URL,
+		Pagination: httpapi.PaginationConfig{
+			Type: httpapi.OffsetPagination,
+		},
+	}, 5)
+
+	if len(records) != totalSales {
+		tests.Failed("Should have received %d records from server capping page size but got %d", totalSales, len(records))
+	}
+	tests.Passed("Should have received %d records from server capping page size", totalSales)
+}
+
+func TestHTTPPullWithNumericCursorPagination(t *testing.T) {
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		after := r.URL.Query().Get("after")
+		cursors = append(cursors, after)
+
+		if after != "" {
+			writeJSON(w, map[string]interface{}{"records": sales(3, 6)})
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"records": sales(0, 3),
+			"next":    12345678901,
+		})
+	}))
+	defer server.Close()
+
+	records := pullAll(t, httpapi.Config{
+		URL:     server.URL,
+		Records: "records",
+		Pagination: httpapi.PaginationConfig{
+			Type:        httpapi.CursorPagination,
+			CursorParam: "after",
+			CursorPath:  "$.next",
+		},
+	}, 5)
+
+	if len(records) != 6 {
+		tests.Failed("Should have received %d records but got %d", 6, len(records))
+	}
+	tests.Passed("Should have received %d records", 6)
+
+	if len(cursors) != 2 || cursors[1] != "12345678901" {
+		tests.Failed("Should have requested next page with numeric cursor as written but got %q", cursors)
+	}
+	tests.Passed("Should have requested next page with numeric cursor as written")
+}
+
+func TestHTTPPullWithCursorPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		after, _ := strconv.Atoi(r.URL.Query().Get("after"))
+
+		next := ""
+		if after+3 < totalSales {
+			next = strconv.Itoa(after + 3)
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"records": sales(after, after+3),
+			"meta":    map[string]interface{}{"next": next},
+		})
+	}))
+	defer server.Close()
+
+	records := pullAll(t, httpapi.Config{
+		URL:     server.URL,
+		Records: "records",
+		Pagination: httpapi.PaginationConfig{
+			Type:        httpapi.CursorPagination,
+			CursorParam: "after",
+			CursorPath:  "$.meta.next",
+		},
+	}, 5)
+
+	if len(records) != totalSales {
+		tests.Failed("Should have received %d records but got %d", totalSales, len(records))
+	}
+	tests.Passed("Should have received %d records", totalSales)
+}
+
+func TestHTTPPullWithLinkPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("p"))
+		if (page+1)*3 < totalSales {
+			w.Header().Set("Link", fmt.Sprintf(`</sales?p=%d>; rel="next", </sales?p=2>; rel="last"`, page+1))
+		}
+
+		writeJSON(w, sales(page*3, (page+1)*3))
+	}))
+	defer server.Close()
+
+	records := pullAll(t, httpapi.Config{
+		URL: server.URL + "/sales",
+		Auth: httpapi.AuthConfig{
+			Type:   httpapi.HeaderAuth,
+			Header: "X-API-Key",
+			Token:  "secret",
+		},
+		Pagination: httpapi.PaginationConfig{
+			Type: httpapi.LinkPagination,
+		},
+	}, 3)
+
+	if len(records) != totalSales {
+		tests.Failed("Should have received %d records but got %d", totalSales, len(records))
+	}
+	tests.Passed("Should have received %d records", totalSales)
+}
+
+func TestHTTPPullWithRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		writeJSON(w, sales(0, totalSales))
+	}))
+	defer server.Close()
+
+	tests.Header("When requests fail less than allowed retries")
+	{
+		records := pullAll(t, httpapi.Config{
+			URL:       server.URL,
+			Retries:   3,
+			RetryWait: "1ms",
+		}, 10)
+
+		if len(records) != totalSales {
+			tests.Failed("Should have received %d records but got %d", totalSales, len(records))
+		}
+		tests.Passed("Should have received %d records", totalSales)
+	}
+
+	tests.Header("When requests fail more than allowed retries")
+	{
+		atomic.StoreInt32(&calls, 0)
+
+		puller, err := httpapi.New(httpapi.Config{
+			URL:       server.URL,
+			Retries:   1,
+			RetryWait: "1ms",
+		})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created puller")
+		}
+		tests.Passed("Should have successfully created puller")
+
+		if _, err := puller.Pull(context.Background(), 10); err == nil {
+			tests.Failed("Should have failed after exhausting retries")
+		}
+		tests.Passed("Should have failed after exhausting retries")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	data := map[string]interface{}{
+		"data": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"name": "bob"},
+			},
+		},
+	}
+
+	for _, path := range []string{"$.data.items[0].name", "data.items[0]['name']", `$['data']['items'][0].name`} {
+		value, err := httpapi.Lookup(data, path)
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully looked up path %q", path)
+		}
+
+		if value != "bob" {
+			tests.Failed("Should have found 'bob' at path %q but got %#v", path, value)
+		}
+		tests.Passed("Should have found 'bob' at path %q", path)
+	}
+}
@@ -0,0 +1,83 @@
+package httpapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Lookup returns the value found at the giving JSONPath within data. It supports
+// the root `$`, dot notation `.field`, bracket notation `['field']` and array
+// indexes `[0]`, which covers locating records and cursors within responses.
+// An empty path or `$` returns data itself.
+func Lookup(data interface{}, path string) (interface{}, error) {
+	parts, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := data
+	for _, part := range parts {
+		switch item := current.(type) {
+		case map[string]interface{}:
+			value, ok := item[part]
+			if !ok {
+				return nil, nil
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath %q: %q is not an array index", path, part)
+			}
+
+			if index < 0 || index >= len(item) {
+				return nil, nil
+			}
+			current = item[index]
+		default:
+			return nil, nil
+		}
+	}
+
+	return current, nil
+}
+
+// splitPath splits the JSONPath into the keys and indexes it references.
+func splitPath(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	var parts []string
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end == -1 {
+				end = len(path)
+			}
+
+			if end == 0 {
+				return nil, fmt.Errorf("jsonpath: empty key in %q", path)
+			}
+
+			parts = append(parts, path[:end])
+			path = path[end:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("jsonpath: unterminated bracket in %q", path)
+			}
+
+			key := strings.Trim(path[1:end], `'"`)
+			parts = append(parts, key)
+			path = path[end+1:]
+		default:
+			// allow paths without leading dot e.g `data.items`.
+			path = "." + path
+		}
+	}
+
+	return parts, nil
+}
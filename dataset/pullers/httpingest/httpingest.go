@@ -0,0 +1,361 @@
+package httpingest
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/pullers/jsonfiles"
+)
+
+const (
+	// DefaultQueueSize indicates the default maximum records buffered.
+	DefaultQueueSize = 10000
+
+	// DefaultFlushInterval indicates the default maximum time records are
+	// buffered before being pulled, if a full batch is not received.
+	DefaultFlushInterval = time.Second * 5
+
+	// DefaultPath indicates the default path records are posted to.
+	DefaultPath = "/"
+
+	// maxBodySize sets the maximum size of a request body.
+	maxBodySize = 10 << 20
+
+	// decodeBatch sets the records read at a time when decoding a request body.
+	decodeBatch = 1000
+)
+
+// Config embodies the configuration used by Ingest to receive records
+// posted to it's HTTP server.
+type Config struct {
+	// Addr sets the address the HTTP server listens on (e.g :8080).
+	Addr string `toml:"addr" json:"addr"`
+
+	// Path sets the path records are posted to, defaults to DefaultPath.
+	Path string `toml:"path" json:"path"`
+
+	// Secret sets the shared secret requests must provide as a bearer token
+	// in the Authorization header, or SecretEnv sets the environment variable
+	// to read it from. If both are empty, then requests are not authenticated.
+	Secret    string `toml:"secret" json:"secret"`
+	SecretEnv string `toml:"secret_env" json:"secret_env"`
+
+	// QueueSize sets the maximum records buffered, where requests whose records
+	// do not fit are rejected with 429 Too Many Requests.
+	QueueSize int `toml:"queue_size" json:"queue_size"`
+
+	// FlushInterval sets the maximum time records are buffered before being
+	// pulled, if a full batch is not received.
+	FlushInterval string `toml:"flush_interval" json:"flush_interval"`
+
+	FlushDuration time.Duration `toml:"-" json:"-"`
+}
+
+// Validate returns an error if the config is invalid.
+func (c *Config) Validate() error {
+	if c.Addr == "" {
+		return errors.New("Config.Addr is required")
+	}
+
+	if c.Path == "" {
+		c.Path = DefaultPath
+	}
+
+	if !strings.HasPrefix(c.Path, "/") {
+		return errors.New("Config.Path must start with '/'")
+	}
+
+	if c.QueueSize < 0 {
+		return errors.New("Config.QueueSize can't be negative")
+	}
+
+	if c.QueueSize == 0 {
+		c.QueueSize = DefaultQueueSize
+	}
+
+	c.FlushDuration = DefaultFlushInterval
+	if c.FlushInterval != "" {
+		flush, err := time.ParseDuration(c.FlushInterval)
+		if err != nil {
+			return err
+		}
+
+		c.FlushDuration = flush
+	}
+
+	return nil
+}
+
+// entry embodies a buffered record with the time it was received.
+type entry struct {
+	received time.Time
+	record   map[string]interface{}
+}
+
+// Ingest implements the dataset.DataPull interface for records posted to it's HTTP
+// server as JSON arrays or newline delimited JSON objects, which are buffered into a
+// bounded queue till pulled.
+type Ingest struct {
+	config Config
+	secret string
+	server *http.Server
+	notify chan struct{}
+
+	ml       sync.Mutex
+	queue    []entry
+	closed   bool
+	listener net.Listener
+}
+
+// New returns a new instance of Ingest, which starts receiving records once Start is called.
+func New(config Config) (*Ingest, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	secret := config.Secret
+	if config.SecretEnv != "" {
+		secret = strings.TrimSpace(os.Getenv(config.SecretEnv))
+	}
+
+	in := &Ingest{
+		config: config,
+		secret: secret,
+		notify: make(chan struct{}, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(config.Path, in)
+	in.server = &http.Server{Addr: config.Addr, Handler: mux}
+
+	return in, nil
+}
+
+// Start starts the HTTP server on the address of the config.
+func (in *Ingest) Start() error {
+	listener, err := net.Listen("tcp", in.config.Addr)
+	if err != nil {
+		return err
+	}
+
+	in.ml.Lock()
+	in.listener = listener
+	in.ml.Unlock()
+
+	go in.server.Serve(listener)
+	return nil
+}
+
+// Addr returns the address the HTTP server listens on, or the address
+// of the config if it has not being started.
+func (in *Ingest) Addr() string {
+	in.ml.Lock()
+	defer in.ml.Unlock()
+
+	if in.listener == nil {
+		return in.config.Addr
+	}
+
+	return in.listener.Addr().String()
+}
+
+// Close stops the HTTP server, waiting for requests in progress. Records
+// still buffered can be pulled before a dataset.ErrNoMore is returned.
+func (in *Ingest) Close() error {
+	in.ml.Lock()
+	if in.closed {
+		in.ml.Unlock()
+		return nil
+	}
+
+	in.closed = true
+	in.ml.Unlock()
+
+	in.wake()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	return in.server.Shutdown(ctx)
+}
+
+// Pending returns the total records buffered.
+func (in *Ingest) Pending() int {
+	in.ml.Lock()
+	defer in.ml.Unlock()
+	return len(in.queue)
+}
+
+// Pull returns the next batch of records, blocking till batch records are buffered
+// or the oldest record buffered has waited the flush interval.
+func (in *Ingest) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	if batch == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	for {
+		in.ml.Lock()
+		pending := len(in.queue)
+
+		var wait time.Duration
+		if pending != 0 {
+			wait = in.config.FlushDuration - time.Since(in.queue[0].received)
+		}
+
+		if pending >= batch || (pending != 0 && (wait <= 0 || in.closed)) {
+			if pending > batch {
+				pending = batch
+			}
+
+			records := make([]map[string]interface{}, pending)
+			for index, item := range in.queue[:pending] {
+				records[index] = item.record
+			}
+
+			in.queue = in.queue[pending:]
+			in.ml.Unlock()
+			return records, nil
+		}
+
+		closed := in.closed
+		in.ml.Unlock()
+
+		if closed {
+			return nil, dataset.ErrNoMore
+		}
+
+		var flush <-chan time.Time
+		var timer *time.Timer
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			flush = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			err := ctx.Err()
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil, err
+		case <-in.notify:
+		case <-flush:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// ServeHTTP receives records posted as a JSON array or as newline delimited JSON objects.
+// Requests are rejected with 429 Too Many Requests if their records do not fit into the queue.
+func (in *Ingest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST requests are accepted", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !in.authorized(r) {
+		http.Error(w, "invalid or missing secret", http.StatusUnauthorized)
+		return
+	}
+
+	records, err := Decode(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(records) > in.config.QueueSize {
+		http.Error(w, fmt.Sprintf("request has more than the %d records allowed", in.config.QueueSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	in.ml.Lock()
+	if in.closed {
+		in.ml.Unlock()
+		http.Error(w, "ingest is closed", http.StatusServiceUnavailable)
+		return
+	}
+
+	if len(in.queue)+len(records) > in.config.QueueSize {
+		in.ml.Unlock()
+		w.Header().Set("Retry-After", strconv.Itoa(int(in.config.FlushDuration/time.Second)+1))
+		http.Error(w, "queue is full", http.StatusTooManyRequests)
+		return
+	}
+
+	now := time.Now()
+	for _, record := range records {
+		in.queue = append(in.queue, entry{received: now, record: record})
+	}
+	in.ml.Unlock()
+
+	in.wake()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"accepted": len(records)})
+}
+
+// authorized returns true if the request provides the secret, if any.
+func (in *Ingest) authorized(r *http.Request) bool {
+	if in.secret == "" {
+		return true
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(in.secret)) == 1
+}
+
+// wake notifies a waiting Pull of new records or closing.
+func (in *Ingest) wake() {
+	select {
+	case in.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Decode returns the records of the reader, which contains either a JSON array
+// of objects or newline delimited JSON objects, as read by jsonfiles.JSONReader.
+func Decode(r io.Reader) ([]map[string]interface{}, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, errors.New("request body is empty")
+	}
+
+	reader := jsonfiles.NewJSONReader(bytes.NewReader(body))
+
+	var records []map[string]interface{}
+	for {
+		batch, err := reader.Pull(context.Background(), decodeBatch)
+		if err != nil {
+			if err == dataset.ErrNoMore {
+				return records, nil
+			}
+			return nil, err
+		}
+
+		records = append(records, batch...)
+	}
+}
@@ -0,0 +1,191 @@
+package httpingest_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/pullers/httpingest"
+)
+
+func newIngest(config httpingest.Config) *httpingest.Ingest {
+	config.Addr = "127.0.0.1:0"
+
+	ingest, err := httpingest.New(config)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created ingest")
+	}
+	tests.Passed("Should have successfully created ingest")
+
+	if err := ingest.Start(); err != nil {
+		tests.FailedWithError(err, "Should have successfully started ingest server")
+	}
+	tests.Passed("Should have successfully started ingest server")
+
+	return ingest
+}
+
+func post(ingest *httpingest.Ingest, secret string, body string) int {
+	req, err := http.NewRequest("POST", "http://"+ingest.Addr()+"/events", strings.NewReader(body))
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created request")
+	}
+
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully made request")
+	}
+
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func TestIngest(t *testing.T) {
+	ingest := newIngest(httpingest.Config{
+		Path:          "/events",
+		Secret:        "secret",
+		QueueSize:     5,
+		FlushInterval: "100ms",
+	})
+	defer ingest.Close()
+
+	tests.Header("When posting records without the secret")
+	{
+		if status := post(ingest, "", `[{"user": "bob"}]`); status != http.StatusUnauthorized {
+			tests.Failed("Should have rejected request without secret but got %d", status)
+		}
+		tests.Passed("Should have rejected request without secret")
+	}
+
+	tests.Header("When posting a JSON array and NDJSON")
+	{
+		if status := post(ingest, "secret", `[{"user": "bob"}, {"user": "alex"}]`); status != http.StatusAccepted {
+			tests.Failed("Should have accepted JSON array but got %d", status)
+		}
+		tests.Passed("Should have accepted JSON array")
+
+		if status := post(ingest, "secret", "{\"user\": \"ray\"}\n{\"user\": \"kim\"}\n"); status != http.StatusAccepted {
+			tests.Failed("Should have accepted NDJSON but got %d", status)
+		}
+		tests.Passed("Should have accepted NDJSON")
+
+		if status := post(ingest, "secret", `{"user": `); status != http.StatusBadRequest {
+			tests.Failed("Should have rejected invalid JSON but got %d", status)
+		}
+		tests.Passed("Should have rejected invalid JSON")
+
+		if ingest.Pending() != 4 {
+			tests.Failed("Should have buffered 4 records but got %d", ingest.Pending())
+		}
+		tests.Passed("Should have buffered 4 records")
+	}
+
+	tests.Header("When the queue is full")
+	{
+		if status := post(ingest, "secret", `[{"user": "dan"}, {"user": "sam"}]`); status != http.StatusTooManyRequests {
+			tests.Failed("Should have rejected records over queue size but got %d", status)
+		}
+		tests.Passed("Should have rejected records over queue size")
+	}
+
+	tests.Header("When pulling records")
+	{
+		records, err := ingest.Pull(context.Background(), 3)
+		if err != nil || len(records) != 3 {
+			tests.Failed("Should have flushed full batch of 3 records but got %d", len(records))
+		}
+		tests.Passed("Should have flushed full batch of 3 records")
+
+		if records[0]["user"] != "bob" || records[2]["user"] != "ray" {
+			tests.Failed("Should have received records in order posted")
+		}
+		tests.Passed("Should have received records in order posted")
+
+		start := time.Now()
+		records, err = ingest.Pull(context.Background(), 3)
+		if err != nil || len(records) != 1 {
+			tests.Failed("Should have flushed remaining record after flush interval but got %d", len(records))
+		}
+		tests.Passed("Should have flushed remaining record after flush interval")
+
+		if time.Since(start) > time.Second {
+			tests.Failed("Should have flushed within the flush interval")
+		}
+		tests.Passed("Should have flushed within the flush interval")
+	}
+
+	tests.Header("When waiting for records")
+	{
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer cancel()
+
+		if _, err := ingest.Pull(ctx, 3); err != context.DeadlineExceeded {
+			tests.Failed("Should have stopped waiting once context is done")
+		}
+		tests.Passed("Should have stopped waiting once context is done")
+
+		go func() {
+			time.Sleep(time.Millisecond * 50)
+			post(ingest, "secret", `[{"user": "dan"}, {"user": "sam"}, {"user": "joe"}]`)
+		}()
+
+		records, err := ingest.Pull(context.Background(), 3)
+		if err != nil || len(records) != 3 {
+			tests.Failed("Should have received records posted while waiting but got %d", len(records))
+		}
+		tests.Passed("Should have received records posted while waiting")
+	}
+
+	tests.Header("When closed")
+	{
+		if status := post(ingest, "secret", `[{"user": "joe"}]`); status != http.StatusAccepted {
+			tests.Failed("Should have accepted records but got %d", status)
+		}
+		tests.Passed("Should have accepted records")
+
+		ingest.Close()
+
+		records, err := ingest.Pull(context.Background(), 10)
+		if err != nil || len(records) != 1 {
+			tests.Failed("Should have received buffered records after close")
+		}
+		tests.Passed("Should have received buffered records after close")
+
+		if _, err := ingest.Pull(context.Background(), 10); err != dataset.ErrNoMore {
+			tests.Failed("Should have received dataset.ErrNoMore once drained")
+		}
+		tests.Passed("Should have received dataset.ErrNoMore once drained")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	records, err := httpingest.Decode(strings.NewReader("  \n{\"user\": \"bob\"}\n\n{\"user\": \"alex\"}"))
+	if err != nil || len(records) != 2 {
+		tests.Failed("Should have decoded 2 NDJSON records but got %d", len(records))
+	}
+	tests.Passed("Should have decoded 2 NDJSON records")
+
+	records, err = httpingest.Decode(strings.NewReader("\n[{\"user\": \"bob\"}, {\"user\": \"alex\"}]"))
+	if err != nil || len(records) != 2 {
+		tests.Failed("Should have decoded 2 records of JSON array but got %d", len(records))
+	}
+	tests.Passed("Should have decoded 2 records of JSON array")
+
+	if _, err := httpingest.Decode(strings.NewReader("[{\"user\": \"bob\"}, 3]")); err == nil {
+		tests.Failed("Should have failed to decode JSON array with invalid record")
+	}
+	tests.Passed("Should have failed to decode JSON array with invalid record")
+
+	if _, err := httpingest.Decode(strings.NewReader("   ")); err == nil {
+		tests.Failed("Should have failed to decode empty body")
+	}
+	tests.Passed("Should have failed to decode empty body")
+}
@@ -0,0 +1,593 @@
+package join
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/influx6/geckodataset/dataset"
+)
+
+// DefaultMaxMemory indicates the default maximum records of the indexed
+// side held in memory before the index spills to disk.
+const DefaultMaxMemory = 100000
+
+// join types ...
+const (
+	// InnerJoin only returns records of the left side which match records
+	// of the right side.
+	InnerJoin = "inner"
+
+	// LeftJoin returns all records of the left side, merged with matching
+	// records of the right side if any.
+	LeftJoin = "left"
+
+	// FullJoin returns all records of both sides, merged where they match.
+	FullJoin = "full"
+)
+
+// Config embodies the configuration used by JoinPull to join the records
+// of two pullers.
+type Config struct {
+	// Type sets the join type: inner, left or full. Defaults to inner.
+	Type string
+
+	// LeftKeys and RightKeys set the fields of the records of each side which
+	// must have equal values for the records to be joined.
+	LeftKeys  []string
+	RightKeys []string
+
+	// MaxMemory sets the maximum records of the indexed side held in memory, after
+	// which records are spilled into a file in SpillDir, with only their keys and
+	// positions kept in memory. Defaults to DefaultMaxMemory.
+	MaxMemory int
+
+	// SpillDir sets the directory of the spill file, defaults to the
+	// temporary directory of the system.
+	SpillDir string
+}
+
+// Validate returns an error if the config is invalid.
+func (c *Config) Validate() error {
+	c.Type = strings.ToLower(c.Type)
+
+	switch c.Type {
+	case "":
+		c.Type = InnerJoin
+	case InnerJoin, LeftJoin, FullJoin:
+	default:
+		return fmt.Errorf("Config.Type can only be either 'inner', 'left' or 'full' not %q", c.Type)
+	}
+
+	if len(c.LeftKeys) == 0 {
+		return errors.New("Config.LeftKeys is required")
+	}
+
+	if len(c.LeftKeys) != len(c.RightKeys) {
+		return errors.New("Config.LeftKeys and Config.RightKeys must have the same number of fields")
+	}
+
+	if c.MaxMemory < 0 {
+		return errors.New("Config.MaxMemory can't be negative")
+	}
+
+	if c.MaxMemory == 0 {
+		c.MaxMemory = DefaultMaxMemory
+	}
+
+	return nil
+}
+
+// JoinPull implements the dataset.DataPull interface for joining the records of two
+// pullers on key fields. Batches of both sides are first pulled in turn till one side
+// has no more records, where all records of that smaller side are indexed, after which
+// records of the other side are streamed and merged with matching records of the index.
+// If both sides have more than MaxMemory records, the right side is indexed. Where both
+// records have the same field, the value of the left record is kept.
+type JoinPull struct {
+	config Config
+	left   dataset.DataPull
+	right  dataset.DataPull
+
+	ml         sync.Mutex
+	index      *index
+	indexLeft  bool
+	buffered   []map[string]interface{}
+	pending    []map[string]interface{}
+	streamDone bool
+	unmatched  int
+	done       bool
+}
+
+// New returns a new instance of JoinPull for the giving pullers.
+func New(left dataset.DataPull, right dataset.DataPull, config Config) (*JoinPull, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &JoinPull{
+		config: config,
+		left:   left,
+		right:  right,
+	}, nil
+}
+
+// Close removes the spill file of the index, if any.
+func (jp *JoinPull) Close() error {
+	jp.ml.Lock()
+	defer jp.ml.Unlock()
+
+	if jp.index == nil {
+		return nil
+	}
+
+	return jp.index.Close()
+}
+
+// Spilled returns true if the index was spilled to disk.
+func (jp *JoinPull) Spilled() bool {
+	jp.ml.Lock()
+	defer jp.ml.Unlock()
+	return jp.index != nil && jp.index.file != nil
+}
+
+// IndexedLeft returns true if the left side was found to be the smaller side,
+// and so was indexed instead of the right side.
+func (jp *JoinPull) IndexedLeft() bool {
+	jp.ml.Lock()
+	defer jp.ml.Unlock()
+	return jp.indexLeft
+}
+
+// Pull returns the next batch of joined records. Unmatched records of the index
+// returned by the join type are pulled in batches once the streamed side has no
+// more records.
+func (jp *JoinPull) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	jp.ml.Lock()
+	defer jp.ml.Unlock()
+
+	if batch == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	if jp.index == nil {
+		if err := jp.build(ctx, batch); err != nil {
+			return nil, err
+		}
+	}
+
+	for len(jp.pending) < batch && !jp.done {
+		if jp.streamDone && len(jp.buffered) == 0 {
+			if !jp.keepsUnmatchedIndexed() {
+				jp.done = true
+				break
+			}
+
+			unmatched, next, err := jp.index.Unmatched(jp.unmatched, batch-len(jp.pending))
+			if err != nil {
+				return nil, err
+			}
+
+			jp.pending = append(jp.pending, unmatched...)
+			jp.unmatched = next
+			jp.done = next == len(jp.index.keys)
+			continue
+		}
+
+		records, err := jp.stream(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			joined, err := jp.join(record)
+			if err != nil {
+				return nil, err
+			}
+
+			jp.pending = append(jp.pending, joined...)
+		}
+	}
+
+	if len(jp.pending) == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	if batch >= len(jp.pending) {
+		records := jp.pending
+		jp.pending = nil
+		return records, nil
+	}
+
+	records := jp.pending[:batch]
+	jp.pending = jp.pending[batch:]
+	return records, nil
+}
+
+// stream returns the next records of the streamed side, starting with the
+// records pulled while the index was built.
+func (jp *JoinPull) stream(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	if len(jp.buffered) != 0 {
+		if batch > len(jp.buffered) {
+			batch = len(jp.buffered)
+		}
+
+		records := jp.buffered[:batch]
+		jp.buffered = jp.buffered[batch:]
+		return records, nil
+	}
+
+	streamed := jp.left
+	if jp.indexLeft {
+		streamed = jp.right
+	}
+
+	records, done, err := pullSide(ctx, streamed, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	jp.streamDone = done
+	return records, nil
+}
+
+// keepsUnmatchedIndexed returns true if records of the index which never matched
+// are returned by the join type.
+func (jp *JoinPull) keepsUnmatchedIndexed() bool {
+	return jp.config.Type == FullJoin || (jp.indexLeft && jp.config.Type == LeftJoin)
+}
+
+// keepsUnmatchedStreamed returns true if records of the streamed side which do
+// not match are returned by the join type.
+func (jp *JoinPull) keepsUnmatchedStreamed() bool {
+	return jp.config.Type == FullJoin || (!jp.indexLeft && jp.config.Type == LeftJoin)
+}
+
+// join returns the records joined from the streamed record and it's matches.
+func (jp *JoinPull) join(record map[string]interface{}) ([]map[string]interface{}, error) {
+	keys := jp.config.LeftKeys
+	if jp.indexLeft {
+		keys = jp.config.RightKeys
+	}
+
+	var matches []map[string]interface{}
+	if key, ok := keyOf(record, keys); ok {
+		found, err := jp.index.Match(key)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = found
+	}
+
+	if len(matches) == 0 {
+		if !jp.keepsUnmatchedStreamed() {
+			return nil, nil
+		}
+
+		return []map[string]interface{}{record}, nil
+	}
+
+	joined := make([]map[string]interface{}, len(matches))
+	for index, match := range matches {
+		if jp.indexLeft {
+			joined[index] = merge(match, record)
+			continue
+		}
+
+		joined[index] = merge(record, match)
+	}
+
+	return joined, nil
+}
+
+// build pulls batches of both sides in turn, right side first, till one side has no
+// more records, indexing all records of that side, while records pulled from the other
+// side are buffered to be streamed first. Once both sides pulled more than MaxMemory
+// records, the rest of the right side is pulled into the index instead.
+func (jp *JoinPull) build(ctx context.Context, batch int) error {
+	var leftRecords, rightRecords []map[string]interface{}
+	var leftDone, rightDone bool
+
+	for len(leftRecords) <= jp.config.MaxMemory || len(rightRecords) <= jp.config.MaxMemory {
+		records, done, err := pullSide(ctx, jp.right, batch)
+		if err != nil {
+			return err
+		}
+
+		rightRecords = append(rightRecords, records...)
+		if rightDone = done; rightDone {
+			break
+		}
+
+		records, done, err = pullSide(ctx, jp.left, batch)
+		if err != nil {
+			return err
+		}
+
+		leftRecords = append(leftRecords, records...)
+		if leftDone = done; leftDone {
+			break
+		}
+	}
+
+	jp.indexLeft = leftDone
+	index := newIndex(jp.config.MaxMemory, jp.config.SpillDir)
+
+	indexed, keys, side := rightRecords, jp.config.RightKeys, jp.right
+	jp.buffered, jp.streamDone = leftRecords, leftDone
+	if jp.indexLeft {
+		indexed, keys, side = leftRecords, jp.config.LeftKeys, jp.left
+		jp.buffered, jp.streamDone = rightRecords, rightDone
+	}
+
+	done := leftDone || rightDone
+	for {
+		for _, record := range indexed {
+			key, ok := keyOf(record, keys)
+			if !ok {
+				// Records without keys never match, but are still
+				// returned if unmatched records of the index are.
+				if !jp.keepsUnmatchedIndexed() {
+					continue
+				}
+
+				key = noKey
+			}
+
+			if err := index.Add(key, record); err != nil {
+				index.Close()
+				return err
+			}
+		}
+
+		if done {
+			jp.index = index
+			return nil
+		}
+
+		records, sideDone, err := pullSide(ctx, side, batch)
+		if err != nil {
+			index.Close()
+			return err
+		}
+
+		indexed, done = records, sideDone
+	}
+}
+
+// pullSide returns the next records of the puller, returning true once the
+// puller has no more records.
+func pullSide(ctx context.Context, puller dataset.DataPull, batch int) ([]map[string]interface{}, bool, error) {
+	records, err := puller.Pull(ctx, batch)
+	if err != nil {
+		if err == dataset.ErrNoMore {
+			return nil, true, nil
+		}
+
+		return nil, false, err
+	}
+
+	return records, len(records) == 0, nil
+}
+
+// noKey indicates the key of records missing their key fields, which can't
+// collide with keys produced by keyOf.
+const noKey = ""
+
+// keyOf returns the key of the record's values for the giving fields, returning
+// false if any of the fields is missing or null.
+func keyOf(record map[string]interface{}, fields []string) (string, bool) {
+	values := make([]interface{}, len(fields))
+	for index, field := range fields {
+		value, ok := record[field]
+		if !ok || value == nil {
+			return "", false
+		}
+
+		values[index] = value
+	}
+
+	// Encoding values as json ensures equal numbers of different types,
+	// like int and float64, produce the same key.
+	key, err := json.Marshal(values)
+	if err != nil {
+		return "", false
+	}
+
+	return string(key), true
+}
+
+// merge returns a new record containing the fields of both records, where the
+// values of the left record are kept for fields found in both.
+func merge(left map[string]interface{}, right map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(left)+len(right))
+	for field, value := range right {
+		merged[field] = value
+	}
+
+	for field, value := range left {
+		merged[field] = value
+	}
+
+	return merged
+}
+
+// position embodies the location of a record within the spill file.
+type position struct {
+	offset int64
+	size   int
+}
+
+// index holds the records of a side by their keys, in memory till it exceeds
+// maxMemory records, after which all records are kept in a spill file.
+type index struct {
+	maxMemory int
+	spillDir  string
+	total     int
+	keys      []string
+	matched   map[string]bool
+	memory    map[string][]map[string]interface{}
+	file      *os.File
+	offset    int64
+	positions map[string][]position
+}
+
+func newIndex(maxMemory int, spillDir string) *index {
+	return &index{
+		maxMemory: maxMemory,
+		spillDir:  spillDir,
+		matched:   map[string]bool{},
+		memory:    map[string][]map[string]interface{}{},
+	}
+}
+
+// Add adds the record into the index under the giving key. Records are encoded
+// as json, keeping records held in memory of the same types as records read from
+// the spill file, such as float64 for all numbers.
+func (ix *index) Add(key string, record map[string]interface{}) error {
+	if _, ok := ix.matched[key]; !ok {
+		ix.matched[key] = false
+		ix.keys = append(ix.keys, key)
+	}
+
+	ix.total++
+
+	if ix.file == nil && ix.total > ix.maxMemory {
+		if err := ix.spill(); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if ix.file != nil {
+		return ix.write(key, data)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	ix.memory[key] = append(ix.memory[key], decoded)
+	return nil
+}
+
+// Match returns the records of the giving key, marking them as matched.
+func (ix *index) Match(key string) ([]map[string]interface{}, error) {
+	if _, ok := ix.matched[key]; !ok || key == noKey {
+		return nil, nil
+	}
+
+	ix.matched[key] = true
+	return ix.records(key)
+}
+
+// Unmatched returns the records of keys which were never matched, in the order
+// their keys were added, starting from the key at position from till atleast
+// limit records are found. It returns the position of the key to continue from,
+// which is the total keys once all keys have been seen.
+func (ix *index) Unmatched(from int, limit int) ([]map[string]interface{}, int, error) {
+	var unmatched []map[string]interface{}
+	for from < len(ix.keys) && len(unmatched) < limit {
+		key := ix.keys[from]
+		from++
+
+		if ix.matched[key] {
+			continue
+		}
+
+		records, err := ix.records(key)
+		if err != nil {
+			return nil, from, err
+		}
+
+		unmatched = append(unmatched, records...)
+	}
+
+	return unmatched, from, nil
+}
+
+// Close removes the spill file, if any.
+func (ix *index) Close() error {
+	if ix.file == nil {
+		return nil
+	}
+
+	ix.file.Close()
+	err := os.Remove(ix.file.Name())
+	ix.file = nil
+	return err
+}
+
+// records returns the records of the giving key.
+func (ix *index) records(key string) ([]map[string]interface{}, error) {
+	if ix.file == nil {
+		return ix.memory[key], nil
+	}
+
+	positions := ix.positions[key]
+	records := make([]map[string]interface{}, 0, len(positions))
+	for _, pos := range positions {
+		data := make([]byte, pos.size)
+		if _, err := ix.file.ReadAt(data, pos.offset); err != nil {
+			return nil, err
+		}
+
+		var record map[string]interface{}
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// spill moves all records held in memory into a new spill file.
+func (ix *index) spill() error {
+	file, err := ioutil.TempFile(ix.spillDir, "geckodataset-join-")
+	if err != nil {
+		return err
+	}
+
+	ix.file = file
+	ix.positions = map[string][]position{}
+
+	for _, key := range ix.keys {
+		for _, record := range ix.memory[key] {
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+
+			if err := ix.write(key, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	ix.memory = nil
+	return nil
+}
+
+// write appends the encoded record into the spill file under the giving key.
+func (ix *index) write(key string, data []byte) error {
+	if _, err := ix.file.WriteAt(data, ix.offset); err != nil {
+		return err
+	}
+
+	ix.positions[key] = append(ix.positions[key], position{offset: ix.offset, size: len(data)})
+	ix.offset += int64(len(data))
+	return nil
+}
@@ -0,0 +1,277 @@
+package join_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/pullers/join"
+)
+
+// source implements the dataset.DataPull interface over a slice of records.
+type source struct {
+	records []map[string]interface{}
+}
+
+func (s *source) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	if len(s.records) == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	if batch > len(s.records) {
+		batch = len(s.records)
+	}
+
+	next := s.records[:batch]
+	s.records = s.records[batch:]
+	return next, nil
+}
+
+func sales() *source {
+	return &source{records: []map[string]interface{}{
+		{"user_id": 1, "amount": 10},
+		{"user_id": 2, "amount": 20},
+		{"user_id": 1, "amount": 30},
+		{"user_id": 4, "amount": 40},
+		{"amount": 50},
+	}}
+}
+
+func users() *source {
+	return &source{records: []map[string]interface{}{
+		{"id": float64(1), "name": "bob", "amount": 0},
+		{"id": float64(2), "name": "alex"},
+		{"id": float64(3), "name": "ray", "visits": 7},
+	}}
+}
+
+func joinAll(config join.Config) ([]map[string]interface{}, *join.JoinPull) {
+	config.LeftKeys = []string{"user_id"}
+	config.RightKeys = []string{"id"}
+
+	return joinSources(sales(), users(), config)
+}
+
+func joinSources(left dataset.DataPull, right dataset.DataPull, config join.Config) ([]map[string]interface{}, *join.JoinPull) {
+	puller, err := join.New(left, right, config)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created join")
+	}
+	tests.Passed("Should have successfully created join")
+
+	var records []map[string]interface{}
+	for {
+		recs, err := puller.Pull(context.Background(), 2)
+		if err != nil {
+			if err == dataset.ErrNoMore {
+				break
+			}
+			tests.FailedWithError(err, "Should have successfully pulled joined records")
+		}
+
+		if len(recs) > 2 {
+			tests.Failed("Should have received at most 2 records but got %d", len(recs))
+		}
+
+		records = append(records, recs...)
+	}
+
+	return records, puller
+}
+
+func TestJoinPull(t *testing.T) {
+	tests.Header("When using an inner join")
+	{
+		records, puller := joinAll(join.Config{Type: join.InnerJoin})
+		defer puller.Close()
+
+		if len(records) != 3 {
+			tests.Failed("Should have received 3 joined records but got %d", len(records))
+		}
+		tests.Passed("Should have received 3 joined records")
+
+		if records[0]["name"] != "bob" || records[1]["name"] != "alex" || records[2]["name"] != "bob" {
+			tests.Failed("Should have merged users into sales in order")
+		}
+		tests.Passed("Should have merged users into sales in order")
+
+		if records[0]["amount"] != 10 {
+			tests.Failed("Should have kept value of left record for fields of both")
+		}
+		tests.Passed("Should have kept value of left record for fields of both")
+	}
+
+	tests.Header("When using a left join")
+	{
+		records, puller := joinAll(join.Config{Type: join.LeftJoin})
+		defer puller.Close()
+
+		if len(records) != 5 {
+			tests.Failed("Should have received all 5 sales but got %d", len(records))
+		}
+		tests.Passed("Should have received all 5 sales")
+
+		if _, ok := records[3]["name"]; ok {
+			tests.Failed("Should have kept unmatched sale without user fields")
+		}
+		tests.Passed("Should have kept unmatched sale without user fields")
+	}
+
+	tests.Header("When using a full join")
+	{
+		records, puller := joinAll(join.Config{Type: join.FullJoin})
+		defer puller.Close()
+
+		if len(records) != 6 {
+			tests.Failed("Should have received 5 sales and 1 unmatched user but got %d", len(records))
+		}
+		tests.Passed("Should have received 5 sales and 1 unmatched user")
+
+		if records[5]["name"] != "ray" {
+			tests.Failed("Should have received unmatched user last")
+		}
+		tests.Passed("Should have received unmatched user last")
+
+		if records[5]["visits"] != float64(7) {
+			tests.Failed("Should have received numbers of indexed records as float64 but got %T", records[5]["visits"])
+		}
+		tests.Passed("Should have received numbers of indexed records as float64")
+	}
+}
+
+func TestJoinPullWithSmallerLeft(t *testing.T) {
+	joinUsers := func(config join.Config) ([]map[string]interface{}, *join.JoinPull) {
+		config.LeftKeys = []string{"id"}
+		config.RightKeys = []string{"user_id"}
+		return joinSources(users(), sales(), config)
+	}
+
+	tests.Header("When using an inner join")
+	{
+		records, puller := joinUsers(join.Config{Type: join.InnerJoin})
+		defer puller.Close()
+
+		if !puller.IndexedLeft() {
+			tests.Failed("Should have indexed smaller left side")
+		}
+		tests.Passed("Should have indexed smaller left side")
+
+		if len(records) != 3 {
+			tests.Failed("Should have received 3 joined records but got %d", len(records))
+		}
+		tests.Passed("Should have received 3 joined records")
+
+		if records[0]["name"] != "bob" || records[0]["amount"] != float64(0) {
+			tests.Failed("Should have kept value of left record for fields of both: %#v", records[0])
+		}
+		tests.Passed("Should have kept value of left record for fields of both")
+	}
+
+	tests.Header("When using a left join")
+	{
+		records, puller := joinUsers(join.Config{Type: join.LeftJoin})
+		defer puller.Close()
+
+		if len(records) != 4 {
+			tests.Failed("Should have received 3 joined records and 1 unmatched user but got %d", len(records))
+		}
+		tests.Passed("Should have received 3 joined records and 1 unmatched user")
+
+		if records[3]["name"] != "ray" {
+			tests.Failed("Should have received unmatched user last")
+		}
+		tests.Passed("Should have received unmatched user last")
+	}
+
+	tests.Header("When using a full join")
+	{
+		records, puller := joinUsers(join.Config{Type: join.FullJoin})
+		defer puller.Close()
+
+		if len(records) != 6 {
+			tests.Failed("Should have received 3 joined records, 2 unmatched sales and 1 unmatched user but got %d", len(records))
+		}
+		tests.Passed("Should have received 3 joined records, 2 unmatched sales and 1 unmatched user")
+	}
+}
+
+func TestJoinPullWithUnmatchedBatches(t *testing.T) {
+	unknown := &source{records: []map[string]interface{}{
+		{"id": 5, "name": "kim"},
+		{"id": 6, "name": "lee"},
+		{"id": 7, "name": "max"},
+		{"id": 8, "name": "ned"},
+	}}
+
+	records, puller := joinSources(sales(), unknown, join.Config{
+		Type:      join.FullJoin,
+		LeftKeys:  []string{"user_id"},
+		RightKeys: []string{"id"},
+	})
+	defer puller.Close()
+
+	if puller.IndexedLeft() {
+		tests.Failed("Should have indexed smaller right side")
+	}
+	tests.Passed("Should have indexed smaller right side")
+
+	if len(records) != 9 {
+		tests.Failed("Should have received 5 sales and 4 unmatched users in batches but got %d", len(records))
+	}
+	tests.Passed("Should have received 5 sales and 4 unmatched users in batches")
+}
+
+func TestJoinPullWithSpill(t *testing.T) {
+	spillDir, err := ioutil.TempDir("", "join-spill")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created spill directory")
+	}
+	defer os.RemoveAll(spillDir)
+
+	records, puller := joinAll(join.Config{Type: join.FullJoin, MaxMemory: 1, SpillDir: spillDir})
+
+	if !puller.Spilled() {
+		tests.Failed("Should have spilled index to disk")
+	}
+	tests.Passed("Should have spilled index to disk")
+
+	if len(records) != 6 {
+		tests.Failed("Should have received 6 records but got %d", len(records))
+	}
+	tests.Passed("Should have received 6 records")
+
+	if records[0]["name"] != "bob" || records[5]["name"] != "ray" {
+		tests.Failed("Should have joined records from spilled index")
+	}
+	tests.Passed("Should have joined records from spilled index")
+
+	if records[5]["visits"] != float64(7) {
+		tests.Failed("Should have received numbers of spilled records as float64 but got %T", records[5]["visits"])
+	}
+	tests.Passed("Should have received numbers of spilled records as float64")
+
+	puller.Close()
+
+	files, _ := ioutil.ReadDir(spillDir)
+	if len(files) != 0 {
+		tests.Failed("Should have removed spill file on close")
+	}
+	tests.Passed("Should have removed spill file on close")
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := join.Config{Type: "outer", LeftKeys: []string{"id"}, RightKeys: []string{"id"}}
+	if err := config.Validate(); err == nil {
+		tests.Failed("Should have failed with unknown join type")
+	}
+	tests.Passed("Should have failed with unknown join type")
+
+	config = join.Config{LeftKeys: []string{"id", "day"}, RightKeys: []string{"id"}}
+	if err := config.Validate(); err == nil {
+		tests.Failed("Should have failed with mismatched keys")
+	}
+	tests.Passed("Should have failed with mismatched keys")
+}
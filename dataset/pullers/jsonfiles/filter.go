@@ -0,0 +1,156 @@
+package jsonfiles
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar"
+)
+
+// SortOrder defines the order in which files are processed.
+type SortOrder string
+
+// sort orders ...
+const (
+	// SortByName orders files lexically by their path relative to the directory.
+	SortByName SortOrder = "name"
+
+	// SortByModTime orders files by their modification time, oldest first.
+	SortByModTime SortOrder = "mtime"
+
+	// SortByNatural orders files by their path relative to the directory, where
+	// numeric parts are compared by value, such that "2026/9" comes before "2026/10".
+	SortByNatural SortOrder = "natural"
+)
+
+// Filter embodies the include and exclude glob patterns and sort order
+// used to select files from a directory. Patterns are matched against
+// the slash separated path of files relative to the directory and support
+// `**` to match any number of directories.
+type Filter struct {
+	Include []string
+	Exclude []string
+	Order   SortOrder
+}
+
+// Validate returns an error if any of the patterns or the sort order is invalid.
+func (f Filter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := doublestar.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern %q: %+s", pattern, err.Error())
+		}
+	}
+
+	switch f.Order {
+	case "", SortByName, SortByModTime, SortByNatural:
+		return nil
+	default:
+		return fmt.Errorf("sort order can only be either 'name', 'mtime' or 'natural' not %q", f.Order)
+	}
+}
+
+// Match returns true if the path relative to the directory is included and
+// not excluded. If no include patterns are set, only .json files are included.
+func (f Filter) Match(rel string) bool {
+	rel = filepath.ToSlash(rel)
+
+	included := len(f.Include) == 0 && filepath.Ext(rel) == ".json"
+	for _, pattern := range f.Include {
+		if ok, _ := doublestar.Match(pattern, rel); ok {
+			included = true
+			break
+		}
+	}
+
+	if !included {
+		return false
+	}
+
+	for _, pattern := range f.Exclude {
+		if ok, _ := doublestar.Match(pattern, rel); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fileInfo pairs a file's path relative to the directory with it's info.
+type fileInfo struct {
+	rel  string
+	info os.FileInfo
+}
+
+// sortFiles sorts the giving files according to provided order.
+func sortFiles(files []fileInfo, order SortOrder) {
+	switch order {
+	case SortByModTime:
+		sort.SliceStable(files, func(i, j int) bool {
+			if files[i].info.ModTime().Equal(files[j].info.ModTime()) {
+				return files[i].rel < files[j].rel
+			}
+			return files[i].info.ModTime().Before(files[j].info.ModTime())
+		})
+	case SortByNatural:
+		sort.SliceStable(files, func(i, j int) bool {
+			return naturalLess(filepath.ToSlash(files[i].rel), filepath.ToSlash(files[j].rel))
+		})
+	default:
+		sort.SliceStable(files, func(i, j int) bool {
+			return filepath.ToSlash(files[i].rel) < filepath.ToSlash(files[j].rel)
+		})
+	}
+}
+
+// naturalLess returns true if a is ordered before b, comparing runs of digits
+// by their numeric value.
+func naturalLess(a string, b string) bool {
+	for a != "" && b != "" {
+		var chunkA, chunkB string
+		chunkA, a = nextChunk(a)
+		chunkB, b = nextChunk(b)
+
+		if chunkA == chunkB {
+			continue
+		}
+
+		if isDigit(chunkA[0]) && isDigit(chunkB[0]) {
+			numA := strings.TrimLeft(chunkA, "0")
+			numB := strings.TrimLeft(chunkB, "0")
+			if len(numA) != len(numB) {
+				return len(numA) < len(numB)
+			}
+
+			if numA != numB {
+				return numA < numB
+			}
+
+			// same values with different leading zeros.
+			return len(chunkA) < len(chunkB)
+		}
+
+		return chunkA < chunkB
+	}
+
+	return len(a) < len(b)
+}
+
+// nextChunk returns the next run of either digits or non-digits in
+// the giving string and the remaining string.
+func nextChunk(s string) (string, string) {
+	digits := isDigit(s[0])
+
+	index := 1
+	for index < len(s) && isDigit(s[index]) == digits {
+		index++
+	}
+
+	return s[:index], s[index:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -1,8 +1,10 @@
 package jsonfiles
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 
 	"os"
@@ -90,19 +92,87 @@ func (jns *JSONStream) Pull(ctx context.Context, batch int) ([]map[string]interf
 	return next, nil
 }
 
+// ErrorPolicy defines the action to be taken by JSONStreams when a file
+// fails to be loaded.
+type ErrorPolicy string
+
+// error policies ...
+const (
+	// FailOnError stops all processing, returning the file's error.
+	FailOnError ErrorPolicy = "fail"
+
+	// SkipOnError skips the failed file and moves on to the next file.
+	SkipOnError ErrorPolicy = "skip"
+
+	// QuarantineOnError moves the failed file into the quarantine directory
+	// and moves on to the next file.
+	QuarantineOnError ErrorPolicy = "quarantine"
+)
+
+// FileReport contains the outcome of the processing of a giving file.
+type FileReport struct {
+	File    string `json:"file"`
+	Records int    `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Report contains the summary of all files processed by a JSONStreams.
+type Report struct {
+	Succeeded []FileReport `json:"succeeded"`
+	Failed    []FileReport `json:"failed"`
+}
+
+// String returns a readable summary of the report.
+func (r Report) String() string {
+	var summary bytes.Buffer
+	fmt.Fprintf(&summary, "%d file(s) succeeded, %d file(s) failed\n", len(r.Succeeded), len(r.Failed))
+
+	for _, file := range r.Succeeded {
+		fmt.Fprintf(&summary, "\tok\t%s (%d records)\n", file.File, file.Records)
+	}
+
+	for _, file := range r.Failed {
+		fmt.Fprintf(&summary, "\tfailed\t%s: %s\n", file.File, file.Error)
+	}
+
+	return summary.String()
+}
+
 // JSONStreams embodies the collection of json files loaded from provided directory.
 // It creates JSONStream objects which lazy load required data of files during their initial
 // call. To pull data.
 type JSONStreams struct {
+	// OnFileError sets the policy used when a file fails to be loaded.
+	// Defaults to FailOnError.
+	OnFileError ErrorPolicy
+
+	// QuarantineDir sets the directory failed files are moved into when
+	// OnFileError is QuarantineOnError.
+	QuarantineDir string
+
+	dir     string
 	streams []JSONStream
 	ml      sync.Mutex
 	current *JSONStream
+	pulled  int
+	report  Report
 }
 
 // New returns a new instance of JSONStreams. JSONStreams only generates a file lists of
 // files within root if deep is false, else runs into all files with .json prefix.
 func New(dir string, deep bool) (*JSONStreams, error) {
-	var streams JSONStreams
+	return NewWithFilter(dir, deep, Filter{})
+}
+
+// NewWithFilter returns a new instance of JSONStreams, where files within root, or all
+// sub-directories if deep is true, are selected using the provided Filter and ordered
+// by it's sort order, which defaults to ordering by name.
+func NewWithFilter(dir string, deep bool, filter Filter) (*JSONStreams, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	var files []fileInfo
 
 	if deep {
 		if err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -114,16 +184,16 @@ func New(dir string, deep bool) (*JSONStreams, error) {
 				return nil
 			}
 
-			if filepath.Ext(path) != ".json" {
-				return nil
-			}
-
-			stream, err := NewJSONStream(path)
+			rel, err := filepath.Rel(dir, path)
 			if err != nil {
 				return err
 			}
 
-			streams.streams = append(streams.streams, stream)
+			if !filter.Match(rel) {
+				return nil
+			}
+
+			files = append(files, fileInfo{rel: rel, info: info})
 			return nil
 		}); err != nil {
 			return nil, err
@@ -146,17 +216,24 @@ func New(dir string, deep bool) (*JSONStreams, error) {
 				continue
 			}
 
-			if filepath.Ext(item.Name()) != ".json" {
+			if !filter.Match(item.Name()) {
 				continue
 			}
 
-			stream, err := NewJSONStream(filepath.Join(dir, item.Name()))
-			if err != nil {
-				return &streams, err
-			}
+			files = append(files, fileInfo{rel: item.Name(), info: item})
+		}
+	}
 
-			streams.streams = append(streams.streams, stream)
+	sortFiles(files, filter.Order)
+
+	streams := JSONStreams{dir: dir}
+	for _, file := range files {
+		stream, err := NewJSONStream(filepath.Join(dir, file.rel))
+		if err != nil {
+			return &streams, err
 		}
+
+		streams.streams = append(streams.streams, stream)
 	}
 
 	return &streams, nil
@@ -167,10 +244,18 @@ func (jns *JSONStreams) Total() int {
 	return len(jns.streams)
 }
 
+// Report returns the summary of files processed so far.
+func (jns *JSONStreams) Report() Report {
+	jns.ml.Lock()
+	defer jns.ml.Unlock()
+	return jns.report
+}
+
 // Pull attempts to load current streams data with batch parameters if found else, walks through
 // directory which it loads all fileInfo items, it scans in attempt to load next which if is a valid
 // json file and with respect to it's strict flag, will load the content and use this data has
 // a means of loading continuous json feed of record values for processing.
+// Files which fail to load are handled according to the OnFileError policy.
 func (jns *JSONStreams) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
 	jns.ml.Lock()
 	defer jns.ml.Unlock()
@@ -179,41 +264,96 @@ func (jns *JSONStreams) Pull(ctx context.Context, batch int) ([]map[string]inter
 		return nil, dataset.ErrNoMore
 	}
 
-	if jns.current != nil {
-		recs, err := jns.current.Pull(ctx, batch)
-		if err != nil {
-			if err != dataset.ErrNoMore {
-				return nil, err
-			}
-
-			jns.current = nil
+	for {
+		if jns.current == nil {
 			if len(jns.streams) == 0 {
 				return nil, dataset.ErrNoMore
 			}
 
-			goto NextStream
+			next := jns.streams[0]
+			jns.streams = jns.streams[1:]
+			jns.current = &next
+			jns.pulled = 0
 		}
 
-		return recs, nil
-	}
+		recs, err := jns.current.Pull(ctx, batch)
+		if err == nil {
+			jns.pulled += len(recs)
+			return recs, nil
+		}
 
-NextStream:
-	if len(jns.streams) == 0 {
-		return nil, dataset.ErrNoMore
-	}
+		if err == dataset.ErrNoMore {
+			jns.report.Succeeded = append(jns.report.Succeeded, FileReport{
+				File:    jns.current.targetFile,
+				Records: jns.pulled,
+			})
 
-	next := jns.streams[0]
-	jns.streams = jns.streams[1:]
-	jns.current = &next
+			jns.current = nil
+			continue
+		}
 
-	recs, err := jns.current.Pull(ctx, batch)
-	if err != nil {
-		if err != dataset.ErrNoMore {
+		if err := jns.fail(err); err != nil {
 			return nil, err
 		}
+	}
+}
+
+// Failed records the failure of the file currently being processed and applies the
+// OnFileError policy, returning an error if processing should stop. It should be called
+// when processing of the last pulled records failed.
+func (jns *JSONStreams) Failed(err error) error {
+	jns.ml.Lock()
+	defer jns.ml.Unlock()
 
-		goto NextStream
+	if jns.current == nil {
+		return err
+	}
+
+	return jns.fail(err)
+}
+
+// Abort records the failure of the file currently being processed without applying
+// the OnFileError policy. It should be called when the last pulled records failed
+// to be pushed, which is no fault of the file, before processing is stopped.
+func (jns *JSONStreams) Abort(err error) {
+	jns.ml.Lock()
+	defer jns.ml.Unlock()
+
+	if jns.current == nil {
+		return
 	}
 
-	return recs, nil
+	jns.abort(err)
+}
+
+// abort records the failure of the current file, returning it's path.
+func (jns *JSONStreams) abort(err error) string {
+	file := jns.current.targetFile
+	jns.current = nil
+
+	jns.report.Failed = append(jns.report.Failed, FileReport{
+		File:    file,
+		Records: jns.pulled,
+		Error:   err.Error(),
+	})
+
+	return file
+}
+
+// fail records the failure of the current file and applies the OnFileError policy.
+func (jns *JSONStreams) fail(err error) error {
+	file := jns.abort(err)
+
+	switch jns.OnFileError {
+	case SkipOnError:
+		return nil
+	case QuarantineOnError:
+		if jns.QuarantineDir == "" {
+			return errors.New("JSONStreams.QuarantineDir is required for quarantine")
+		}
+
+		return moveInto(file, jns.dir, jns.QuarantineDir)
+	default:
+		return fmt.Errorf("%s: %+s", file, err.Error())
+	}
 }
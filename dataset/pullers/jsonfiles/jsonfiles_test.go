@@ -2,6 +2,10 @@ package jsonfiles_test
 
 import (
 	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/influx6/faux/tests"
@@ -159,3 +163,276 @@ func runStreams(t *testing.T, batch int, after func([]map[string]interface{})) {
 		after(recs)
 	}
 }
+
+func TestJSONStreamsWithFailPolicy(t *testing.T) {
+	jssm, err := jsonfiles.New("./fixtures/mixed", false)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully loaded json files")
+	}
+	tests.Passed("Should have successfully loaded json files")
+
+	var failed error
+	for {
+		if _, err := jssm.Pull(context.Background(), 2); err != nil {
+			if err != dataset.ErrNoMore {
+				failed = err
+			}
+			break
+		}
+	}
+
+	if failed == nil {
+		tests.Failed("Should have stopped processing with bad file error")
+	}
+	tests.PassedWithError(failed, "Should have stopped processing with bad file error")
+}
+
+func TestJSONStreamsWithSkipPolicy(t *testing.T) {
+	jssm, err := jsonfiles.New("./fixtures/mixed", false)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully loaded json files")
+	}
+	tests.Passed("Should have successfully loaded json files")
+
+	jssm.OnFileError = jsonfiles.SkipOnError
+
+	var total int
+	for {
+		recs, err := jssm.Pull(context.Background(), 2)
+		if err != nil {
+			if err == dataset.ErrNoMore {
+				break
+			}
+
+			tests.FailedWithError(err, "Should have successfully skipped bad file")
+		}
+
+		total += len(recs)
+	}
+	tests.Passed("Should have successfully skipped bad file")
+
+	if total != 3 {
+		tests.Failed("Should have received 3 records but got %d", total)
+	}
+	tests.Passed("Should have received 3 records")
+
+	report := jssm.Report()
+	if len(report.Succeeded) != 1 || len(report.Failed) != 1 {
+		tests.Failed("Should have reported 1 succeeded and 1 failed file: %s", report)
+	}
+	tests.Passed("Should have reported 1 succeeded and 1 failed file")
+
+	if report.Succeeded[0].Records != 3 {
+		tests.Failed("Should have reported 3 records for succeeded file")
+	}
+	tests.Passed("Should have reported 3 records for succeeded file")
+
+	if report.Failed[0].Error == "" {
+		tests.Failed("Should have reported error for failed file")
+	}
+	tests.Passed("Should have reported error for failed file")
+}
+
+func TestJSONStreamsWithQuarantinePolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonquarantine")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created temporary directory")
+	}
+	tests.Passed("Should have successfully created temporary directory")
+
+	defer os.RemoveAll(dir)
+
+	sourceDir := filepath.Join(dir, "source")
+	quarantineDir := filepath.Join(dir, "quarantine")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		tests.FailedWithError(err, "Should have successfully created source directory")
+	}
+	tests.Passed("Should have successfully created source directory")
+
+	if err := ioutil.WriteFile(filepath.Join(sourceDir, "bad.json"), []byte(`{"name":"Josh"}`), 0644); err != nil {
+		tests.FailedWithError(err, "Should have successfully written bad file")
+	}
+	tests.Passed("Should have successfully written bad file")
+
+	jssm, err := jsonfiles.New(sourceDir, false)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully loaded json files")
+	}
+	tests.Passed("Should have successfully loaded json files")
+
+	jssm.OnFileError = jsonfiles.QuarantineOnError
+	jssm.QuarantineDir = quarantineDir
+
+	if _, err := jssm.Pull(context.Background(), 2); err != dataset.ErrNoMore {
+		tests.Failed("Should have received dataset.ErrNoMore after quarantine of bad file")
+	}
+	tests.Passed("Should have received dataset.ErrNoMore after quarantine of bad file")
+
+	if _, err := os.Stat(filepath.Join(quarantineDir, "bad.json")); err != nil {
+		tests.FailedWithError(err, "Should have moved bad file into quarantine directory")
+	}
+	tests.Passed("Should have moved bad file into quarantine directory")
+}
+
+func TestJSONStreamsWithDeepQuarantinePolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonquarantine")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created temporary directory")
+	}
+	tests.Passed("Should have successfully created temporary directory")
+
+	defer os.RemoveAll(dir)
+
+	sourceDir := filepath.Join(dir, "source")
+	quarantineDir := filepath.Join(dir, "quarantine")
+	for _, sub := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join(sourceDir, sub), 0755); err != nil {
+			tests.FailedWithError(err, "Should have successfully created source directory")
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(sourceDir, sub, "bad.json"), []byte(`{"name":"Josh"}`), 0644); err != nil {
+			tests.FailedWithError(err, "Should have successfully written bad file")
+		}
+	}
+	tests.Passed("Should have successfully written bad files of the same name")
+
+	jssm, err := jsonfiles.New(sourceDir, true)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully loaded json files")
+	}
+	tests.Passed("Should have successfully loaded json files")
+
+	jssm.OnFileError = jsonfiles.QuarantineOnError
+	jssm.QuarantineDir = quarantineDir
+
+	if _, err := jssm.Pull(context.Background(), 2); err != dataset.ErrNoMore {
+		tests.Failed("Should have received dataset.ErrNoMore after quarantine of bad files")
+	}
+	tests.Passed("Should have received dataset.ErrNoMore after quarantine of bad files")
+
+	for _, sub := range []string{"a", "b"} {
+		if _, err := os.Stat(filepath.Join(quarantineDir, sub, "bad.json")); err != nil {
+			tests.FailedWithError(err, "Should have moved bad file into it's sub-directory of quarantine directory")
+		}
+	}
+	tests.Passed("Should have moved bad files into their sub-directories of quarantine directory")
+}
+
+func TestJSONStreamsFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonfailed")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created temporary directory")
+	}
+	tests.Passed("Should have successfully created temporary directory")
+
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a.json", "b.json", "c.json"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(`[{"name":"Josh"},{"name":"Alex"}]`), 0644); err != nil {
+			tests.FailedWithError(err, "Should have successfully written json file")
+		}
+	}
+	tests.Passed("Should have successfully written json files")
+
+	jssm, err := jsonfiles.New(dir, false)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully loaded json files")
+	}
+	tests.Passed("Should have successfully loaded json files")
+
+	jssm.OnFileError = jsonfiles.SkipOnError
+
+	tests.Header("When processing of pulled records fails")
+	{
+		if _, err := jssm.Pull(context.Background(), 1); err != nil {
+			tests.FailedWithError(err, "Should have successfully pulled records")
+		}
+
+		if err := jssm.Failed(errors.New("transform failed")); err != nil {
+			tests.FailedWithError(err, "Should have skipped file whose records failed to be transformed")
+		}
+		tests.Passed("Should have skipped file whose records failed to be transformed")
+
+		if _, err := jssm.Pull(context.Background(), 2); err != nil {
+			tests.FailedWithError(err, "Should have successfully pulled records")
+		}
+
+		jssm.Abort(errors.New("push failed"))
+
+		report := jssm.Report()
+		if len(report.Succeeded) != 0 || len(report.Failed) != 2 {
+			tests.Failed("Should have reported 2 failed files: %s", report)
+		}
+		tests.Passed("Should have reported 2 failed files")
+
+		if report.Failed[0].Error != "transform failed" || report.Failed[1].Error != "push failed" {
+			tests.Failed("Should have reported errors of failed files: %s", report)
+		}
+		tests.Passed("Should have reported errors of failed files")
+
+		if _, err := os.Stat(filepath.Join(dir, "b.json")); err != nil {
+			tests.FailedWithError(err, "Should have left file which failed to be pushed in place")
+		}
+		tests.Passed("Should have left file which failed to be pushed in place")
+	}
+}
+
+func TestJSONStreamsWithFilter(t *testing.T) {
+	tests.Header("When including date-partitioned files in natural order")
+	{
+		jssm, err := jsonfiles.NewWithFilter("./fixtures/partitioned", true, jsonfiles.Filter{
+			Include: []string{"**/*.json"},
+			Exclude: []string{"**/draft.json"},
+			Order:   jsonfiles.SortByNatural,
+		})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully loaded json files")
+		}
+		tests.Passed("Should have successfully loaded json files")
+
+		if jssm.Total() != 3 {
+			tests.Failed("Should have loaded 3 files but got %d", jssm.Total())
+		}
+		tests.Passed("Should have loaded 3 files")
+
+		expected := []string{"2025-12", "2026-09", "2026-10"}
+		for _, month := range expected {
+			recs, err := jssm.Pull(context.Background(), 1)
+			if err != nil {
+				tests.FailedWithError(err, "Should have successfully pulled record")
+			}
+
+			if len(recs) != 1 || recs[0]["month"] != month {
+				tests.Failed("Should have received record for %q but got %#v", month, recs)
+			}
+			tests.Passed("Should have received record for %q", month)
+		}
+	}
+
+	tests.Header("When including only files of a giving partition")
+	{
+		jssm, err := jsonfiles.NewWithFilter("./fixtures/partitioned", true, jsonfiles.Filter{
+			Include: []string{"2026/10/*.json"},
+		})
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully loaded json files")
+		}
+		tests.Passed("Should have successfully loaded json files")
+
+		if jssm.Total() != 2 {
+			tests.Failed("Should have loaded 2 files but got %d", jssm.Total())
+		}
+		tests.Passed("Should have loaded 2 files")
+	}
+
+	tests.Header("When using an unknown sort order")
+	{
+		_, err := jsonfiles.NewWithFilter("./fixtures/partitioned", true, jsonfiles.Filter{
+			Order: "size",
+		})
+		if err == nil {
+			tests.Failed("Should have failed with unknown sort order")
+		}
+		tests.PassedWithError(err, "Should have failed with unknown sort order")
+	}
+}
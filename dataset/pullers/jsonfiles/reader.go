@@ -0,0 +1,170 @@
+package jsonfiles
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/influx6/geckodataset/dataset"
+)
+
+// StdinSource indicates the source path which refers to standard input.
+const StdinSource = "-"
+
+// JSONReader implements the dataset.DataPull interface for streaming records from
+// a reader containing either a JSON array of objects or newline delimited JSON
+// objects, such as standard input or a named pipe, where records are decoded as
+// they are pulled instead of being loaded into memory.
+type JSONReader struct {
+	ml      sync.Mutex
+	reader  *bufio.Reader
+	closer  io.Closer
+	decoder *json.Decoder
+	started bool
+	array   bool
+	done    bool
+	total   int
+}
+
+// NewJSONReader returns a new instance of JSONReader for the giving reader.
+func NewJSONReader(r io.Reader) *JSONReader {
+	return &JSONReader{reader: bufio.NewReader(r)}
+}
+
+// OpenJSONReader returns a new instance of JSONReader for the giving source, which is
+// either StdinSource for standard input, or the path of a file or named pipe.
+func OpenJSONReader(source string) (*JSONReader, error) {
+	if source == StdinSource {
+		return NewJSONReader(os.Stdin), nil
+	}
+
+	stat, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if stat.IsDir() {
+		return nil, errors.New("only files or named pipes allowed")
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := NewJSONReader(file)
+	reader.closer = file
+	return reader, nil
+}
+
+// Close closes the underline file of the JSONReader, if opened by it.
+func (jr *JSONReader) Close() error {
+	jr.ml.Lock()
+	defer jr.ml.Unlock()
+
+	if jr.closer == nil {
+		return nil
+	}
+
+	err := jr.closer.Close()
+	jr.closer = nil
+	return err
+}
+
+// Pull returns the next batch of records decoded from the reader.
+func (jr *JSONReader) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	jr.ml.Lock()
+	defer jr.ml.Unlock()
+
+	if batch == 0 || jr.done {
+		return nil, dataset.ErrNoMore
+	}
+
+	if !jr.started {
+		if err := jr.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	var records []map[string]interface{}
+	for len(records) < batch && !jr.done {
+		record, err := jr.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if record != nil {
+			records = append(records, record)
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	return records, nil
+}
+
+// start detects if the reader contains a JSON array or newline delimited JSON.
+func (jr *JSONReader) start() error {
+	jr.started = true
+	jr.decoder = json.NewDecoder(jr.reader)
+
+	for {
+		next, err := jr.reader.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				jr.done = true
+				return nil
+			}
+			return err
+		}
+
+		switch next {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+
+		if err := jr.reader.UnreadByte(); err != nil {
+			return err
+		}
+
+		if next == '[' {
+			jr.array = true
+			if _, err := jr.decoder.Token(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// next returns the next record of the reader, marking the reader as done
+// once all records are read.
+func (jr *JSONReader) next() (map[string]interface{}, error) {
+	if jr.array && !jr.decoder.More() {
+		jr.done = true
+		if _, err := jr.decoder.Token(); err != nil {
+			return nil, fmt.Errorf("invalid end of JSON array: %+s", err.Error())
+		}
+		return nil, nil
+	}
+
+	var record map[string]interface{}
+	if err := jr.decoder.Decode(&record); err != nil {
+		if err == io.EOF && !jr.array {
+			jr.done = true
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid JSON record %d: %+s", jr.total+1, err.Error())
+	}
+
+	jr.total++
+	return record, nil
+}
@@ -0,0 +1,101 @@
+package jsonfiles_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/pullers/jsonfiles"
+)
+
+func pullReader(reader *jsonfiles.JSONReader, batch int) ([]map[string]interface{}, error) {
+	var records []map[string]interface{}
+	for {
+		recs, err := reader.Pull(context.Background(), batch)
+		if err != nil {
+			if err == dataset.ErrNoMore {
+				return records, nil
+			}
+			return records, err
+		}
+
+		if len(recs) > batch {
+			tests.Failed("Should have received at most %d records but got %d", batch, len(recs))
+		}
+
+		records = append(records, recs...)
+	}
+}
+
+func TestJSONReader(t *testing.T) {
+	tests.Header("When reading a JSON array")
+	{
+		records, err := pullReader(jsonfiles.NewJSONReader(strings.NewReader(` [{"user": "bob"}, {"user": "alex"}, {"user": "ray"}] `)), 2)
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully read JSON array")
+		}
+		tests.Passed("Should have successfully read JSON array")
+
+		if len(records) != 3 || records[2]["user"] != "ray" {
+			tests.Failed("Should have received 3 records in order but got %d", len(records))
+		}
+		tests.Passed("Should have received 3 records in order")
+	}
+
+	tests.Header("When reading newline delimited JSON")
+	{
+		records, err := pullReader(jsonfiles.NewJSONReader(strings.NewReader("{\"user\": \"bob\"}\n{\"user\": \"alex\"}\n\n{\"user\": \"ray\"}\n")), 2)
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully read NDJSON")
+		}
+		tests.Passed("Should have successfully read NDJSON")
+
+		if len(records) != 3 {
+			tests.Failed("Should have received 3 records but got %d", len(records))
+		}
+		tests.Passed("Should have received 3 records")
+	}
+
+	tests.Header("When reading empty or invalid input")
+	{
+		records, err := pullReader(jsonfiles.NewJSONReader(strings.NewReader("  \n")), 2)
+		if err != nil || len(records) != 0 {
+			tests.Failed("Should have received no records for empty input")
+		}
+		tests.Passed("Should have received no records for empty input")
+
+		if _, err := pullReader(jsonfiles.NewJSONReader(strings.NewReader("{\"user\": \"bob\"}\n{\"user\": ")), 2); err == nil {
+			tests.Failed("Should have failed to read invalid NDJSON")
+		}
+		tests.Passed("Should have failed to read invalid NDJSON")
+
+		if _, err := pullReader(jsonfiles.NewJSONReader(strings.NewReader(`[{"user": "bob"}`)), 2); err == nil {
+			tests.Failed("Should have failed to read unterminated JSON array")
+		}
+		tests.Passed("Should have failed to read unterminated JSON array")
+	}
+
+	tests.Header("When opening a file")
+	{
+		reader, err := jsonfiles.OpenJSONReader("./fixtures/sentos/rack.json")
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully opened file")
+		}
+		tests.Passed("Should have successfully opened file")
+
+		defer reader.Close()
+
+		records, err := pullReader(reader, 1)
+		if err != nil || len(records) == 0 {
+			tests.Failed("Should have successfully read records from file")
+		}
+		tests.Passed("Should have successfully read records from file")
+
+		if _, err := jsonfiles.OpenJSONReader("./fixtures/sentos"); err == nil {
+			tests.Failed("Should have failed to open directory")
+		}
+		tests.Passed("Should have failed to open directory")
+	}
+}
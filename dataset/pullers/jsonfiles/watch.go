@@ -0,0 +1,597 @@
+package jsonfiles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/influx6/geckodataset/dataset"
+)
+
+const (
+	// DefaultSettle indicates the default duration a file must go without
+	// changes in size or modification time before it is considered ready.
+	DefaultSettle = time.Second * 2
+
+	// DefaultPollInterval indicates the default interval used to rescan the
+	// watched directory when polling is used.
+	DefaultPollInterval = time.Second * 5
+)
+
+// WatchConfig embodies the configuration used by a JSONWatcher to watch
+// a directory for new json files.
+type WatchConfig struct {
+	// Dir sets the directory to be watched for new json files.
+	Dir string
+
+	// Deep sets if sub-directories of Dir should also be watched.
+	Deep bool
+
+	// Poll forces the use of directory polling instead of file system
+	// notifications.
+	Poll bool
+
+	// ArchiveDir sets the directory processed files are moved into. If empty,
+	// processed files are left in place and never processed again unless modified.
+	ArchiveDir string
+
+	// FailedDir sets the directory files which failed processing are moved into.
+	// If empty, failed files are left in place.
+	FailedDir string
+
+	// Settle sets the duration a file must go without being written to
+	// before it is read.
+	Settle time.Duration
+
+	// PollInterval sets the interval for rescanning Dir when polling.
+	PollInterval time.Duration
+
+	// Filter sets the include and exclude patterns used to select files
+	// and the order in which settled files are processed.
+	Filter Filter
+}
+
+// pendingFile holds the last observed state of a file awaiting for
+// writes to settle.
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	changed time.Time
+}
+
+// JSONWatcher implements the dataset.DataPull interface over a directory which
+// is continuously watched for new json files. Files are only read after writes
+// to them have settled and are moved into the archive directory once all their
+// records have been handed out and the next pull is requested, or into the failed
+// directory when they fail to load or when Failed is called.
+//
+// JSONWatcher uses file system notifications where available and falls back
+// to polling the directory at the configured interval.
+type JSONWatcher struct {
+	config  WatchConfig
+	watcher *fsnotify.Watcher
+	closer  chan struct{}
+	waiter  sync.WaitGroup
+
+	ml          sync.Mutex
+	ready       []string
+	pending     map[string]pendingFile
+	processed   map[string]time.Time
+	current     *JSONStream
+	currentFile string
+}
+
+// Watch returns a new instance of JSONWatcher which watches the directory set in the
+// provided config. Existing json files within the directory are picked up as well.
+func Watch(config WatchConfig) (*JSONWatcher, error) {
+	stat, err := os.Stat(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !stat.IsDir() {
+		return nil, errors.New("only directories allowed")
+	}
+
+	if err := config.Filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Settle <= 0 {
+		config.Settle = DefaultSettle
+	}
+
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+
+	for _, dir := range []string{config.ArchiveDir, config.FailedDir} {
+		if dir == "" {
+			continue
+		}
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	jw := &JSONWatcher{
+		config:    config,
+		closer:    make(chan struct{}),
+		pending:   map[string]pendingFile{},
+		processed: map[string]time.Time{},
+	}
+
+	if !config.Poll {
+		// If we fail to setup file system notifications, then fallback
+		// to polling of directory.
+		if watcher, err := fsnotify.NewWatcher(); err == nil {
+			jw.watcher = watcher
+			if err := jw.watchDirs(); err != nil {
+				watcher.Close()
+				jw.watcher = nil
+			}
+		}
+	}
+
+	if err := jw.scan(); err != nil {
+		jw.Close()
+		return nil, err
+	}
+
+	jw.waiter.Add(1)
+	go jw.run()
+
+	return jw, nil
+}
+
+// Close stops the watching of the directory.
+func (jw *JSONWatcher) Close() error {
+	select {
+	case <-jw.closer:
+		return nil
+	default:
+		close(jw.closer)
+	}
+
+	var err error
+	if jw.watcher != nil {
+		err = jw.watcher.Close()
+	}
+
+	jw.waiter.Wait()
+	return err
+}
+
+// Pending returns total files found but yet to be processed.
+func (jw *JSONWatcher) Pending() int {
+	jw.ml.Lock()
+	defer jw.ml.Unlock()
+	return len(jw.ready) + len(jw.pending)
+}
+
+// Pull returns the next batch of records from the current file being processed. It
+// archives the current file once all it's records have been delivered and moves on
+// to the next settled file. If no file is ready then a dataset.ErrNoMore is returned,
+// which only signals that nothing is available at the moment.
+func (jw *JSONWatcher) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	jw.ml.Lock()
+	defer jw.ml.Unlock()
+
+	if batch == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	for {
+		if jw.current == nil {
+			if len(jw.ready) == 0 {
+				return nil, dataset.ErrNoMore
+			}
+
+			next := jw.ready[0]
+			jw.ready = jw.ready[1:]
+
+			stream, err := NewJSONStream(next)
+			if err != nil {
+				// file may have being removed before we got to it.
+				continue
+			}
+
+			jw.current = &stream
+			jw.currentFile = next
+		}
+
+		recs, err := jw.current.Pull(ctx, batch)
+		if err == nil {
+			return recs, nil
+		}
+
+		if err == dataset.ErrNoMore {
+			if err := jw.archive(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := jw.fail(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Failed moves the file currently being processed into the failed directory.
+// It should be called when processing of the last pulled records failed.
+func (jw *JSONWatcher) Failed() error {
+	jw.ml.Lock()
+	defer jw.ml.Unlock()
+
+	if jw.current == nil {
+		return nil
+	}
+
+	return jw.fail()
+}
+
+// Release stops the processing of the current file, leaving it in place to be
+// processed again from it's start by the next pull. It should be called when the
+// last pulled records failed to be pushed, so the records of the file are not lost.
+func (jw *JSONWatcher) Release() {
+	jw.ml.Lock()
+	defer jw.ml.Unlock()
+
+	if jw.current == nil {
+		return
+	}
+
+	jw.ready = append([]string{jw.currentFile}, jw.ready...)
+	jw.reset()
+}
+
+// archive moves the current file into the archive directory if set.
+func (jw *JSONWatcher) archive() error {
+	defer jw.reset()
+	return jw.moveCurrent(jw.config.ArchiveDir)
+}
+
+// fail moves the current file into the failed directory if set.
+func (jw *JSONWatcher) fail() error {
+	defer jw.reset()
+	return jw.moveCurrent(jw.config.FailedDir)
+}
+
+func (jw *JSONWatcher) reset() {
+	jw.current = nil
+	jw.currentFile = ""
+}
+
+func (jw *JSONWatcher) moveCurrent(dir string) error {
+	if dir == "" {
+		stat, err := os.Stat(jw.currentFile)
+		if err != nil {
+			return nil
+		}
+
+		jw.processed[jw.currentFile] = stat.ModTime()
+		return nil
+	}
+
+	return moveInto(jw.currentFile, jw.config.Dir, dir)
+}
+
+// run handles the events received from the file system notifications or
+// the polling of the directory, and promotes settled files to ready.
+func (jw *JSONWatcher) run() {
+	defer jw.waiter.Done()
+
+	var events chan fsnotify.Event
+	var errs chan error
+	if jw.watcher != nil {
+		events = jw.watcher.Events
+		errs = jw.watcher.Errors
+	}
+
+	interval := jw.config.Settle
+	if jw.watcher == nil && jw.config.PollInterval < interval {
+		interval = jw.config.PollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastScan := time.Now()
+	lastPrune := time.Now()
+	for {
+		select {
+		case <-jw.closer:
+			return
+		case <-errs:
+			continue
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+
+			if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+				continue
+			}
+
+			stat, err := os.Stat(event.Name)
+			if err != nil {
+				continue
+			}
+
+			if stat.IsDir() {
+				if jw.config.Deep {
+					jw.addDir(event.Name)
+				}
+				continue
+			}
+
+			jw.ml.Lock()
+			jw.track(event.Name, stat)
+			jw.ml.Unlock()
+		case <-ticker.C:
+			if jw.watcher == nil && time.Since(lastScan) >= jw.config.PollInterval {
+				lastScan = time.Now()
+				jw.scan()
+			}
+
+			if time.Since(lastPrune) >= jw.config.PollInterval {
+				lastPrune = time.Now()
+				jw.prune()
+			}
+
+			jw.settle()
+		}
+	}
+}
+
+// watchDirs adds the watched directory and if deep, all sub-directories to the
+// file system watcher.
+func (jw *JSONWatcher) watchDirs() error {
+	if !jw.config.Deep {
+		return jw.watcher.Add(jw.config.Dir)
+	}
+
+	return filepath.Walk(jw.config.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		if jw.ignored(path) {
+			return filepath.SkipDir
+		}
+
+		return jw.watcher.Add(path)
+	})
+}
+
+// addDir adds a directory created or moved into the watched directory, with all
+// it's sub-directories, to the file system watcher, tracking the files already
+// within them, as files written before a directory is watched send no events.
+func (jw *JSONWatcher) addDir(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if jw.ignored(path) {
+				return filepath.SkipDir
+			}
+
+			return jw.watcher.Add(path)
+		}
+
+		jw.ml.Lock()
+		defer jw.ml.Unlock()
+
+		jw.track(path, info)
+		return nil
+	})
+}
+
+// prune forgets processed files which were removed or changed since, so the
+// processed files left in place don't grow without end.
+func (jw *JSONWatcher) prune() {
+	jw.ml.Lock()
+	defer jw.ml.Unlock()
+
+	for path, modTime := range jw.processed {
+		stat, err := os.Stat(path)
+		if err != nil || !stat.ModTime().Equal(modTime) {
+			delete(jw.processed, path)
+		}
+	}
+}
+
+// scan walks the watched directory tracking all json files found.
+func (jw *JSONWatcher) scan() error {
+	jw.ml.Lock()
+	defer jw.ml.Unlock()
+
+	return filepath.Walk(jw.config.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path == jw.config.Dir {
+				return nil
+			}
+
+			if !jw.config.Deep || jw.ignored(path) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		jw.track(path, info)
+		return nil
+	})
+}
+
+// track records the current state of a json file which is yet to be processed.
+// It must be called with the lock held.
+func (jw *JSONWatcher) track(path string, info os.FileInfo) {
+	rel, err := filepath.Rel(jw.config.Dir, path)
+	if err != nil || !jw.config.Filter.Match(rel) {
+		return
+	}
+
+	if path == jw.currentFile {
+		return
+	}
+
+	if modTime, ok := jw.processed[path]; ok && modTime.Equal(info.ModTime()) {
+		return
+	}
+
+	for _, ready := range jw.ready {
+		if ready == path {
+			return
+		}
+	}
+
+	last, ok := jw.pending[path]
+	if ok && last.size == info.Size() && last.modTime.Equal(info.ModTime()) {
+		return
+	}
+
+	jw.pending[path] = pendingFile{
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		changed: time.Now(),
+	}
+}
+
+// settle promotes pending files which have not changed within the settle
+// duration into the ready list.
+func (jw *JSONWatcher) settle() {
+	jw.ml.Lock()
+	defer jw.ml.Unlock()
+
+	var settled []fileInfo
+	for path, last := range jw.pending {
+		stat, err := os.Stat(path)
+		if err != nil {
+			delete(jw.pending, path)
+			continue
+		}
+
+		if stat.Size() != last.size || !stat.ModTime().Equal(last.modTime) {
+			jw.pending[path] = pendingFile{
+				size:    stat.Size(),
+				modTime: stat.ModTime(),
+				changed: time.Now(),
+			}
+			continue
+		}
+
+		if time.Since(last.changed) < jw.config.Settle {
+			continue
+		}
+
+		// all paths share the watched directory as prefix, so ordering
+		// by full path matches ordering by relative path.
+		delete(jw.pending, path)
+		settled = append(settled, fileInfo{rel: path, info: stat})
+	}
+
+	sortFiles(settled, jw.config.Filter.Order)
+	for _, file := range settled {
+		jw.ready = append(jw.ready, file.rel)
+	}
+}
+
+// ignored returns true if path is the archive or failed directory. Paths are
+// compared as absolute paths, as the directories may be set relative to the
+// working directory while Dir is absolute, or the other way around.
+func (jw *JSONWatcher) ignored(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	for _, dir := range []string{jw.config.ArchiveDir, jw.config.FailedDir} {
+		if dir == "" {
+			continue
+		}
+
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+
+		if absDir == abs {
+			return true
+		}
+	}
+	return false
+}
+
+// moveInto moves the file into dir, keeping it's path relative to root so files of
+// the same name from different sub-directories do not collide. Files already found
+// at the target path are kept, with the moved file suffixed by the current time.
+func moveInto(file string, root string, dir string) error {
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		rel = filepath.Base(file)
+	}
+
+	target := filepath.Join(dir, rel)
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(target)
+		target = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(target, ext), time.Now().UnixNano(), ext)
+	}
+
+	return moveFile(file, target)
+}
+
+// moveFile moves the file from src to dest, copying content over if the
+// rename fails as can happen across devices.
+func moveFile(src string, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(src, dest); err == nil {
+		return nil
+	}
+
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer srcFile.Close()
+
+	destFile, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(destFile, srcFile); err != nil {
+		destFile.Close()
+		return err
+	}
+
+	if err := destFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
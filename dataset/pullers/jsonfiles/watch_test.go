@@ -0,0 +1,402 @@
+package jsonfiles_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/pullers/jsonfiles"
+)
+
+func TestJSONWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonwatch")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created temporary directory")
+	}
+	tests.Passed("Should have successfully created temporary directory")
+
+	defer os.RemoveAll(dir)
+
+	sourceDir := filepath.Join(dir, "source")
+	archiveDir := filepath.Join(dir, "archive")
+	failedDir := filepath.Join(dir, "failed")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		tests.FailedWithError(err, "Should have successfully created source directory")
+	}
+	tests.Passed("Should have successfully created source directory")
+
+	watcher, err := jsonfiles.Watch(jsonfiles.WatchConfig{
+		Dir:          sourceDir,
+		Poll:         true,
+		ArchiveDir:   archiveDir,
+		FailedDir:    failedDir,
+		Settle:       time.Millisecond * 50,
+		PollInterval: time.Millisecond * 20,
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created watcher")
+	}
+	tests.Passed("Should have successfully created watcher")
+
+	defer watcher.Close()
+
+	if _, err := watcher.Pull(context.Background(), 2); err != dataset.ErrNoMore {
+		tests.Failed("Should have received dataset.ErrNoMore from empty directory")
+	}
+	tests.Passed("Should have received dataset.ErrNoMore from empty directory")
+
+	tests.Header("When a new json file arrives in watched directory")
+	{
+		if err := ioutil.WriteFile(filepath.Join(sourceDir, "sales.json"), []byte(`[{"user":"bob"},{"user":"alex"},{"user":"dan"}]`), 0644); err != nil {
+			tests.FailedWithError(err, "Should have successfully written json file")
+		}
+		tests.Passed("Should have successfully written json file")
+
+		var total int
+		deadline := time.Now().Add(time.Second * 5)
+		for total < 3 && time.Now().Before(deadline) {
+			recs, err := watcher.Pull(context.Background(), 2)
+			if err != nil && err != dataset.ErrNoMore {
+				tests.FailedWithError(err, "Should have successfully pulled records")
+			}
+
+			total += len(recs)
+			time.Sleep(time.Millisecond * 10)
+		}
+
+		if total != 3 {
+			tests.Failed("Should have received 3 records but got %d", total)
+		}
+		tests.Passed("Should have received 3 records")
+
+		if _, err := watcher.Pull(context.Background(), 2); err != dataset.ErrNoMore {
+			tests.Failed("Should have received dataset.ErrNoMore after processing file")
+		}
+		tests.Passed("Should have received dataset.ErrNoMore after processing file")
+
+		if _, err := os.Stat(filepath.Join(archiveDir, "sales.json")); err != nil {
+			tests.FailedWithError(err, "Should have moved processed file into archive directory")
+		}
+		tests.Passed("Should have moved processed file into archive directory")
+	}
+
+	tests.Header("When a bad json file arrives in watched directory")
+	{
+		if err := ioutil.WriteFile(filepath.Join(sourceDir, "bad.json"), []byte(`{"user":`), 0644); err != nil {
+			tests.FailedWithError(err, "Should have successfully written json file")
+		}
+		tests.Passed("Should have successfully written json file")
+
+		deadline := time.Now().Add(time.Second * 5)
+		for time.Now().Before(deadline) {
+			if _, err := os.Stat(filepath.Join(failedDir, "bad.json")); err == nil {
+				break
+			}
+
+			if _, err := watcher.Pull(context.Background(), 2); err != nil && err != dataset.ErrNoMore {
+				tests.FailedWithError(err, "Should have skipped bad file")
+			}
+
+			time.Sleep(time.Millisecond * 10)
+		}
+
+		if _, err := os.Stat(filepath.Join(failedDir, "bad.json")); err != nil {
+			tests.FailedWithError(err, "Should have moved bad file into failed directory")
+		}
+		tests.Passed("Should have moved bad file into failed directory")
+	}
+}
+
+func TestJSONWatcherWithRelativeArchiveDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonwatch")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created temporary directory")
+	}
+	tests.Passed("Should have successfully created temporary directory")
+
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully retrieved working directory")
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		tests.FailedWithError(err, "Should have successfully changed working directory")
+	}
+
+	defer os.Chdir(wd)
+
+	sourceDir := filepath.Join(dir, "source")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		tests.FailedWithError(err, "Should have successfully created source directory")
+	}
+	tests.Passed("Should have successfully created source directory")
+
+	watcher, err := jsonfiles.Watch(jsonfiles.WatchConfig{
+		Dir:          sourceDir,
+		Deep:         true,
+		Poll:         true,
+		ArchiveDir:   filepath.Join("source", "archive"),
+		Settle:       time.Millisecond * 50,
+		PollInterval: time.Millisecond * 20,
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created watcher")
+	}
+	tests.Passed("Should have successfully created watcher")
+
+	defer watcher.Close()
+
+	tests.Header("When archive directory is relative within absolute watched directory")
+	{
+		if err := ioutil.WriteFile(filepath.Join(sourceDir, "sales.json"), []byte(`[{"user":"bob"}]`), 0644); err != nil {
+			tests.FailedWithError(err, "Should have successfully written json file")
+		}
+		tests.Passed("Should have successfully written json file")
+
+		var total int
+		deadline := time.Now().Add(time.Second * 5)
+		for time.Now().Before(deadline) {
+			recs, err := watcher.Pull(context.Background(), 2)
+			if err != nil && err != dataset.ErrNoMore {
+				tests.FailedWithError(err, "Should have successfully pulled records")
+			}
+
+			total += len(recs)
+			if total != 0 && watcher.Pending() == 0 {
+				if _, err := os.Stat(filepath.Join(sourceDir, "archive", "sales.json")); err == nil {
+					break
+				}
+			}
+
+			time.Sleep(time.Millisecond * 10)
+		}
+
+		if _, err := os.Stat(filepath.Join(sourceDir, "archive", "sales.json")); err != nil {
+			tests.FailedWithError(err, "Should have moved processed file into archive directory")
+		}
+		tests.Passed("Should have moved processed file into archive directory")
+
+		// Give the watcher time to rescan and settle, where archived files
+		// must never be picked up again.
+		time.Sleep(time.Millisecond * 200)
+
+		if recs, err := watcher.Pull(context.Background(), 2); err != dataset.ErrNoMore || watcher.Pending() != 0 {
+			tests.Failed("Should have ignored archive directory but got %d records", len(recs))
+		}
+		tests.Passed("Should have ignored archive directory")
+
+		if total != 1 {
+			tests.Failed("Should have received 1 record but got %d", total)
+		}
+		tests.Passed("Should have received 1 record")
+	}
+}
+
+func TestJSONWatcherRelease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonwatch")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created temporary directory")
+	}
+	tests.Passed("Should have successfully created temporary directory")
+
+	defer os.RemoveAll(dir)
+
+	watcher, err := jsonfiles.Watch(jsonfiles.WatchConfig{
+		Dir:          dir,
+		Poll:         true,
+		Settle:       time.Millisecond * 50,
+		PollInterval: time.Millisecond * 20,
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created watcher")
+	}
+	tests.Passed("Should have successfully created watcher")
+
+	defer watcher.Close()
+
+	tests.Header("When the current file is released")
+	{
+		if err := ioutil.WriteFile(filepath.Join(dir, "sales.json"), []byte(`[{"user":"bob"},{"user":"alex"},{"user":"dan"}]`), 0644); err != nil {
+			tests.FailedWithError(err, "Should have successfully written json file")
+		}
+		tests.Passed("Should have successfully written json file")
+
+		var recs []map[string]interface{}
+		deadline := time.Now().Add(time.Second * 5)
+		for len(recs) == 0 && time.Now().Before(deadline) {
+			recs, err = watcher.Pull(context.Background(), 2)
+			if err != nil && err != dataset.ErrNoMore {
+				tests.FailedWithError(err, "Should have successfully pulled records")
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+
+		if len(recs) != 2 {
+			tests.Failed("Should have received 2 records but got %d", len(recs))
+		}
+		tests.Passed("Should have received 2 records")
+
+		watcher.Release()
+
+		var total int
+		for {
+			recs, err := watcher.Pull(context.Background(), 2)
+			if err == dataset.ErrNoMore {
+				break
+			}
+
+			if err != nil {
+				tests.FailedWithError(err, "Should have successfully pulled records")
+			}
+
+			total += len(recs)
+		}
+
+		if total != 3 {
+			tests.Failed("Should have received all 3 records of released file again but got %d", total)
+		}
+		tests.Passed("Should have received all 3 records of released file again")
+	}
+}
+
+// pullWithin pulls records from the watcher until total records are received or
+// the deadline passes, returning the records received.
+func pullWithin(watcher *jsonfiles.JSONWatcher, total int, wait time.Duration) int {
+	var received int
+	deadline := time.Now().Add(wait)
+	for received < total && time.Now().Before(deadline) {
+		recs, err := watcher.Pull(context.Background(), 2)
+		if err != nil && err != dataset.ErrNoMore {
+			tests.FailedWithError(err, "Should have successfully pulled records")
+		}
+
+		received += len(recs)
+		time.Sleep(time.Millisecond * 10)
+	}
+	return received
+}
+
+func TestJSONWatcherWithMovedInDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonwatch")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created temporary directory")
+	}
+	tests.Passed("Should have successfully created temporary directory")
+
+	defer os.RemoveAll(dir)
+
+	sourceDir := filepath.Join(dir, "source")
+	stagingDir := filepath.Join(dir, "staging")
+
+	for _, path := range []string{sourceDir, filepath.Join(stagingDir, "daily", "eu")} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			tests.FailedWithError(err, "Should have successfully created directory")
+		}
+	}
+	tests.Passed("Should have successfully created directories")
+
+	watcher, err := jsonfiles.Watch(jsonfiles.WatchConfig{
+		Dir:          sourceDir,
+		Deep:         true,
+		Settle:       time.Millisecond * 50,
+		PollInterval: time.Hour,
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created watcher")
+	}
+	tests.Passed("Should have successfully created watcher")
+
+	defer watcher.Close()
+
+	tests.Header("When a directory holding json files is moved into watched directory")
+	{
+		for _, path := range []string{filepath.Join(stagingDir, "daily", "sales.json"), filepath.Join(stagingDir, "daily", "eu", "sales.json")} {
+			if err := ioutil.WriteFile(path, []byte(`[{"user":"bob"},{"user":"alex"}]`), 0644); err != nil {
+				tests.FailedWithError(err, "Should have successfully written json file")
+			}
+		}
+		tests.Passed("Should have successfully written json files")
+
+		if err := os.Rename(filepath.Join(stagingDir, "daily"), filepath.Join(sourceDir, "daily")); err != nil {
+			tests.FailedWithError(err, "Should have successfully moved directory")
+		}
+		tests.Passed("Should have successfully moved directory")
+
+		if total := pullWithin(watcher, 4, time.Second*5); total != 4 {
+			tests.Failed("Should have received 4 records of files within moved directory but got %d", total)
+		}
+		tests.Passed("Should have received 4 records of files within moved directory")
+	}
+}
+
+func TestJSONWatcherForgetsRemovedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonwatch")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created temporary directory")
+	}
+	tests.Passed("Should have successfully created temporary directory")
+
+	defer os.RemoveAll(dir)
+
+	watcher, err := jsonfiles.Watch(jsonfiles.WatchConfig{
+		Dir:          dir,
+		Poll:         true,
+		Settle:       time.Millisecond * 50,
+		PollInterval: time.Millisecond * 20,
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created watcher")
+	}
+	tests.Passed("Should have successfully created watcher")
+
+	defer watcher.Close()
+
+	path := filepath.Join(dir, "sales.json")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	write := func() {
+		if err := ioutil.WriteFile(path, []byte(`[{"user":"bob"}]`), 0644); err != nil {
+			tests.FailedWithError(err, "Should have successfully written json file")
+		}
+
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			tests.FailedWithError(err, "Should have successfully set time of json file")
+		}
+	}
+
+	tests.Header("When a processed file is removed and written again unchanged")
+	{
+		write()
+
+		if total := pullWithin(watcher, 1, time.Second*5); total != 1 {
+			tests.Failed("Should have received 1 record but got %d", total)
+		}
+		tests.Passed("Should have received 1 record")
+
+		if _, err := watcher.Pull(context.Background(), 2); err != dataset.ErrNoMore {
+			tests.Failed("Should have received dataset.ErrNoMore after processing file")
+		}
+		tests.Passed("Should have received dataset.ErrNoMore after processing file")
+
+		if err := os.Remove(path); err != nil {
+			tests.FailedWithError(err, "Should have successfully removed json file")
+		}
+
+		// Allows the watcher to forget the removed file.
+		time.Sleep(time.Millisecond * 200)
+
+		write()
+
+		if total := pullWithin(watcher, 1, time.Second*5); total != 1 {
+			tests.Failed("Should have received record of file written again but got %d", total)
+		}
+		tests.Passed("Should have received record of file written again")
+	}
+}
@@ -0,0 +1,413 @@
+package mongodb
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/faux/db/mongo"
+	"github.com/influx6/geckodataset/dataset"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DefaultDialTimeout indicates the default timeout for connecting to a mongodb server.
+const DefaultDialTimeout = time.Second * 30
+
+// Dial returns a new mongodb session for the provided config.
+func Dial(config mongo.Config) (*mgo.Session, error) {
+	info := &mgo.DialInfo{
+		Addrs:    strings.Split(config.Host, ","),
+		Database: config.AuthDB,
+		Username: config.User,
+		Password: config.Password,
+		Timeout:  DefaultDialTimeout,
+	}
+
+	if info.Database == "" {
+		info.Database = config.DB
+	}
+
+	return mgo.DialWithInfo(info)
+}
+
+// Config embodies the configuration used by MongoPull to pull records
+// from a mongodb collection.
+type Config struct {
+	// DB sets the database containing the collection.
+	DB string
+
+	// Collection sets the collection records are pulled from.
+	Collection string
+
+	// IncrementalField sets the field, which must be increasing (e.g updated_at or _id),
+	// used to only pull documents with values above Watermark, ordered by the field.
+	IncrementalField string
+
+	// Watermark sets the value of IncrementalField after which documents are pulled.
+	// If nil, documents are pulled from the start.
+	Watermark interface{}
+
+	// Query sets the filter for documents pulled from the collection.
+	Query bson.M
+
+	// Projection sets the fields of documents returned.
+	Projection bson.M
+
+	// Sort sets the fields documents are ordered by, where fields prefixed
+	// with `-` are ordered in descending order.
+	Sort []string
+
+	// Limit sets the maximum documents pulled if above zero.
+	Limit int
+
+	// Pipeline sets the aggregation pipeline whose results are pulled instead
+	// of querying the collection. See ParsePipeline.
+	Pipeline []interface{}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if c.DB == "" {
+		return errors.New("mongodb.Config.DB is required")
+	}
+
+	if c.Collection == "" {
+		return errors.New("mongodb.Config.Collection is required")
+	}
+
+	if c.IncrementalField != "" && len(c.Sort) != 0 {
+		return errors.New("mongodb.Config.Sort can't be used with IncrementalField")
+	}
+
+	if c.Limit < 0 {
+		return errors.New("mongodb.Config.Limit can't be negative")
+	}
+
+	if len(c.Pipeline) != 0 {
+		if c.IncrementalField != "" || len(c.Query) != 0 || len(c.Projection) != 0 || len(c.Sort) != 0 || c.Limit != 0 {
+			return errors.New("mongodb.Config.Pipeline can't be used with IncrementalField, Query, Projection, Sort or Limit")
+		}
+	}
+
+	return nil
+}
+
+// ParseQuery returns the filter document of the giving mongodb extended json, which
+// supports values like `{"$date": "2026-10-01T00:00:00Z"}` and `{"$oid": "..."}`.
+func ParseQuery(query string) (bson.M, error) {
+	filter := bson.M{}
+	if strings.TrimSpace(query) == "" {
+		return filter, nil
+	}
+
+	if err := bson.UnmarshalJSON([]byte(query), &filter); err != nil {
+		return nil, fmt.Errorf("invalid query %q: %+s", query, err.Error())
+	}
+
+	return filter, nil
+}
+
+// MongoPull implements the dataset.DataPull interface for pulling records from
+// a mongodb collection, where documents are read in batches from a single cursor.
+type MongoPull struct {
+	config  Config
+	session *mgo.Session
+
+	ml           sync.Mutex
+	iter         *mgo.Iter
+	watermark    interface{}
+	hasWatermark bool
+}
+
+// New returns a new instance of MongoPull using a copy of the provided session.
+func New(session *mgo.Session, config Config) (*MongoPull, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &MongoPull{
+		config:       config,
+		session:      session.Copy(),
+		watermark:    config.Watermark,
+		hasWatermark: config.Watermark != nil,
+	}, nil
+}
+
+// Close closes the cursor and session of the MongoPull.
+func (mp *MongoPull) Close() error {
+	mp.ml.Lock()
+	defer mp.ml.Unlock()
+
+	var err error
+	if mp.iter != nil {
+		err = mp.iter.Close()
+		mp.iter = nil
+	}
+
+	mp.session.Close()
+	return err
+}
+
+// Watermark returns the value of the IncrementalField of the last document pulled,
+// or the starting watermark if none has been pulled yet.
+func (mp *MongoPull) Watermark() (interface{}, bool) {
+	mp.ml.Lock()
+	defer mp.ml.Unlock()
+	return mp.watermark, mp.hasWatermark
+}
+
+// Pull returns the next batch of documents from the collection.
+func (mp *MongoPull) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	mp.ml.Lock()
+	defer mp.ml.Unlock()
+
+	if batch == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	if mp.iter == nil {
+		mp.iter = mp.query(batch)
+	}
+
+	var records []map[string]interface{}
+	for len(records) < batch {
+		var doc bson.M
+		if !mp.iter.Next(&doc) {
+			break
+		}
+
+		if mp.config.IncrementalField != "" {
+			if value := Lookup(doc, mp.config.IncrementalField); value != nil {
+				mp.watermark = value
+				mp.hasWatermark = true
+			}
+		}
+
+		records = append(records, Normalize(doc).(map[string]interface{}))
+	}
+
+	if err := mp.iter.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	return records, nil
+}
+
+// query returns the cursor for documents of the collection, or for the
+// results of the aggregation pipeline if one is set.
+func (mp *MongoPull) query(batch int) *mgo.Iter {
+	collection := mp.session.DB(mp.config.DB).C(mp.config.Collection)
+	if len(mp.config.Pipeline) != 0 {
+		return collection.Pipe(mp.config.Pipeline).AllowDiskUse().Batch(batch).Iter()
+	}
+
+	filter := mp.config.Query
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	if mp.config.IncrementalField != "" && mp.config.Watermark != nil {
+		after := bson.M{mp.config.IncrementalField: bson.M{"$gt": mp.config.Watermark}}
+		if len(filter) == 0 {
+			filter = after
+		} else {
+			filter = bson.M{"$and": []interface{}{filter, after}}
+		}
+	}
+
+	query := collection.Find(filter)
+
+	if projection := mp.projection(); len(projection) != 0 {
+		query = query.Select(projection)
+	}
+
+	if mp.config.IncrementalField != "" {
+		query = query.Sort(mp.config.IncrementalField)
+	} else if len(mp.config.Sort) != 0 {
+		query = query.Sort(mp.config.Sort...)
+	}
+
+	if mp.config.Limit > 0 {
+		query = query.Limit(mp.config.Limit)
+	}
+
+	return query.Batch(batch).Iter()
+}
+
+// projection returns the projection for documents, ensuring the incremental field is
+// included when the projection only includes selected fields.
+func (mp *MongoPull) projection() bson.M {
+	if mp.config.IncrementalField == "" {
+		return mp.config.Projection
+	}
+
+	return IncludeField(mp.config.Projection, mp.config.IncrementalField)
+}
+
+// IncludeField returns a copy of the projection with the field included, when the
+// projection only includes selected fields, else the projection is returned as is.
+// Projections can't mix included and excluded fields besides _id, so the first
+// field other than _id by name decides, where any non-zero number includes it, as
+// numbers are decoded as int64 from toml and float64 from json.
+func IncludeField(projection bson.M, field string) bson.M {
+	if len(projection) == 0 {
+		return projection
+	}
+
+	fields := make([]string, 0, len(projection))
+	for name := range projection {
+		if name != "_id" {
+			fields = append(fields, name)
+		}
+	}
+
+	if len(fields) == 0 {
+		return projection
+	}
+
+	sort.Strings(fields)
+	if !included(projection[fields[0]]) {
+		return projection
+	}
+
+	res := make(bson.M, len(projection)+1)
+	for name, value := range projection {
+		res[name] = value
+	}
+
+	res[field] = 1
+	return res
+}
+
+// included returns true if the projection value of a field includes the field.
+func included(value interface{}) bool {
+	item := reflect.ValueOf(value)
+	switch item.Kind() {
+	case reflect.Bool:
+		return item.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return item.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return item.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return item.Float() != 0
+	default:
+		return false
+	}
+}
+
+// Lookup returns the value of the giving dotted field path within the document.
+func Lookup(doc bson.M, field string) interface{} {
+	var current interface{} = doc
+	for _, key := range strings.Split(field, ".") {
+		switch item := current.(type) {
+		case bson.M:
+			current = item[key]
+		case map[string]interface{}:
+			current = item[key]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// Normalize returns a json friendly version of the giving bson value, where
+// object ids are turned into their hex values, times into RFC3339 strings and
+// binary data into base64 strings.
+func Normalize(value interface{}) interface{} {
+	switch item := value.(type) {
+	case bson.M:
+		return Normalize(map[string]interface{}(item))
+	case map[string]interface{}:
+		doc := make(map[string]interface{}, len(item))
+		for key, elem := range item {
+			doc[key] = Normalize(elem)
+		}
+		return doc
+	case bson.D:
+		doc := make(map[string]interface{}, len(item))
+		for _, elem := range item {
+			doc[elem.Name] = Normalize(elem.Value)
+		}
+		return doc
+	case []interface{}:
+		list := make([]interface{}, len(item))
+		for index, elem := range item {
+			list[index] = Normalize(elem)
+		}
+		return list
+	case bson.ObjectId:
+		return item.Hex()
+	case time.Time:
+		return item.Format(time.RFC3339)
+	case bson.MongoTimestamp:
+		return int64(item)
+	case bson.Binary:
+		return base64.StdEncoding.EncodeToString(item.Data)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(item)
+	default:
+		return value
+	}
+}
+
+// StateStore persists values such as watermarks of datasets into a
+// mongodb collection, where each value is stored by a giving key.
+type StateStore struct {
+	db         string
+	collection string
+	session    *mgo.Session
+}
+
+// NewStateStore returns a new instance of StateStore using a copy of the provided session.
+func NewStateStore(session *mgo.Session, db string, collection string) *StateStore {
+	return &StateStore{
+		db:         db,
+		collection: collection,
+		session:    session.Copy(),
+	}
+}
+
+// Close closes the session of the StateStore.
+func (ss *StateStore) Close() {
+	ss.session.Close()
+}
+
+// Load returns the value stored for the giving key, returning false if none exists.
+func (ss *StateStore) Load(key string) (interface{}, bool, error) {
+	var state struct {
+		Value interface{} `bson:"value"`
+	}
+
+	if err := ss.session.DB(ss.db).C(ss.collection).FindId(key).One(&state); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return state.Value, true, nil
+}
+
+// Save stores the value for the giving key.
+func (ss *StateStore) Save(key string, value interface{}) error {
+	_, err := ss.session.DB(ss.db).C(ss.collection).UpsertId(key, bson.M{
+		"$set": bson.M{
+			"value":      value,
+			"updated_at": time.Now(),
+		},
+	})
+	return err
+}
@@ -0,0 +1,499 @@
+package mongodb_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/faux/db/mongo"
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/pullers/mongodb"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	mongoEnvName = "MONGO_TEST_HOST"
+	testDB       = "geckodataset_test"
+)
+
+// testSession returns a session to the mongodb server set in the MONGO_TEST_HOST
+// environment variable, or nil if it is not set.
+func testSession() *mgo.Session {
+	host := strings.TrimSpace(os.Getenv(mongoEnvName))
+	if host == "" {
+		tests.Info("Mongo tests requires %+q environment variable first", mongoEnvName)
+		return nil
+	}
+
+	session, err := mongodb.Dial(mongo.Config{Host: host, DB: testDB})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully connected to mongodb")
+	}
+	tests.Passed("Should have successfully connected to mongodb")
+
+	return session
+}
+
+func pullAll(puller dataset.DataPull, batch int) []map[string]interface{} {
+	var records []map[string]interface{}
+	for {
+		recs, err := puller.Pull(context.Background(), batch)
+		if err != nil {
+			if err == dataset.ErrNoMore {
+				break
+			}
+			tests.FailedWithError(err, "Should have successfully pulled records")
+		}
+
+		records = append(records, recs...)
+	}
+	return records
+}
+
+func TestNormalize(t *testing.T) {
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	doc := mongodb.Normalize(bson.M{
+		"created": created,
+		"meta":    bson.M{"tags": []interface{}{bson.M{"name": "sales"}}},
+	}).(map[string]interface{})
+
+	if doc["created"] != "2026-10-01T12:00:00Z" {
+		tests.Failed("Should have formatted time as RFC3339 but got %#v", doc["created"])
+	}
+	tests.Passed("Should have formatted time as RFC3339")
+
+	meta, ok := doc["meta"].(map[string]interface{})
+	if !ok {
+		tests.Failed("Should have converted nested bson.M into map")
+	}
+	tests.Passed("Should have converted nested bson.M into map")
+
+	tags, ok := meta["tags"].([]interface{})
+	if !ok || len(tags) != 1 {
+		tests.Failed("Should have kept nested list")
+	}
+	tests.Passed("Should have kept nested list")
+
+	if _, ok := tags[0].(map[string]interface{}); !ok {
+		tests.Failed("Should have converted bson.M within list into map")
+	}
+	tests.Passed("Should have converted bson.M within list into map")
+}
+
+func TestIncludeField(t *testing.T) {
+	cases := []struct {
+		projection bson.M
+		included   bool
+	}{
+		{projection: bson.M{"_id": 0, "name": int64(1)}, included: true},
+		{projection: bson.M{"_id": 0, "name": 1}, included: true},
+		{projection: bson.M{"_id": 0, "name": float64(1)}, included: true},
+		{projection: bson.M{"_id": 0, "name": true}, included: true},
+		{projection: bson.M{"_id": 1, "name": int64(0)}, included: false},
+		{projection: bson.M{"_id": 0, "name": false}, included: false},
+		{projection: bson.M{"_id": 0}, included: false},
+	}
+
+	for _, item := range cases {
+		projection := mongodb.IncludeField(item.projection, "seq")
+		if _, ok := projection["seq"]; ok != item.included {
+			tests.Failed("Should have included field %t for projection %#v but got %#v", item.included, item.projection, projection)
+		}
+		tests.Passed("Should have included field %t for projection %#v", item.included, item.projection)
+
+		if _, ok := item.projection["seq"]; ok {
+			tests.Failed("Should not have changed projection %#v", item.projection)
+		}
+	}
+}
+
+func TestIncrementalMongoPull(t *testing.T) {
+	session := testSession()
+	if session == nil {
+		return
+	}
+
+	defer session.Close()
+
+	collection := session.DB(testDB).C("incremental_sales")
+	collection.DropCollection()
+	defer collection.DropCollection()
+
+	for i := 1; i <= 5; i++ {
+		if err := collection.Insert(bson.M{"seq": i, "user": "bob"}); err != nil {
+			tests.FailedWithError(err, "Should have successfully inserted document")
+		}
+	}
+	tests.Passed("Should have successfully inserted documents")
+
+	config := mongodb.Config{
+		DB:               testDB,
+		Collection:       "incremental_sales",
+		IncrementalField: "seq",
+	}
+
+	puller, err := mongodb.New(session, config)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created puller")
+	}
+	tests.Passed("Should have successfully created puller")
+
+	if records := pullAll(puller, 2); len(records) != 5 {
+		tests.Failed("Should have received 5 records but got %d", len(records))
+	}
+	tests.Passed("Should have received 5 records")
+
+	watermark, ok := puller.Watermark()
+	if !ok || watermark != 5 {
+		tests.Failed("Should have watermark of 5 but got %#v", watermark)
+	}
+	tests.Passed("Should have watermark of 5")
+	puller.Close()
+
+	store := mongodb.NewStateStore(session, testDB, "incremental_watermarks")
+	defer store.Close()
+	defer session.DB(testDB).C("incremental_watermarks").DropCollection()
+
+	if err := store.Save("sales", watermark); err != nil {
+		tests.FailedWithError(err, "Should have successfully saved watermark")
+	}
+	tests.Passed("Should have successfully saved watermark")
+
+	saved, found, err := store.Load("sales")
+	if err != nil || !found || saved != 5 {
+		tests.Failed("Should have loaded saved watermark of 5 but got %#v", saved)
+	}
+	tests.Passed("Should have loaded saved watermark of 5")
+
+	for i := 6; i <= 7; i++ {
+		if err := collection.Insert(bson.M{"seq": i, "user": "alex"}); err != nil {
+			tests.FailedWithError(err, "Should have successfully inserted document")
+		}
+	}
+	tests.Passed("Should have successfully inserted new documents")
+
+	config.Watermark = saved
+	puller, err = mongodb.New(session, config)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created puller")
+	}
+	tests.Passed("Should have successfully created puller")
+
+	defer puller.Close()
+
+	records := pullAll(puller, 10)
+	if len(records) != 2 {
+		tests.Failed("Should have received only 2 new records but got %d", len(records))
+	}
+	tests.Passed("Should have received only 2 new records")
+}
+
+func TestParseQuery(t *testing.T) {
+	filter, err := mongodb.ParseQuery(`{"created_at": {"$gte": {"$date": "2026-10-01T00:00:00Z"}}, "region": "emea"}`)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully parsed query")
+	}
+	tests.Passed("Should have successfully parsed query")
+
+	if filter["region"] != "emea" {
+		tests.Failed("Should have received region in filter")
+	}
+	tests.Passed("Should have received region in filter")
+
+	if created, ok := mongodb.Lookup(filter, "created_at.$gte").(time.Time); !ok {
+		tests.Failed("Should have parsed $date into time.Time but got %T", created)
+	}
+	tests.Passed("Should have parsed $date into time.Time")
+
+	if _, err := mongodb.ParseQuery(`{"region": `); err == nil {
+		tests.Failed("Should have failed to parse invalid query")
+	}
+	tests.Passed("Should have failed to parse invalid query")
+}
+
+func TestMongoPullWithQuery(t *testing.T) {
+	session := testSession()
+	if session == nil {
+		return
+	}
+
+	defer session.Close()
+
+	collection := session.DB(testDB).C("query_sales")
+	collection.DropCollection()
+	defer collection.DropCollection()
+
+	for i := 1; i <= 6; i++ {
+		region := "emea"
+		if i%2 == 0 {
+			region = "apac"
+		}
+
+		if err := collection.Insert(bson.M{"seq": i, "region": region, "user": "bob", "notes": "large notes"}); err != nil {
+			tests.FailedWithError(err, "Should have successfully inserted document")
+		}
+	}
+	tests.Passed("Should have successfully inserted documents")
+
+	query, err := mongodb.ParseQuery(`{"region": "emea"}`)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully parsed query")
+	}
+	tests.Passed("Should have successfully parsed query")
+
+	puller, err := mongodb.New(session, mongodb.Config{
+		DB:         testDB,
+		Collection: "query_sales",
+		Query:      query,
+		Projection: bson.M{"_id": 0, "seq": 1, "region": 1},
+		Sort:       []string{"-seq"},
+		Limit:      2,
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created puller")
+	}
+	tests.Passed("Should have successfully created puller")
+
+	defer puller.Close()
+
+	records := pullAll(puller, 10)
+	if len(records) != 2 {
+		tests.Failed("Should have received 2 records but got %d", len(records))
+	}
+	tests.Passed("Should have received 2 records")
+
+	if records[0]["seq"] != 5 || records[1]["seq"] != 3 {
+		tests.Failed("Should have received records in descending order of seq")
+	}
+	tests.Passed("Should have received records in descending order of seq")
+
+	if _, ok := records[0]["notes"]; ok {
+		tests.Failed("Should have excluded notes field through projection")
+	}
+	tests.Passed("Should have excluded notes field through projection")
+}
+
+func TestParsePipeline(t *testing.T) {
+	pipeline, err := mongodb.ParsePipeline([]interface{}{
+		map[string]interface{}{"$match": map[string]interface{}{"status": "paid"}},
+		`{"$match": {"created_at": {"$gte": {"$date": "2026-10-01T00:00:00Z"}}}}`,
+		`{"$sort": {"total": -1, "user": 1, "region": 1}}`,
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully parsed pipeline")
+	}
+	tests.Passed("Should have successfully parsed pipeline")
+
+	if len(pipeline) != 3 {
+		tests.Failed("Should have received 3 stages but got %d", len(pipeline))
+	}
+	tests.Passed("Should have received 3 stages")
+
+	match := pipeline[1].(bson.D)[0].Value.(bson.D)
+	if _, ok := match[0].Value.(bson.D)[0].Value.(time.Time); !ok {
+		tests.Failed("Should have parsed $date into time.Time")
+	}
+	tests.Passed("Should have parsed $date into time.Time")
+
+	sort := pipeline[2].(bson.D)[0].Value.(bson.D)
+	if sort[0].Name != "total" || sort[1].Name != "user" || sort[2].Name != "region" {
+		tests.Failed("Should have kept order of $sort fields but got %#v", sort)
+	}
+	tests.Passed("Should have kept order of $sort fields")
+
+	if sort[0].Value != int64(-1) {
+		tests.Failed("Should have parsed integers as int64 but got %T", sort[0].Value)
+	}
+	tests.Passed("Should have parsed integers as int64")
+
+	if _, err := mongodb.ParsePipeline([]interface{}{`["$match"]`}); err == nil {
+		tests.Failed("Should have failed to parse stage which is not a document")
+	}
+	tests.Passed("Should have failed to parse stage which is not a document")
+}
+
+func TestMongoPullWithPipeline(t *testing.T) {
+	session := testSession()
+	if session == nil {
+		return
+	}
+
+	defer session.Close()
+
+	collection := session.DB(testDB).C("pipeline_sales")
+	collection.DropCollection()
+	defer collection.DropCollection()
+
+	for i := 1; i <= 6; i++ {
+		user := "bob"
+		if i%3 == 0 {
+			user = "alex"
+		}
+
+		if err := collection.Insert(bson.M{"seq": i, "user": user, "amount": i * 10}); err != nil {
+			tests.FailedWithError(err, "Should have successfully inserted document")
+		}
+	}
+	tests.Passed("Should have successfully inserted documents")
+
+	pipeline, err := mongodb.ParsePipeline([]interface{}{
+		`{"$group": {"_id": "$user", "total": {"$sum": "$amount"}}}`,
+		`{"$sort": {"total": -1}}`,
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully parsed pipeline")
+	}
+	tests.Passed("Should have successfully parsed pipeline")
+
+	puller, err := mongodb.New(session, mongodb.Config{
+		DB:         testDB,
+		Collection: "pipeline_sales",
+		Pipeline:   pipeline,
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created puller")
+	}
+	tests.Passed("Should have successfully created puller")
+
+	defer puller.Close()
+
+	records := pullAll(puller, 1)
+	if len(records) != 2 {
+		tests.Failed("Should have received 2 records but got %d", len(records))
+	}
+	tests.Passed("Should have received 2 records")
+
+	if records[0]["_id"] != "bob" || records[0]["total"] != 120 {
+		tests.Failed("Should have received bob with total of 120 first but got %#v", records[0])
+	}
+	tests.Passed("Should have received bob with total of 120 first")
+}
+
+// TestMongoTail requires MONGO_TEST_HOST to point to a replica set, as change streams
+// are not available on standalone servers. A local single-node replica set can be
+// started with `mongod --replSet rs0` followed by `rs.initiate()` from the mongo shell.
+func TestMongoTail(t *testing.T) {
+	session := testSession()
+	if session == nil {
+		return
+	}
+
+	defer session.Close()
+
+	collection := session.DB(testDB).C("tail_sales")
+	collection.DropCollection()
+	defer collection.DropCollection()
+
+	if err := collection.Insert(bson.M{"seq": 0, "user": "bob"}); err != nil {
+		tests.FailedWithError(err, "Should have successfully inserted document")
+	}
+	tests.Passed("Should have successfully inserted document")
+
+	config := mongodb.TailConfig{
+		DB:         testDB,
+		Collection: "tail_sales",
+		BatchWait:  time.Millisecond * 500,
+	}
+
+	tail, err := mongodb.Tail(session, config)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully opened change stream")
+	}
+	tests.Passed("Should have successfully opened change stream")
+
+	for i := 1; i <= 3; i++ {
+		if err := collection.Insert(bson.M{"seq": i, "user": "bob"}); err != nil {
+			tests.FailedWithError(err, "Should have successfully inserted document")
+		}
+	}
+
+	if err := collection.Update(bson.M{"seq": 0}, bson.M{"$set": bson.M{"user": "alex"}}); err != nil {
+		tests.FailedWithError(err, "Should have successfully updated document")
+	}
+	tests.Passed("Should have successfully inserted and updated documents")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	records, err := tail.Pull(ctx, 2)
+	if err != nil || len(records) != 2 {
+		tests.Failed("Should have received batch of 2 records but got %d: %+q", len(records), err)
+	}
+	tests.Passed("Should have received batch of 2 records")
+
+	token, ok := tail.ResumeToken()
+	if !ok {
+		tests.Failed("Should have resume token after pull")
+	}
+	tests.Passed("Should have resume token after pull")
+	tail.Close()
+
+	config.ResumeToken = token
+	tail, err = mongodb.Tail(session, config)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully resumed change stream")
+	}
+	tests.Passed("Should have successfully resumed change stream")
+
+	defer tail.Close()
+
+	records, err = tail.Pull(ctx, 10)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully pulled remaining changes")
+	}
+	tests.Passed("Should have successfully pulled remaining changes")
+
+	if len(records) != 2 {
+		tests.Failed("Should have received remaining 2 changes within batch wait but got %d", len(records))
+	}
+	tests.Passed("Should have received remaining 2 changes within batch wait")
+
+	if records[1]["user"] != "alex" || records[1]["seq"] != 0 {
+		tests.Failed("Should have received full document of update but got %#v", records[1])
+	}
+	tests.Passed("Should have received full document of update")
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+
+	if _, err := tail.Pull(cancelled, 10); err != context.Canceled {
+		tests.Failed("Should have stopped waiting for changes once context is done")
+	}
+	tests.Passed("Should have stopped waiting for changes once context is done")
+
+	pulled := make(chan error, 1)
+	go func() {
+		_, err := tail.Pull(context.Background(), 10)
+		pulled <- err
+	}()
+
+	// Give the pull time to block waiting for changes.
+	time.Sleep(time.Millisecond * 100)
+
+	closed := make(chan struct{})
+	go func() {
+		tail.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second * 5):
+		tests.Failed("Should have closed change stream while pull waits for changes")
+	}
+	tests.Passed("Should have closed change stream while pull waits for changes")
+
+	select {
+	case err := <-pulled:
+		if err != dataset.ErrNoMore {
+			tests.Failed("Should have received dataset.ErrNoMore once closed but got %+q", err)
+		}
+	case <-time.After(time.Second * 5):
+		tests.Failed("Should have unblocked pull once closed")
+	}
+	tests.Passed("Should have unblocked pull once closed")
+}
@@ -0,0 +1,182 @@
+package mongodb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ParsePipeline returns the aggregation pipeline for the giving stages, where each
+// stage is either a document or a string of mongodb extended json. Stages given as
+// strings keep the order of their fields, which matters for stages like `$sort`
+// on multiple fields. Extended json values like `{"$date": "..."}` and `{"$oid": "..."}`
+// are supported within both.
+func ParsePipeline(stages []interface{}) ([]interface{}, error) {
+	pipeline := make([]interface{}, 0, len(stages))
+	for index, stage := range stages {
+		var data []byte
+		switch item := stage.(type) {
+		case string:
+			data = []byte(item)
+		default:
+			encoded, err := json.Marshal(normalizeKeys(item))
+			if err != nil {
+				return nil, fmt.Errorf("invalid pipeline stage %d: %+s", index, err.Error())
+			}
+			data = encoded
+		}
+
+		parsed, err := ParseOrderedJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pipeline stage %d: %+s", index, err.Error())
+		}
+
+		if _, ok := parsed.(bson.D); !ok {
+			return nil, fmt.Errorf("invalid pipeline stage %d: expected a document", index)
+		}
+
+		pipeline = append(pipeline, parsed)
+	}
+
+	return pipeline, nil
+}
+
+// ParseOrderedJSON decodes the giving mongodb extended json, where documents are
+// decoded into bson.D to keep the order of their fields.
+func ParseOrderedJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	value, err := decodeOrdered(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.More() {
+		return nil, errors.New("unexpected data after json value")
+	}
+
+	return value, nil
+}
+
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch item := token.(type) {
+	case json.Delim:
+		switch item {
+		case '{':
+			var doc bson.D
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				value, err := decodeOrdered(decoder)
+				if err != nil {
+					return nil, err
+				}
+
+				doc = append(doc, bson.DocElem{Name: key.(string), Value: value})
+			}
+
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+
+			return extendedValue(doc)
+		case '[':
+			list := []interface{}{}
+			for decoder.More() {
+				value, err := decodeOrdered(decoder)
+				if err != nil {
+					return nil, err
+				}
+
+				list = append(list, value)
+			}
+
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+
+			return list, nil
+		default:
+			return nil, fmt.Errorf("unexpected json delimiter %q", item)
+		}
+	case json.Number:
+		if number, err := item.Int64(); err == nil {
+			return number, nil
+		}
+		return item.Float64()
+	default:
+		return item, nil
+	}
+}
+
+// extendedValue returns the value of extended json documents like `{"$date": ...}` and
+// `{"$oid": ...}`, else the document itself.
+func extendedValue(doc bson.D) (interface{}, error) {
+	if len(doc) != 1 {
+		return doc, nil
+	}
+
+	switch doc[0].Name {
+	case "$date":
+		switch value := doc[0].Value.(type) {
+		case string:
+			date, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, err
+			}
+			return date, nil
+		case int64:
+			return time.Unix(0, value*int64(time.Millisecond)).UTC(), nil
+		default:
+			return nil, fmt.Errorf("invalid $date value %v", value)
+		}
+	case "$oid":
+		value, ok := doc[0].Value.(string)
+		if !ok || !bson.IsObjectIdHex(value) {
+			return nil, fmt.Errorf("invalid $oid value %v", doc[0].Value)
+		}
+		return bson.ObjectIdHex(value), nil
+	default:
+		return doc, nil
+	}
+}
+
+// normalizeKeys returns the value with maps of interface keys, as decoded from
+// yaml configuration, turned into maps of string keys for json encoding.
+func normalizeKeys(value interface{}) interface{} {
+	switch item := value.(type) {
+	case map[interface{}]interface{}:
+		doc := make(map[string]interface{}, len(item))
+		for key, elem := range item {
+			doc[fmt.Sprint(key)] = normalizeKeys(elem)
+		}
+		return doc
+	case map[string]interface{}:
+		doc := make(map[string]interface{}, len(item))
+		for key, elem := range item {
+			doc[key] = normalizeKeys(elem)
+		}
+		return doc
+	case []interface{}:
+		list := make([]interface{}, len(item))
+		for index, elem := range item {
+			list[index] = normalizeKeys(elem)
+		}
+		return list
+	default:
+		return value
+	}
+}
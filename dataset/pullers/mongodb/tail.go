@@ -0,0 +1,277 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influx6/geckodataset/dataset"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DefaultBatchWait indicates the default duration MongoTail waits for
+// a batch to fill up, after it's first record, before returning it.
+const DefaultBatchWait = time.Second * 5
+
+// defaultOperations sets the change events turned into records by MongoTail.
+var defaultOperations = []string{"insert", "update", "replace"}
+
+// TailConfig embodies the configuration used by MongoTail to tail changes
+// of a mongodb collection.
+type TailConfig struct {
+	// DB sets the database containing the collection.
+	DB string
+
+	// Collection sets the collection whose changes are tailed.
+	Collection string
+
+	// ResumeToken sets the token of the change event after which changes
+	// are tailed. If nil, only changes from now on are tailed.
+	ResumeToken interface{}
+
+	// BatchWait sets the maximum duration to wait for a batch to fill up
+	// after it's first record, before returning it. Defaults to DefaultBatchWait.
+	BatchWait time.Duration
+}
+
+// Validate returns an error if the config is invalid.
+func (c TailConfig) Validate() error {
+	if c.DB == "" {
+		return errors.New("mongodb.TailConfig.DB is required")
+	}
+
+	if c.Collection == "" {
+		return errors.New("mongodb.TailConfig.Collection is required")
+	}
+
+	if c.BatchWait < 0 {
+		return errors.New("mongodb.TailConfig.BatchWait can't be negative")
+	}
+
+	return nil
+}
+
+// changeEvent embodies a change event read from a change stream.
+type changeEvent struct {
+	token  interface{}
+	record map[string]interface{}
+}
+
+// MongoTail implements the dataset.DataPull interface for tailing inserts and updates
+// of a mongodb collection through a change stream, which requires a replica set of
+// mongodb 3.6 or later. Each record is the full document after the change.
+//
+// mgo.v2 predates change streams and has no support for them, so the change stream is
+// opened as an aggregation starting with a $changeStream stage through Pipe, whose
+// iterator only receives changes by polling the server with getMore commands, where
+// each getMore waits for changes till the server's await time passes.
+type MongoTail struct {
+	config    TailConfig
+	session   *mgo.Session
+	iter      *mgo.Iter
+	events    chan changeEvent
+	closer    chan struct{}
+	closeOnce sync.Once
+	err       error
+
+	// pull serializes calls to Pull, which block till changes are received,
+	// while ml only guards the resume token, so Close and ResumeToken never
+	// wait on a blocked Pull.
+	pull      sync.Mutex
+	remaining []changeEvent
+
+	ml       sync.Mutex
+	token    interface{}
+	hasToken bool
+}
+
+// Tail returns a new instance of MongoTail using a copy of the provided session, opening
+// the change stream of the collection right away so no changes after it's return are missed.
+func Tail(session *mgo.Session, config TailConfig) (*MongoTail, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.BatchWait == 0 {
+		config.BatchWait = DefaultBatchWait
+	}
+
+	stream := bson.M{"fullDocument": "updateLookup"}
+	if config.ResumeToken != nil {
+		stream["resumeAfter"] = config.ResumeToken
+	}
+
+	pipeline := []bson.M{
+		{"$changeStream": stream},
+		{"$match": bson.M{"operationType": bson.M{"$in": defaultOperations}}},
+	}
+
+	tailSession := session.Copy()
+
+	info, err := tailSession.BuildInfo()
+	if err != nil {
+		tailSession.Close()
+		return nil, err
+	}
+
+	if !info.VersionAtLeast(3, 6) {
+		tailSession.Close()
+		return nil, fmt.Errorf("mongodb change streams require mongodb 3.6 or later, found %s", info.Version)
+	}
+
+	iter := tailSession.DB(config.DB).C(config.Collection).Pipe(pipeline).Iter()
+	if err := iter.Err(); err != nil {
+		tailSession.Close()
+		return nil, err
+	}
+
+	mt := &MongoTail{
+		config:   config,
+		session:  tailSession,
+		iter:     iter,
+		events:   make(chan changeEvent),
+		closer:   make(chan struct{}),
+		token:    config.ResumeToken,
+		hasToken: config.ResumeToken != nil,
+	}
+
+	go mt.watch()
+	return mt, nil
+}
+
+// Close closes the change stream and session of the MongoTail, unblocking any
+// Pull waiting for changes.
+func (mt *MongoTail) Close() error {
+	mt.closeOnce.Do(func() {
+		close(mt.closer)
+
+		// Closing the session unblocks the change stream if it's waiting for changes.
+		mt.session.Close()
+	})
+	return nil
+}
+
+// ResumeToken returns the token of the change event of the last record pulled,
+// or the starting resume token if none has been pulled yet.
+func (mt *MongoTail) ResumeToken() (interface{}, bool) {
+	mt.ml.Lock()
+	defer mt.ml.Unlock()
+	return mt.token, mt.hasToken
+}
+
+// Pull returns the next batch of changed documents, blocking till at least one change
+// occurs. The batch is returned once it's filled or BatchWait has passed since it's
+// first record. If the provided context is done, then no records are returned and
+// the changes received are kept for the next pull, with the resume token left at
+// the last record returned so a restart carries on without gaps.
+func (mt *MongoTail) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	mt.pull.Lock()
+	defer mt.pull.Unlock()
+
+	if batch == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	// Events received but not returned by the last pull are used first.
+	events := mt.remaining
+	mt.remaining = nil
+
+	var wait <-chan time.Time
+	if len(events) != 0 {
+		wait = time.After(mt.config.BatchWait)
+	}
+
+	for len(events) < batch {
+		select {
+		case <-ctx.Done():
+			mt.remaining = events
+			return nil, ctx.Err()
+		case <-wait:
+			return mt.records(events), nil
+		case <-mt.closer:
+			if len(events) != 0 {
+				return mt.records(events), nil
+			}
+
+			return nil, dataset.ErrNoMore
+		case event, ok := <-mt.events:
+			if !ok {
+				if len(events) != 0 {
+					return mt.records(events), nil
+				}
+
+				if mt.err != nil {
+					return nil, mt.err
+				}
+
+				return nil, dataset.ErrNoMore
+			}
+
+			if wait == nil {
+				wait = time.After(mt.config.BatchWait)
+			}
+
+			events = append(events, event)
+		}
+	}
+
+	return mt.records(events), nil
+}
+
+// records returns the records of the events, updating the resume token to that
+// of the last event.
+func (mt *MongoTail) records(events []changeEvent) []map[string]interface{} {
+	records := make([]map[string]interface{}, len(events))
+	for index, event := range events {
+		records[index] = event.record
+	}
+
+	mt.ml.Lock()
+	mt.token = events[len(events)-1].token
+	mt.hasToken = true
+	mt.ml.Unlock()
+
+	return records
+}
+
+// watch reads change events from the change stream till it's closed or fails.
+func (mt *MongoTail) watch() {
+	defer close(mt.events)
+
+	for {
+		var event struct {
+			ID           bson.Raw `bson:"_id"`
+			FullDocument bson.M   `bson:"fullDocument"`
+		}
+
+		if !mt.iter.Next(&event) {
+			select {
+			case <-mt.closer:
+			default:
+				mt.err = mt.iter.Err()
+			}
+			return
+		}
+
+		// Updated documents deleted before their lookup have no full document.
+		if event.FullDocument == nil {
+			continue
+		}
+
+		// Resume tokens are kept as bson.D, as the order of their fields matters.
+		var token bson.D
+		if err := event.ID.Unmarshal(&token); err != nil {
+			mt.err = err
+			return
+		}
+
+		select {
+		case mt.events <- changeEvent{token: token, record: Normalize(event.FullDocument).(map[string]interface{})}:
+		case <-mt.closer:
+			return
+		}
+	}
+}
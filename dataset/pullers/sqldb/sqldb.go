@@ -0,0 +1,358 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/geckodataset/dataset"
+)
+
+// cursorParam sets the name the last cursor value is bound to within queries, which
+// is reserved from Config.Params.
+const cursorParam = "_cursor"
+
+// Config embodies the configuration used by SQLPull to query records
+// from a sql database.
+type Config struct {
+	// Driver sets the database/sql driver name e.g postgres, mysql, sqlite.
+	Driver string
+
+	// DSN sets the data source name used to connect to the database.
+	DSN string
+
+	// Query sets the select query for records, which can reference values
+	// from Params by name using the `:name` syntax.
+	Query string
+
+	// Params contains the named parameters referenced within Query, where
+	// the `_cursor` name is reserved for the cursor value.
+	Params map[string]interface{}
+
+	// Cursor sets the column, which must be unique and increasing, used
+	// for keyset pagination of Query results. It must be a plain column
+	// name of letters, digits and underscores, as it is written unquoted
+	// into queries.
+	Cursor string
+
+	// Start sets the cursor value after which records are pulled. If nil,
+	// records are pulled from the start.
+	Start interface{}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if c.Driver == "" {
+		return errors.New("sqldb.Config.Driver is required")
+	}
+
+	if c.Query == "" {
+		return errors.New("sqldb.Config.Query is required")
+	}
+
+	if c.Cursor == "" {
+		return errors.New("sqldb.Config.Cursor is required")
+	}
+
+	if !isIdentifier(c.Cursor) {
+		return fmt.Errorf("sqldb.Config.Cursor must be a column name of letters, digits and underscores not %q", c.Cursor)
+	}
+
+	if _, ok := c.Params[cursorParam]; ok {
+		return fmt.Errorf("sqldb.Config.Params can't contain %q, which is reserved for the cursor", cursorParam)
+	}
+
+	return nil
+}
+
+// SQLPull implements the dataset.DataPull interface for pulling records from a sql
+// database. Every call to Pull issues a bounded query which selects the next batch of
+// records with cursor column values above the last record seen.
+type SQLPull struct {
+	config Config
+	db     *sql.DB
+	owned  bool
+
+	ml        sync.Mutex
+	cursor    interface{}
+	hasCursor bool
+}
+
+// Open returns a new instance of SQLPull connected to the database using
+// the config driver and dsn.
+func Open(config Config) (*SQLPull, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(config.Driver, config.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	puller, err := New(db, config)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	puller.owned = true
+	return puller, nil
+}
+
+// New returns a new instance of SQLPull which uses provided database.
+func New(db *sql.DB, config Config) (*SQLPull, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &SQLPull{
+		db:        db,
+		config:    config,
+		cursor:    config.Start,
+		hasCursor: config.Start != nil,
+	}, nil
+}
+
+// Close closes the database if it was opened by the SQLPull.
+func (sp *SQLPull) Close() error {
+	if !sp.owned {
+		return nil
+	}
+	return sp.db.Close()
+}
+
+// Cursor returns the cursor column value of the last record pulled.
+func (sp *SQLPull) Cursor() interface{} {
+	sp.ml.Lock()
+	defer sp.ml.Unlock()
+	return sp.cursor
+}
+
+// Pull returns the next batch of records after the last cursor value.
+func (sp *SQLPull) Pull(ctx context.Context, batch int) ([]map[string]interface{}, error) {
+	sp.ml.Lock()
+	defer sp.ml.Unlock()
+
+	if batch == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	params := make(map[string]interface{}, len(sp.config.Params)+1)
+	for name, value := range sp.config.Params {
+		params[name] = value
+	}
+
+	query := fmt.Sprintf("SELECT * FROM (%s) AS src", sp.config.Query)
+	if sp.hasCursor {
+		query = fmt.Sprintf("%s WHERE src.%s > :%s", query, sp.config.Cursor, cursorParam)
+		params[cursorParam] = sp.cursor
+	}
+	query = fmt.Sprintf("%s ORDER BY src.%s LIMIT %d", query, sp.config.Cursor, batch)
+
+	bound, args, err := BindNamed(query, placeholderFor(sp.config.Driver), params)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sp.db.QueryContext(ctx, bound, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	cursorIndex := -1
+	for index, column := range columns {
+		if column.Name() == sp.config.Cursor {
+			cursorIndex = index
+			break
+		}
+	}
+
+	if cursorIndex == -1 {
+		return nil, fmt.Errorf("cursor column %q not found in query result", sp.config.Cursor)
+	}
+
+	var records []map[string]interface{}
+
+	values := make([]interface{}, len(columns))
+	targets := make([]interface{}, len(columns))
+	for index := range values {
+		targets[index] = &values[index]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+
+		record := make(map[string]interface{}, len(columns))
+		for index, column := range columns {
+			record[column.Name()] = Normalize(values[index], column.DatabaseTypeName())
+		}
+
+		records = append(records, record)
+
+		// keep the raw value of cursor, so it compares correctly within
+		// the database on the next query.
+		cursor := values[cursorIndex]
+		if raw, ok := cursor.([]byte); ok {
+			cursor = string(raw)
+		}
+
+		sp.cursor = cursor
+		sp.hasCursor = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, dataset.ErrNoMore
+	}
+
+	return records, nil
+}
+
+// Normalize returns a json friendly value of a value scanned from a column with the
+// giving database type name.
+func Normalize(value interface{}, typeName string) interface{} {
+	switch item := value.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		return item.Format(time.RFC3339)
+	case []byte:
+		typeName = strings.ToUpper(typeName)
+		switch {
+		case strings.Contains(typeName, "BLOB"), strings.Contains(typeName, "BINARY"), typeName == "BYTEA":
+			return base64.StdEncoding.EncodeToString(item)
+		case strings.Contains(typeName, "INT"):
+			if number, err := strconv.ParseInt(string(item), 10, 64); err == nil {
+				return number
+			}
+		case strings.Contains(typeName, "DECIMAL"), strings.Contains(typeName, "NUMERIC"),
+			strings.Contains(typeName, "FLOAT"), strings.Contains(typeName, "DOUBLE"),
+			strings.Contains(typeName, "REAL"):
+			if number, err := strconv.ParseFloat(string(item), 64); err == nil {
+				return number
+			}
+		case typeName == "BOOL", typeName == "BOOLEAN":
+			if flag, err := strconv.ParseBool(string(item)); err == nil {
+				return flag
+			}
+		}
+		return string(item)
+	default:
+		return value
+	}
+}
+
+// Placeholder defines the style of bind parameters used by a sql driver.
+type Placeholder int
+
+// placeholders ...
+const (
+	// QuestionPlaceholder uses `?` for bind parameters as used by mysql and sqlite.
+	QuestionPlaceholder Placeholder = iota
+
+	// DollarPlaceholder uses `$1`, `$2` and so on for bind parameters as used by postgres.
+	DollarPlaceholder
+)
+
+// placeholderFor returns the placeholder style for the giving driver name.
+func placeholderFor(driver string) Placeholder {
+	switch strings.ToLower(driver) {
+	case "postgres", "pgx", "cloudsqlpostgres":
+		return DollarPlaceholder
+	default:
+		return QuestionPlaceholder
+	}
+}
+
+// BindNamed rewrites `:name` parameters within query into the giving placeholder style,
+// returning the arguments in their order of appearance. Quoted strings, quoted identifiers
+// and `::` casts are left untouched.
+func BindNamed(query string, style Placeholder, params map[string]interface{}) (string, []interface{}, error) {
+	var bound strings.Builder
+	var args []interface{}
+
+	for index := 0; index < len(query); index++ {
+		char := query[index]
+
+		switch {
+		case char == '\'' || char == '"' || char == '`':
+			end := strings.IndexByte(query[index+1:], char)
+			if end == -1 {
+				return "", nil, errors.New("unterminated quote in query")
+			}
+
+			bound.WriteString(query[index : index+end+2])
+			index += end + 1
+		case char == ':' && index+1 < len(query) && query[index+1] == ':':
+			bound.WriteString("::")
+			index++
+		case char == ':' && index+1 < len(query) && isNameChar(query[index+1]):
+			end := index + 1
+			for end < len(query) && isNameChar(query[end]) {
+				end++
+			}
+
+			name := query[index+1 : end]
+			value, ok := params[name]
+			if !ok {
+				return "", nil, fmt.Errorf("missing value for query parameter %q", name)
+			}
+
+			args = append(args, value)
+			if style == DollarPlaceholder {
+				bound.WriteString("$" + strconv.Itoa(len(args)))
+			} else {
+				bound.WriteByte('?')
+			}
+
+			index = end - 1
+		default:
+			bound.WriteByte(char)
+		}
+	}
+
+	return bound.String(), args, nil
+}
+
+// isIdentifier returns true if name is an unquoted sql identifier, which starts
+// with a letter or underscore.
+func isIdentifier(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+
+	for index := 0; index < len(name); index++ {
+		if !isNameChar(name[index]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,157 @@
+package sqldb_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset"
+	"github.com/influx6/geckodataset/dataset/pullers/sqldb"
+	_ "modernc.org/sqlite"
+)
+
+func TestBindNamed(t *testing.T) {
+	query, args, err := sqldb.BindNamed(
+		"SELECT id::text FROM sales WHERE region = :region AND note != ':skip' AND total > :total",
+		sqldb.DollarPlaceholder,
+		map[string]interface{}{"region": "emea", "total": 20},
+	)
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully bound named parameters")
+	}
+	tests.Passed("Should have successfully bound named parameters")
+
+	expected := "SELECT id::text FROM sales WHERE region = $1 AND note != ':skip' AND total > $2"
+	if query != expected {
+		tests.Failed("Should have rewritten query to %q but got %q", expected, query)
+	}
+	tests.Passed("Should have rewritten query with dollar placeholders")
+
+	if len(args) != 2 || args[0] != "emea" || args[1] != 20 {
+		tests.Failed("Should have received arguments in order of appearance")
+	}
+	tests.Passed("Should have received arguments in order of appearance")
+
+	if _, _, err := sqldb.BindNamed("SELECT * FROM sales WHERE id = :id", sqldb.QuestionPlaceholder, nil); err == nil {
+		tests.Failed("Should have failed with missing parameter")
+	}
+	tests.Passed("Should have failed with missing parameter")
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := sqldb.Config{
+		Driver: "sqlite",
+		Query:  "SELECT * FROM sales WHERE region = :region",
+		Params: map[string]interface{}{"region": "emea"},
+		Cursor: "sale_id",
+	}
+
+	if err := config.Validate(); err != nil {
+		tests.FailedWithError(err, "Should have successfully validated config")
+	}
+	tests.Passed("Should have successfully validated config")
+
+	for _, cursor := range []string{"id; DROP TABLE sales", "src.id", "1id", `"id"`} {
+		config.Cursor = cursor
+		if err := config.Validate(); err == nil {
+			tests.Failed("Should have failed to validate cursor %q", cursor)
+		}
+		tests.Passed("Should have failed to validate cursor %q", cursor)
+	}
+
+	config.Cursor = "id"
+	config.Params["_cursor"] = 10
+	if err := config.Validate(); err == nil {
+		tests.Failed("Should have failed to validate params with reserved cursor name")
+	}
+	tests.Passed("Should have failed to validate params with reserved cursor name")
+}
+
+func TestSQLPull(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqldb")
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created temporary directory")
+	}
+	tests.Passed("Should have successfully created temporary directory")
+
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open("sqlite", filepath.Join(dir, "sales.db"))
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully opened sqlite database")
+	}
+	tests.Passed("Should have successfully opened sqlite database")
+
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE sales (id INTEGER PRIMARY KEY, name TEXT, region TEXT, total REAL)`); err != nil {
+		tests.FailedWithError(err, "Should have successfully created sales table")
+	}
+	tests.Passed("Should have successfully created sales table")
+
+	for i := 1; i <= 7; i++ {
+		region := "emea"
+		if i%2 == 0 {
+			region = "apac"
+		}
+
+		if _, err := db.Exec(`INSERT INTO sales (id, name, region, total) VALUES (?, ?, ?, ?)`, i, fmt.Sprintf("user-%d", i), region, float64(i)*10.5); err != nil {
+			tests.FailedWithError(err, "Should have successfully inserted sale")
+		}
+	}
+	tests.Passed("Should have successfully inserted sales")
+
+	puller, err := sqldb.New(db, sqldb.Config{
+		Driver: "sqlite",
+		Query:  "SELECT id, name, total FROM sales WHERE region = :region",
+		Params: map[string]interface{}{"region": "emea"},
+		Cursor: "id",
+	})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully created puller")
+	}
+	tests.Passed("Should have successfully created puller")
+
+	var records []map[string]interface{}
+	for {
+		recs, err := puller.Pull(context.Background(), 3)
+		if err != nil {
+			if err == dataset.ErrNoMore {
+				break
+			}
+			tests.FailedWithError(err, "Should have successfully pulled records")
+		}
+
+		if len(recs) > 3 {
+			tests.Failed("Should have received at most 3 records but got %d", len(recs))
+		}
+
+		records = append(records, recs...)
+	}
+	tests.Passed("Should have successfully pulled records in batches")
+
+	if len(records) != 4 {
+		tests.Failed("Should have received 4 records but got %d", len(records))
+	}
+	tests.Passed("Should have received 4 records")
+
+	if records[0]["name"] != "user-1" || records[3]["name"] != "user-7" {
+		tests.Failed("Should have received records in order of cursor")
+	}
+	tests.Passed("Should have received records in order of cursor")
+
+	if total, ok := records[0]["total"].(float64); !ok || total != 10.5 {
+		tests.Failed("Should have received total as float64")
+	}
+	tests.Passed("Should have received total as float64")
+
+	if cursor, ok := puller.Cursor().(int64); !ok || cursor != 7 {
+		tests.Failed("Should have last cursor value as 7")
+	}
+	tests.Passed("Should have last cursor value as 7")
+}
@@ -0,0 +1,93 @@
+package pushers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MongoPusher implements the Pusher interface for saving records into a mongodb
+// collection, where records are upserted by the values of their UniqueBy fields,
+// so pushing the same records again does not duplicate them.
+type MongoPusher struct {
+	DB         string
+	Collection string
+	UniqueBy   []string
+	Session    *mgo.Session
+}
+
+// NewMongoPusher returns a new instance of MongoPusher using the provided session. If
+// replace is true, then all documents of the collection are removed, so the collection
+// only contains the records pushed during the run.
+func NewMongoPusher(session *mgo.Session, db string, collection string, uniqueBy []string, replace bool) (MongoPusher, error) {
+	if db == "" {
+		return MongoPusher{}, errors.New("database is required for mongodb pusher")
+	}
+
+	if collection == "" {
+		return MongoPusher{}, errors.New("collection is required for mongodb pusher")
+	}
+
+	pusher := MongoPusher{
+		DB:         db,
+		Collection: collection,
+		UniqueBy:   uniqueBy,
+		Session:    session,
+	}
+
+	if replace {
+		if err := pusher.Clear(); err != nil {
+			return MongoPusher{}, err
+		}
+	}
+
+	return pusher, nil
+}
+
+// Clear removes all documents of the collection.
+func (mp MongoPusher) Clear() error {
+	session := mp.Session.Copy()
+	defer session.Close()
+
+	_, err := session.DB(mp.DB).C(mp.Collection).RemoveAll(nil)
+	return err
+}
+
+// Push upserts the records into the collection by the values of their UniqueBy fields,
+// which each record must have. If no UniqueBy fields are set, then records are inserted.
+func (mp MongoPusher) Push(ctx context.Context, recs ...map[string]interface{}) error {
+	if len(recs) == 0 {
+		return nil
+	}
+
+	session := mp.Session.Copy()
+	defer session.Close()
+
+	bulk := session.DB(mp.DB).C(mp.Collection).Bulk()
+	bulk.Unordered()
+
+	for _, rec := range recs {
+		if len(mp.UniqueBy) == 0 {
+			bulk.Insert(rec)
+			continue
+		}
+
+		selector := make(bson.M, len(mp.UniqueBy))
+		for _, field := range mp.UniqueBy {
+			value, ok := rec[field]
+			if !ok {
+				return fmt.Errorf("record is missing unique field %q: %#v", field, rec)
+			}
+
+			selector[field] = value
+		}
+
+		bulk.Upsert(selector, rec)
+	}
+
+	_, err := bulk.Run()
+	return err
+}
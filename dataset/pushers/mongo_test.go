@@ -0,0 +1,95 @@
+package pushers_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/influx6/faux/db/mongo"
+	"github.com/influx6/faux/tests"
+	"github.com/influx6/geckodataset/dataset/pullers/mongodb"
+	"github.com/influx6/geckodataset/dataset/pushers"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	mongoEnvName = "MONGO_TEST_HOST"
+	testDB       = "geckodataset_test"
+)
+
+func TestMongoPusher(t *testing.T) {
+	host := strings.TrimSpace(os.Getenv(mongoEnvName))
+	if host == "" {
+		tests.Info("Mongo tests requires %+q environment variable first", mongoEnvName)
+		return
+	}
+
+	session, err := mongodb.Dial(mongo.Config{Host: host, DB: testDB})
+	if err != nil {
+		tests.FailedWithError(err, "Should have successfully connected to mongodb")
+	}
+	tests.Passed("Should have successfully connected to mongodb")
+
+	defer session.Close()
+
+	collection := session.DB(testDB).C("pusher_metrics")
+	collection.DropCollection()
+	defer collection.DropCollection()
+
+	records := []map[string]interface{}{
+		{"user": "bob", "region": "emea", "total": 10},
+		{"user": "bob", "region": "apac", "total": 20},
+	}
+
+	tests.Header("When pushing the same records twice")
+	{
+		pusher, err := pushers.NewMongoPusher(session, testDB, "pusher_metrics", []string{"user", "region"}, false)
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created pusher")
+		}
+		tests.Passed("Should have successfully created pusher")
+
+		for i := 0; i < 2; i++ {
+			if err := pusher.Push(context.Background(), records...); err != nil {
+				tests.FailedWithError(err, "Should have successfully pushed records")
+			}
+		}
+		tests.Passed("Should have successfully pushed records")
+
+		if total, err := collection.Count(); err != nil || total != 2 {
+			tests.Failed("Should have upserted records into 2 documents but got %d", total)
+		}
+		tests.Passed("Should have upserted records into 2 documents")
+
+		if err := pusher.Push(context.Background(), map[string]interface{}{"user": "alex"}); err == nil {
+			tests.Failed("Should have failed to push record missing unique field")
+		}
+		tests.Passed("Should have failed to push record missing unique field")
+	}
+
+	tests.Header("When replacing the collection")
+	{
+		pusher, err := pushers.NewMongoPusher(session, testDB, "pusher_metrics", []string{"user", "region"}, true)
+		if err != nil {
+			tests.FailedWithError(err, "Should have successfully created pusher")
+		}
+		tests.Passed("Should have successfully created pusher")
+
+		if err := pusher.Push(context.Background(), records[1]); err != nil {
+			tests.FailedWithError(err, "Should have successfully pushed records")
+		}
+		tests.Passed("Should have successfully pushed records")
+
+		var docs []bson.M
+		if err := collection.Find(nil).All(&docs); err != nil || len(docs) != 1 {
+			tests.Failed("Should have only kept records pushed after replace but got %d", len(docs))
+		}
+		tests.Passed("Should have only kept records pushed after replace")
+
+		if docs[0]["region"] != "apac" || docs[0]["total"] != 20 {
+			tests.Failed("Should have saved record values but got %#v", docs[0])
+		}
+		tests.Passed("Should have saved record values")
+	}
+}